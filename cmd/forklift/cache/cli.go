@@ -3,6 +3,8 @@ package cache
 
 import (
 	"github.com/urfave/cli/v2"
+
+	fcli "github.com/forklift-run/forklift/internal/app/forklift/cli"
 )
 
 var Cmd = &cli.Command{
@@ -84,6 +86,7 @@ var Cmd = &cli.Command{
 			Category:  "Modify the cache",
 			Usage:     "Downloads local copies of pallets from remote releases",
 			ArgsUsage: "[pallet_path@release]...",
+			Before:    lockCaches,
 			Action:    addGitRepoAction(getPalletCache),
 		},
 		{
@@ -92,6 +95,7 @@ var Cmd = &cli.Command{
 			Category:  "Modify the cache",
 			Usage:     "Downloads local copies of repos from remote releases",
 			ArgsUsage: "[repo_path@release]...",
+			Before:    lockCaches,
 			Action:    addGitRepoAction(getRepoCache),
 		},
 		{
@@ -99,6 +103,7 @@ var Cmd = &cli.Command{
 			Aliases:  []string{"delete-all"},
 			Category: "Modify the cache",
 			Usage:    "Removes all cached resources",
+			Before:   lockCaches,
 			Action:   delAllAction,
 		},
 		{
@@ -107,6 +112,7 @@ var Cmd = &cli.Command{
 			Category: "Modify the cache",
 			Usage:    "Removes local mirrors of git repositories",
 			// TODO: allow only removing mirrors matching a glob pattern
+			Before: lockCaches,
 			Action: delGitRepoAction("mirror", getMirrorCache),
 		},
 		{
@@ -115,6 +121,7 @@ var Cmd = &cli.Command{
 			Category: "Modify the cache",
 			Usage:    "Removes locally-cached pallets",
 			// TODO: allow only removing pallets matching a glob pattern
+			Before: lockCaches,
 			Action: delGitRepoAction("pallet", getPalletCache),
 		},
 		{
@@ -123,6 +130,7 @@ var Cmd = &cli.Command{
 			Category: "Modify the cache",
 			Usage:    "Removes locally-cached repos",
 			// TODO: allow only removing repos matching a glob pattern
+			Before: lockCaches,
 			Action: delGitRepoAction("repo", getRepoCache),
 		},
 		{
@@ -130,6 +138,7 @@ var Cmd = &cli.Command{
 			Aliases:  []string{"delete-downloads"},
 			Category: "Modify the cache",
 			Usage:    "Removes locally-cached file downloads",
+			Before:   lockCaches,
 			Action:   delDlAction,
 		},
 		{
@@ -141,3 +150,10 @@ var Cmd = &cli.Command{
 		},
 	},
 }
+
+// lockCaches locks the workspace's caches against concurrent modification by other processes.
+func lockCaches(c *cli.Context) error {
+	return fcli.LockWorkspace(
+		c.String("workspace"), "", fcli.WorkspaceLocks{Caches: true}, c.Duration("lock-timeout"),
+	)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/urfave/cli/v2"
 
 	"github.com/forklift-run/forklift/internal/app/forklift"
+	fcli "github.com/forklift-run/forklift/internal/app/forklift/cli"
 )
 
 // ls-dl
@@ -32,15 +33,22 @@ func lsDlAction(c *cli.Context) error {
 	}
 
 	// TODO: add a --pattern cli flag for the pattern
+	paths := make([]string, 0)
 	if err = doublestar.GlobWalk(cache.FS, "**", func(path string, d fs.DirEntry) error {
 		if d.IsDir() {
 			return nil
 		}
-		fmt.Println(path)
+		paths = append(paths, path)
 		return nil
 	}); err != nil {
 		return errors.Wrapf(err, "couldn't list files in download cache %s", cache.FS.Path())
 	}
+	if format := c.String("output"); fcli.IsStructuredOutput(format) {
+		return fcli.FprintDocument(os.Stdout, format, paths)
+	}
+	for _, path := range paths {
+		fmt.Println(path)
+	}
 	return nil
 }
 
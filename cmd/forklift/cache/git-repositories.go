@@ -21,7 +21,16 @@ type versionQuerier interface {
 	VersionQuery() string
 }
 
+// A versionedDocument identifies a cached pallet, repo, or package in structured output.
+type versionedDocument struct {
+	// Path is the path of the pallet, repo, or package.
+	Path string `yaml:"path"`
+	// Version is the version of the pallet or repo (or of the repo providing the package).
+	Version string `yaml:"version"`
+}
+
 func lsGitRepo[GitRepo versionQuerier](
+	out io.Writer, format string,
 	gitRepoType, searchPattern string,
 	loader func(searchPattern string) ([]GitRepo, error),
 	comparer func(r, s GitRepo) int,
@@ -33,8 +42,16 @@ func lsGitRepo[GitRepo versionQuerier](
 	sort.Slice(allLoaded, func(i, j int) bool {
 		return comparer(allLoaded[i], allLoaded[j]) < 0
 	})
+	if fcli.IsStructuredOutput(format) {
+		docs := make([]versionedDocument, 0, len(allLoaded))
+		for _, loaded := range allLoaded {
+			path, version, _ := strings.Cut(loaded.VersionQuery(), "@")
+			docs = append(docs, versionedDocument{Path: path, Version: version})
+		}
+		return fcli.FprintDocument(out, format, docs)
+	}
 	for _, loaded := range allLoaded {
-		fmt.Println(loaded.VersionQuery())
+		_, _ = fmt.Fprintln(out, loaded.VersionQuery())
 	}
 	return nil
 }
@@ -42,11 +59,11 @@ func lsGitRepo[GitRepo versionQuerier](
 // show-*
 
 func showGitRepo[GitRepo any](
-	out io.Writer,
+	out io.Writer, format string,
 	cache core.Pather, versionQuery string,
 	loader func(path, version string) (GitRepo, error),
 	fprinter func(
-		indent int, out io.Writer, cache core.Pather, gitRepo GitRepo, printHeader bool,
+		indent int, out io.Writer, format string, cache core.Pather, gitRepo GitRepo, printHeader bool,
 	) error,
 	printHeader bool,
 ) error {
@@ -60,7 +77,7 @@ func showGitRepo[GitRepo any](
 	if err != nil {
 		return errors.Wrapf(err, "couldn't find %s@%s", gitRepoPath, version)
 	}
-	return fprinter(0, out, cache, gitRepo, printHeader)
+	return fprinter(0, out, format, cache, gitRepo, printHeader)
 }
 
 // add-*
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"fmt"
+	"os"
 	"sort"
 
 	units "github.com/docker/go-units"
@@ -28,6 +29,13 @@ func lsImgAction(c *cli.Context) error {
 	sort.Slice(imgs, func(i, j int) bool {
 		return imgs[i].Repository < imgs[j].Repository
 	})
+	if format := c.String("output"); fcli.IsStructuredOutput(format) {
+		docs := make([]fcli.ImageDocument, 0, len(imgs))
+		for _, img := range imgs {
+			docs = append(docs, fcli.NewImageDocument(img))
+		}
+		return fcli.FprintDocument(os.Stdout, format, docs)
+	}
 	for _, img := range imgs {
 		fmt.Printf("%s: %s", img.ID, img.Repository)
 		if img.Tag != "" {
@@ -51,6 +59,9 @@ func showImgAction(c *cli.Context) error {
 	if err != nil {
 		return errors.Wrapf(err, "couldn't inspect image %s", imageHash)
 	}
+	if format := c.String("output"); fcli.IsStructuredOutput(format) {
+		return fcli.FprintDocument(os.Stdout, format, fcli.NewImageDocument(image))
+	}
 	printImg(0, image)
 	return nil
 }
@@ -32,6 +32,13 @@ func lsPkgAction(c *cli.Context) error {
 	sort.Slice(pkgs, func(i, j int) bool {
 		return core.ComparePkgs(pkgs[i].Pkg, pkgs[j].Pkg) < 0
 	})
+	if format := c.String("output"); fcli.IsStructuredOutput(format) {
+		docs := make([]versionedDocument, 0, len(pkgs))
+		for _, pkg := range pkgs {
+			docs = append(docs, versionedDocument{Path: pkg.Path(), Version: pkg.Repo.Version})
+		}
+		return fcli.FprintDocument(os.Stdout, format, docs)
+	}
 	for _, pkg := range pkgs {
 		fmt.Printf("%s@%s\n", pkg.Path(), pkg.Repo.Version)
 	}
@@ -60,6 +67,5 @@ func showPkgAction(c *cli.Context) error {
 	if err != nil {
 		return errors.Wrapf(err, "couldn't resolve package query %s@%s", pkgPath, version)
 	}
-	fcli.FprintPkg(0, os.Stdout, cache, pkg)
-	return nil
+	return fcli.FprintPkg(0, os.Stdout, c.String("output"), cache, pkg)
 }
@@ -21,9 +21,12 @@ func lsPltAction(c *cli.Context) error {
 	}
 
 	// TODO: add a --pattern cli flag for the pattern
-	return lsGitRepo("pallet", "**", cache.LoadFSPallets, func(r, s *forklift.FSPallet) int {
-		return forklift.ComparePallets(r.Pallet, s.Pallet)
-	})
+	return lsGitRepo(
+		os.Stdout, c.String("output"), "pallet", "**", cache.LoadFSPallets,
+		func(r, s *forklift.FSPallet) int {
+			return forklift.ComparePallets(r.Pallet, s.Pallet)
+		},
+	)
 }
 
 // show-plt
@@ -38,6 +41,7 @@ func showPltAction(c *cli.Context) error {
 	}
 
 	return showGitRepo(
-		os.Stdout, cache, c.Args().First(), cache.LoadFSPallet, fcli.FprintCachedPallet, true,
+		os.Stdout, c.String("output"), cache, c.Args().First(),
+		cache.LoadFSPallet, fcli.FprintCachedPallet, true,
 	)
 }
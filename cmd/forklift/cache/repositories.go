@@ -21,9 +21,12 @@ func lsRepoAction(c *cli.Context) error {
 	}
 
 	// TODO: add a --pattern cli flag for the pattern
-	return lsGitRepo("repo", "**", cache.LoadFSRepos, func(r, s *core.FSRepo) int {
-		return core.CompareRepos(r.Repo, s.Repo)
-	})
+	return lsGitRepo(
+		os.Stdout, c.String("output"), "repo", "**", cache.LoadFSRepos,
+		func(r, s *core.FSRepo) int {
+			return core.CompareRepos(r.Repo, s.Repo)
+		},
+	)
 }
 
 // show-repo
@@ -38,6 +41,7 @@ func showRepoAction(c *cli.Context) error {
 	}
 
 	return showGitRepo(
-		os.Stdout, cache, c.Args().First(), cache.LoadFSRepo, fcli.FprintCachedRepo, true,
+		os.Stdout, c.String("output"), cache, c.Args().First(),
+		cache.LoadFSRepo, fcli.FprintCachedRepo, true,
 	)
 }
@@ -46,6 +46,32 @@ func MakeCmd(versions Versions) *cli.Command {
 	}
 }
 
+// Locking
+
+// lockWorkspace returns a function which locks the specified parts of the workspace against
+// concurrent modification by other processes.
+func lockWorkspace(locks fcli.WorkspaceLocks) cli.BeforeFunc {
+	return func(c *cli.Context) error {
+		return fcli.LockWorkspace(
+			c.String("workspace"), c.String("stage-store"), locks, c.Duration("lock-timeout"),
+		)
+	}
+}
+
+var (
+	lockStageStore = lockWorkspace(fcli.WorkspaceLocks{StageStore: true})
+	lockCaches     = lockWorkspace(fcli.WorkspaceLocks{Caches: true})
+)
+
+// lockForModify locks the caches (if requirements will be cached) and the stage store (if the
+// pallet will be staged) against concurrent modification by other processes.
+func lockForModify(c *cli.Context) error {
+	return lockWorkspace(fcli.WorkspaceLocks{
+		StageStore: c.Bool("stage") || c.Bool("apply"),
+		Caches:     c.Bool("cache-req"),
+	})(c)
+}
+
 func makeUseSubcmds(versions Versions) []*cli.Command {
 	const category = "Use the pallet"
 	return append(
@@ -62,11 +88,29 @@ func makeUseSubcmds(versions Versions) []*cli.Command {
 			Usage: "Determines the changes needed to update the host to match the deployments " +
 				"specified by the local pallet",
 			Action: planAction(versions),
+			Flags:  planFlags,
+		},
+		&cli.Command{
+			Name:     "graph",
+			Category: category,
+			Usage: "Shows the dependency relationships among the package deployments specified by the " +
+				"development pallet, as a Graphviz DOT or Mermaid graph",
+			Action: graphAction(versions),
+			Flags:  graphFlags,
+		},
+		&cli.Command{
+			Name:     "sbom",
+			Category: category,
+			Usage: "Prints a software bill of materials for the development pallet, as a " +
+				"CycloneDX or SPDX JSON document",
+			Action: sbomAction(versions),
+			Flags:  sbomFlags,
 		},
 		&cli.Command{
 			Name:     "stage",
 			Category: category,
 			Usage:    "Builds and stages a bundle of the development pallet to be applied later",
+			Before:   lockStageStore,
 			Action:   stageAction(versions),
 			Flags: []cli.Flag{
 				&cli.BoolFlag{
@@ -74,6 +118,11 @@ func makeUseSubcmds(versions Versions) []*cli.Command {
 					Usage: "Download container images",
 					Value: true,
 				},
+				&cli.BoolFlag{
+					Name: "bundle-img",
+					Usage: "Save the container images required by the pallet into the staged bundle, so " +
+						"that the bundle can be applied without internet access",
+				},
 			},
 		},
 		&cli.Command{
@@ -81,11 +130,60 @@ func makeUseSubcmds(versions Versions) []*cli.Command {
 			Category: category,
 			Usage: "Builds, stages, and immediately applies a bundle of the development pallet to " +
 				"update the host to match the deployments specified by the development pallet",
+			Before: lockStageStore,
 			Action: applyAction(versions),
+			Flags:  applyFlags,
 		},
 	)
 }
 
+var planFlags = append([]cli.Flag{
+	&cli.StringFlag{
+		Name:  "out",
+		Usage: "Save the plan as a JSON file at the specified path, so that it can be applied later",
+	},
+}, deplSelectionFlags...)
+
+var graphFlags = append([]cli.Flag{
+	&cli.StringFlag{
+		Name:  "format",
+		Value: fcli.GraphFormatDOT,
+		Usage: "Graph format (dot or mermaid)",
+	},
+	&cli.BoolFlag{
+		Name: "changes",
+		Usage: "Show the ordering relationships among the changes needed to update the host, " +
+			"instead of the dependency relationships among package deployments",
+	},
+}, deplSelectionFlags...)
+
+var sbomFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "format",
+		Value: fcli.SBOMFormatCycloneDX,
+		Usage: "SBOM format (cyclonedx or spdx)",
+	},
+}
+
+var applyFlags = append([]cli.Flag{
+	&cli.IntFlag{
+		Name:  "retries",
+		Usage: "Retry each failed change the specified number of times, with exponential backoff",
+	},
+}, deplSelectionFlags...)
+
+var deplSelectionFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name: "only",
+		Usage: "Only change the specified package deployment and any deployments required by it " +
+			"(can be specified multiple times)",
+	},
+	&cli.StringSliceFlag{
+		Name:  "exclude",
+		Usage: "Leave the specified package deployment untouched (can be specified multiple times)",
+	},
+}
+
 func makeUseCacheSubcmds(versions Versions) []*cli.Command {
 	const category = "Use the pallet"
 	return []*cli.Command{
@@ -93,6 +191,7 @@ func makeUseCacheSubcmds(versions Versions) []*cli.Command {
 			Name:     "cache-all",
 			Category: category,
 			Usage:    "Updates the cache with everything needed to apply the development pallet",
+			Before:   lockCaches,
 			Action:   cacheAllAction(versions),
 			Flags: []cli.Flag{
 				&cli.BoolFlag{
@@ -106,6 +205,7 @@ func makeUseCacheSubcmds(versions Versions) []*cli.Command {
 			Aliases:  []string{"cache-pallets"},
 			Category: category,
 			Usage:    "Updates the cache with the pallets required by the development pallet",
+			Before:   lockCaches,
 			Action:   cachePltAction(versions),
 		},
 		{
@@ -113,6 +213,7 @@ func makeUseCacheSubcmds(versions Versions) []*cli.Command {
 			Aliases:  []string{"cache-repositories"},
 			Category: category,
 			Usage:    "Updates the cache with the repos required by the development pallet",
+			Before:   lockCaches,
 			Action:   cacheRepoAction(versions),
 		},
 		{
@@ -120,6 +221,7 @@ func makeUseCacheSubcmds(versions Versions) []*cli.Command {
 			Aliases:  []string{"cache-downloads"},
 			Category: category,
 			Usage:    "Pre-downloads files to be exported by the development pallet",
+			Before:   lockCaches,
 			Action:   cacheDlAction(versions),
 		},
 		{
@@ -505,6 +607,7 @@ func makeModifyPltSubcmds(versions Versions) []*cli.Command {
 					Value: true,
 				},
 			},
+			Before: lockForModify,
 			Action: addPltAction(versions),
 		},
 		// TODO: add an upgrade-plt [plt_path]... command (upgrade all if no args)
@@ -554,6 +657,7 @@ func makeModifyRepoSubcmds(versions Versions) []*cli.Command {
 					Value: true,
 				},
 			},
+			Before: lockForModify,
 			Action: addRepoAction(versions),
 			// TODO: add an upgrade-repo [repo_path]... command (upgrade all if no args)
 			// TODO: add a check-upgrade-repo [repo_path]... command (check all upgrades if no args)
@@ -628,6 +732,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 				},
 				baseFlags,
 			),
+			Before: lockForModify,
 			Action: addDeplAction(versions),
 		},
 		{
@@ -637,6 +742,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 			Usage:     "Removes deployment from the pallet",
 			ArgsUsage: "deployment_name...",
 			Flags:     baseFlags,
+			Before:    lockForModify,
 			Action:    delDeplAction(versions),
 		},
 		{
@@ -655,6 +761,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 				},
 				baseFlags,
 			),
+			Before: lockForModify,
 			Action: setDeplPkgAction(versions),
 		},
 		{
@@ -679,6 +786,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 				},
 				baseFlags,
 			),
+			Before: lockForModify,
 			Action: addDeplFeatAction(versions),
 		},
 		{
@@ -694,6 +802,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 			Usage:     "Disables the specified package features in the specified deployment",
 			ArgsUsage: "deployment_name feature_name...",
 			Flags:     baseFlags,
+			Before:    lockForModify,
 			Action:    delDeplFeatAction(versions),
 		},
 		{
@@ -703,6 +812,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 			Usage:     "Disables the specified deployment",
 			ArgsUsage: "deployment_name",
 			Flags:     baseFlags,
+			Before:    lockForModify,
 			Action:    setDeplDisabledAction(versions, true),
 		},
 		{
@@ -712,6 +822,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 			Usage:     "Enables the specified deployment",
 			ArgsUsage: "deployment_name",
 			Flags:     baseFlags,
+			Before:    lockForModify,
 			Action:    setDeplDisabledAction(versions, false),
 		},
 	}
@@ -20,7 +20,7 @@ func lsDeplAction(c *cli.Context) error {
 		return err
 	}
 
-	return fcli.FprintPalletDepls(0, os.Stdout, plt)
+	return fcli.FprintPalletDepls(0, os.Stdout, c.String("output"), plt)
 }
 
 // show-depl
@@ -35,7 +35,7 @@ func showDeplAction(c *cli.Context) error {
 		return err
 	}
 
-	return fcli.FprintDeplInfo(0, os.Stdout, plt, caches.r, c.Args().First())
+	return fcli.FprintDeplInfo(0, os.Stdout, c.String("output"), plt, caches.r, c.Args().First())
 }
 
 // locate-depl-pkg
@@ -6,6 +6,7 @@ import (
 
 	"github.com/urfave/cli/v2"
 
+	"github.com/forklift-run/forklift/internal/app/forklift"
 	fcli "github.com/forklift-run/forklift/internal/app/forklift/cli"
 )
 
@@ -25,6 +26,12 @@ func lsDlAction(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	if format := c.String("output"); fcli.IsStructuredOutput(format) {
+		return fcli.FprintDocument(os.Stdout, format, forklift.BundleDeplDownloads{
+			HTTPFile: http,
+			OCIImage: oci,
+		})
+	}
 	for _, download := range http {
 		fmt.Println(download)
 	}
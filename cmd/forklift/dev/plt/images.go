@@ -25,6 +25,9 @@ func lsImgAction(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	if format := c.String("output"); fcli.IsStructuredOutput(format) {
+		return fcli.FprintDocument(os.Stdout, format, images)
+	}
 	for _, image := range images {
 		fmt.Println(image)
 	}
@@ -2,33 +2,79 @@
 package host
 
 import (
+	"time"
+
 	"github.com/urfave/cli/v2"
 )
 
-var Cmd = &cli.Command{
-	Name:  "host",
-	Usage: "Manages the local Docker host",
-	Subcommands: []*cli.Command{
-		{
-			Name:     "ls-app",
-			Aliases:  []string{"list-applications"},
-			Category: "Query the Docker host",
-			Usage:    "Lists running Docker Compose applications",
-			Action:   lsAppAction,
-		},
-		{
-			Name:     "ls-con",
-			Aliases:  []string{"list-containers"},
-			Category: "Query the Docker host",
-			Usage:    "Lists the containers associated with a package deployment",
-			Action:   lsConAction,
-		},
-		{
-			Name:     "del",
-			Aliases:  []string{"delete"},
-			Category: "Modify the Docker host",
-			Usage:    "Removes all Docker Compose applications",
-			Action:   delAction,
+type Versions struct {
+	Tool               string
+	MinSupportedBundle string
+	NewStageStore      string
+}
+
+func MakeCmd(versions Versions) *cli.Command {
+	return &cli.Command{
+		Name:  "host",
+		Usage: "Manages the local Docker host",
+		Subcommands: []*cli.Command{
+			{
+				Name:     "ls-app",
+				Aliases:  []string{"list-applications"},
+				Category: "Query the Docker host",
+				Usage:    "Lists running Docker Compose applications",
+				Action:   lsAppAction,
+			},
+			{
+				Name:     "ls-con",
+				Aliases:  []string{"list-containers"},
+				Category: "Query the Docker host",
+				Usage:    "Lists the containers associated with a package deployment",
+				Action:   lsConAction,
+			},
+			{
+				Name:     "reconcile",
+				Category: "Modify the Docker host",
+				Usage: "Updates the host to match the last successfully-applied staged pallet bundle, " +
+					"without changing the stage store",
+				Action: reconcileAction(versions),
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name: "watch",
+						Usage: "Keep running, and reconcile package deployments again whenever any of " +
+							"their containers stops permanently or is removed",
+					},
+					&cli.IntFlag{
+						Name: "retries",
+						Usage: "Retry each failed change the specified number of times, with exponential " +
+							"backoff",
+					},
+					&cli.DurationFlag{
+						Name:  "settle-delay",
+						Value: 5 * time.Second,
+						Usage: "With --watch, how long to wait after a container stops before checking " +
+							"whether Docker will restart it",
+					},
+					&cli.DurationFlag{
+						Name:  "min-interval",
+						Value: 10 * time.Second,
+						Usage: "With --watch, the minimum time between reconciliations",
+					},
+					&cli.DurationFlag{
+						Name:  "max-backoff",
+						Value: 5 * time.Minute,
+						Usage: "With --watch, the maximum delay before retrying a failed reconciliation",
+					},
+				},
+			},
+			{
+				Name:     "del",
+				Aliases:  []string{"delete"},
+				Category: "Modify the Docker host",
+				Usage: "Removes all Docker Compose applications created by Forklift (and also other " +
+					"Docker Compose applications, if the unowned apps policy is remove)",
+				Action: delAction,
+			},
 		},
-	},
+	}
 }
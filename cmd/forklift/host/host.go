@@ -4,13 +4,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
 
+	fcli "github.com/forklift-run/forklift/internal/app/forklift/cli"
 	"github.com/forklift-run/forklift/internal/clients/docker"
+	"github.com/forklift-run/forklift/pkg/core"
+	"github.com/forklift-run/forklift/pkg/structures"
 )
 
 // ls-app
@@ -65,20 +69,36 @@ func delAction(c *cli.Context) error {
 		return errors.Wrap(err, "couldn't make Docker API client")
 	}
 
+	policy := c.String("unowned-apps")
+	if err = fcli.CheckUnownedAppsPolicy(policy); err != nil {
+		return err
+	}
 	apps, err := client.ListApps(context.Background())
 	if err != nil {
 		return errors.Wrap(err, "couldn't list running Docker Compose apps")
 	}
-	sort.Slice(apps, func(i, j int) bool {
-		return apps[i].Name < apps[j].Name
-	})
-	// FIXME: instead of sorting alphabetically, we must sort by dependencies - preferably using
-	// Compose's dependency graph functionality
-
+	owned, err := fcli.ListOwnedApps(context.Background(), client, policy)
+	if err != nil {
+		return err
+	}
 	names := make([]string, 0, len(apps))
+	ignored := make([]string, 0, len(apps))
 	for _, app := range apps {
+		if policy != fcli.UnownedAppsRemove && !owned.Has(app.Name) {
+			ignored = append(ignored, app.Name)
+			continue
+		}
 		names = append(names, app.Name)
 	}
+	if len(ignored) > 0 {
+		slices.Sort(ignored)
+		fmt.Fprintf(os.Stderr, "Ignoring Docker Compose apps not created by Forklift: %+v\n", ignored)
+	}
+	deps, err := client.ResolveAppDeps(context.Background(), names)
+	if err != nil {
+		return errors.Wrap(err, "couldn't determine dependency relationships among Docker Compose apps")
+	}
+	sortAppRemovals(names, deps)
 	if err := client.RemoveApps(context.Background(), names); err != nil {
 		return errors.Wrap(
 			err, "couldn't fully remove all apps (remaining resources must be manually removed)",
@@ -87,3 +107,29 @@ func delAction(c *cli.Context) error {
 	fmt.Fprintln(os.Stderr, "Done!")
 	return nil
 }
+
+// sortAppRemovals sorts the names of Docker Compose apps so that each app will be removed before
+// any app which provides some Docker resource used by it. deps should be a graph of direct
+// dependencies between apps, as produced by [docker.Client.ResolveAppDeps]. Apps without any
+// dependency relationship (or in a dependency cycle) are sorted alphabetically.
+func sortAppRemovals(names []string, deps structures.Digraph[string]) {
+	closure := deps.ComputeTransitiveClosure()
+	dependents := closure.Invert()
+	slices.SortFunc(names, func(r, s string) int {
+		rDependsOnS := closure.HasEdge(r, s)
+		sDependsOnR := closure.HasEdge(s, r)
+		switch {
+		case rDependsOnS && !sDependsOnR:
+			return core.CompareLT
+		case !rDependsOnS && sDependsOnR:
+			return core.CompareGT
+		// Apps with fewer dependents go first (needed for correct ordering among unrelated apps
+		// sorted by slices.SortFunc).
+		case len(dependents[r]) < len(dependents[s]):
+			return core.CompareLT
+		case len(dependents[r]) > len(dependents[s]):
+			return core.CompareGT
+		}
+		return strings.Compare(r, s)
+	})
+}
@@ -0,0 +1,53 @@
+package host
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/forklift-run/forklift/pkg/structures"
+)
+
+func TestSortAppRemovals(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		names    []string
+		deps     [][2]string
+		expected []string
+	}{
+		{
+			name:     "unrelated",
+			names:    []string{"c", "a", "b"},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "chain",
+			names:    []string{"db", "proxy", "web"},
+			deps:     [][2]string{{"web", "db"}, {"db", "proxy"}},
+			expected: []string{"web", "db", "proxy"},
+		},
+		{
+			name:     "shared provider",
+			names:    []string{"a", "b", "network", "z"},
+			deps:     [][2]string{{"z", "network"}, {"b", "network"}},
+			expected: []string{"a", "b", "z", "network"},
+		},
+		{
+			name:     "cycle",
+			names:    []string{"right", "left", "user"},
+			deps:     [][2]string{{"left", "right"}, {"right", "left"}, {"user", "left"}},
+			expected: []string{"user", "left", "right"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			deps := make(structures.Digraph[string])
+			for _, dep := range test.deps {
+				deps.AddEdge(dep[0], dep[1])
+			}
+			names := slices.Clone(test.names)
+			sortAppRemovals(names, deps)
+			if !slices.Equal(names, test.expected) {
+				t.Errorf("expected removal order %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
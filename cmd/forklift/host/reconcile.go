@@ -0,0 +1,106 @@
+package host
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	fcli "github.com/forklift-run/forklift/internal/app/forklift/cli"
+)
+
+// reconcile
+
+func reconcileAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		// The current staged pallet bundle is loaded again for every reconciliation, since it may be
+		// changed (e.g. by `forklift stage apply`) while we're watching Docker:
+		loadBundle := func() (*forklift.FSBundle, error) {
+			return loadCurrentBundle(
+				c.String("workspace"), c.String("stage-store"), versions, c.Bool("ignore-tool-version"),
+			)
+		}
+		bundle, err := loadBundle()
+		if err != nil {
+			return err
+		}
+		ctx, changeCtx, stopHandlingInterrupts := fcli.HandleInterrupts(0)
+		defer stopHandlingInterrupts()
+
+		opts := fcli.ApplyOptions{
+			PlanOptions: fcli.PlanOptions{
+				Parallel:          c.Bool("parallel"),
+				PrecreateNetworks: c.Bool("precreate-networks"),
+				OrphanedVolumes:   c.String("orphaned-volumes"),
+				UnownedApps:       c.String("unowned-apps"),
+			},
+			Retries: c.Int("retries"),
+		}
+		err = fcli.ReconcileBundle(ctx, changeCtx, 0, bundle, opts)
+		if !c.Bool("watch") {
+			if err != nil {
+				return errors.Wrap(err, "couldn't reconcile the host with the staged pallet bundle")
+			}
+			fmt.Fprintln(os.Stderr, "Done!")
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(
+				os.Stderr, "Error: couldn't reconcile the host with the staged pallet bundle: %s\n",
+				err.Error(),
+			)
+		}
+		fmt.Fprintln(os.Stderr)
+		return fcli.WatchAndReconcile(ctx, changeCtx, 0, loadBundle, opts, fcli.WatchOptions{
+			SettleDelay: c.Duration("settle-delay"),
+			MinInterval: c.Duration("min-interval"),
+			MaxBackoff:  c.Duration("max-backoff"),
+		})
+	}
+}
+
+// loadCurrentBundle loads the last successfully-applied staged pallet bundle, and it checks that
+// the bundle may be applied: the bundle must be compatible with the Forklift tool, it must be
+// signed by a trusted key (if the stage store's bundle trust config requires it), and it must pass
+// its integrity check.
+func loadCurrentBundle(
+	wpath, sspath string, versions Versions, ignoreToolVersion bool,
+) (*forklift.FSBundle, error) {
+	var workspace *forklift.FSWorkspace
+	if sspath == "" {
+		var err error
+		if workspace, err = forklift.LoadWorkspace(wpath); err != nil {
+			return nil, errors.Wrap(
+				err, "couldn't load workspace to load the stage store, since no explicit path was "+
+					"provided for the stage store",
+			)
+		}
+	}
+	store, err := fcli.GetStageStore(workspace, sspath, versions.NewStageStore)
+	if err != nil {
+		return nil, err
+	}
+	current, ok := store.GetCurrent()
+	if !ok {
+		return nil, errors.New("no staged pallet bundle has been successfully applied yet")
+	}
+	fmt.Fprintf(os.Stderr, "Reconciling with the current staged pallet bundle: %d\n", current)
+	bundle, err := store.LoadFSBundle(current)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't load staged pallet bundle %d", current)
+	}
+	if err = fcli.CheckBundleShallowCompat(
+		bundle, versions.Tool, versions.MinSupportedBundle, ignoreToolVersion,
+	); err != nil {
+		return nil, err
+	}
+	if err = fcli.CheckStagedBundleTrust(0, store, bundle, current); err != nil {
+		return nil, err
+	}
+	if err = fcli.CheckStagedBundleIntegrity(0, bundle, current); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"runtime/debug"
+	"time"
 
 	"github.com/carlmjohnson/versioninfo"
 	"github.com/urfave/cli/v2"
@@ -54,7 +55,11 @@ var app = &cli.App{
 			NewStageStore:      newStageStoreVersion,
 		}),
 		cache.Cmd,
-		host.Cmd,
+		host.MakeCmd(host.Versions{
+			Tool:               toolVersion,
+			MinSupportedBundle: bundleMinVersion,
+			NewStageStore:      newStageStoreVersion,
+		}),
 		inspector.Cmd,
 		dev.MakeCmd(dev.Versions{
 			Staging:       fcliVersions,
@@ -89,6 +94,57 @@ var app = &cli.App{
 				"or starting containers",
 			EnvVars: []string{"FORKLIFT_PARALLEL"},
 		},
+		&cli.BoolFlag{
+			Name:  "precreate-networks",
+			Value: false,
+			Usage: "Create Docker networks shared among package deployments before adding or updating " +
+				"the deployments, so that the deployments don't need to wait for each other",
+			EnvVars: []string{"FORKLIFT_PRECREATE_NETWORKS"},
+		},
+		&cli.StringFlag{
+			Name:  "orphaned-volumes",
+			Value: fcli.OrphanedVolumesKeep,
+			Usage: "Policy (keep, remove, or report) for Docker volumes which are no longer declared by " +
+				"a Docker Compose app after the app is updated",
+			EnvVars: []string{"FORKLIFT_ORPHANED_VOLUMES"},
+		},
+		&cli.StringFlag{
+			Name:  "unowned-apps",
+			Value: fcli.UnownedAppsAdopt,
+			Usage: "Policy (adopt, ignore, migrate, or remove) for Docker Compose apps which weren't " +
+				"created by Forklift (use migrate once to take over unlabeled apps created by older " +
+				"versions of Forklift)",
+			EnvVars: []string{"FORKLIFT_UNOWNED_APPS"},
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Value:   fcli.OutputText,
+			Usage:   "Output format (text, json, or yaml) for commands which show or list information",
+			EnvVars: []string{"FORKLIFT_OUTPUT"},
+		},
+		&cli.DurationFlag{
+			Name:  "lock-timeout",
+			Value: time.Minute,
+			Usage: "How long to wait for another forklift process to release its lock on the stage " +
+				"store, the local pallet, or the cache before giving up",
+			EnvVars: []string{"FORKLIFT_LOCK_TIMEOUT"},
+		},
+		&cli.StringFlag{
+			Name:  "events",
+			Value: "",
+			Usage: "Format (json) of machine-readable events to emit about the progress of downloads, " +
+				"changes to the Docker host, and updates to the stage store; events are only emitted if " +
+				"this is set",
+			EnvVars: []string{"FORKLIFT_EVENTS"},
+		},
+		&cli.StringFlag{
+			Name:  "events-file",
+			Value: "-",
+			Usage: "Path of a file to append events to, or fd:N to write events to open file " +
+				"descriptor N, or - to write events to stdout",
+			EnvVars: []string{"FORKLIFT_EVENTS_FILE"},
+		},
 		&cli.StringFlag{
 			Name:    "platform",
 			Value:   defaultPlatform,
@@ -96,6 +152,19 @@ var app = &cli.App{
 			EnvVars: []string{"FORKLIFT_PLATFORM"},
 		},
 	},
+	Before: func(c *cli.Context) error {
+		if err := fcli.CheckOutputFormat(c.String("output")); err != nil {
+			return err
+		}
+		return fcli.OpenEventStream(c.String("events"), c.String("events-file"))
+	},
+	After: func(c *cli.Context) error {
+		releaseErr := fcli.ReleaseLocks()
+		if err := fcli.CloseEventStream(); err != nil {
+			return err
+		}
+		return releaseErr
+	},
 	Suggest: true,
 }
 
@@ -30,6 +30,7 @@ func MakeCmd(versions Versions) *cli.Command {
 					Usage: "Initializes or replaces the local pallet with the specified pallet, and " +
 						"stages the specified pallet",
 					ArgsUsage: "[[pallet_path]@[version_query]]",
+					Before:    lockAll,
 					Action:    switchAction(versions),
 					Flags: []cli.Flag{
 						&cli.BoolFlag{
@@ -63,6 +64,37 @@ func MakeCmd(versions Versions) *cli.Command {
 	}
 }
 
+// Locking
+
+// lockWorkspace returns a function which locks the specified parts of the workspace against
+// concurrent modification by other processes.
+func lockWorkspace(locks fcli.WorkspaceLocks) cli.BeforeFunc {
+	return func(c *cli.Context) error {
+		return fcli.LockWorkspace(
+			c.String("workspace"), c.String("stage-store"), locks, c.Duration("lock-timeout"),
+		)
+	}
+}
+
+var (
+	lockAll = lockWorkspace(fcli.WorkspaceLocks{
+		CurrentPallet: true, StageStore: true, Caches: true,
+	})
+	lockPalletAndStages = lockWorkspace(fcli.WorkspaceLocks{CurrentPallet: true, StageStore: true})
+	lockCaches          = lockWorkspace(fcli.WorkspaceLocks{Caches: true})
+)
+
+// lockForModify locks the local pallet against concurrent modification by other processes, as well
+// as the caches (if requirements will be cached) and the stage store (if the pallet will be
+// staged) after the modification.
+func lockForModify(c *cli.Context) error {
+	return lockWorkspace(fcli.WorkspaceLocks{
+		CurrentPallet: true,
+		StageStore:    c.Bool("stage") || c.Bool("apply"),
+		Caches:        c.Bool("cache-req"),
+	})(c)
+}
+
 func makeUpgradeSubcmds(versions Versions) []*cli.Command {
 	const category = "Upgrade the pallet"
 	return []*cli.Command{
@@ -72,6 +104,7 @@ func makeUpgradeSubcmds(versions Versions) []*cli.Command {
 			Usage: "Replaces the local pallet with an upgraded version, updates the cache, and " +
 				"stages the pallet",
 			ArgsUsage: "[[pallet_path]@[version_query]]",
+			Before:    lockAll,
 			Action:    upgradeAction(versions),
 			Flags:     upgradeFlags,
 		},
@@ -102,6 +135,7 @@ func makeUpgradeSubcmds(versions Versions) []*cli.Command {
 			Category:  category,
 			Usage:     "Changes the query used for pallet upgrades",
 			ArgsUsage: "[[pallet_path]@[version_query]]",
+			Before:    lockForModify,
 			Action:    setUpgradeQueryAction,
 		},
 	}
@@ -150,11 +184,29 @@ func makeUseSubcmds(versions Versions) []*cli.Command {
 			Usage: "Determines the changes needed to update the host to match the deployments " +
 				"specified by the local pallet",
 			Action: planAction(versions),
+			Flags:  planFlags,
+		},
+		&cli.Command{
+			Name:     "graph",
+			Category: category,
+			Usage: "Shows the dependency relationships among the package deployments specified by the " +
+				"local pallet, as a Graphviz DOT or Mermaid graph",
+			Action: graphAction(versions),
+			Flags:  graphFlags,
+		},
+		&cli.Command{
+			Name:     "sbom",
+			Category: category,
+			Usage: "Prints a software bill of materials for the local pallet, as a " +
+				"CycloneDX or SPDX JSON document",
+			Action: sbomAction(versions),
+			Flags:  sbomFlags,
 		},
 		&cli.Command{
 			Name:     "stage",
 			Category: category,
 			Usage:    "Builds and stages a bundle of the local pallet to be applied later",
+			Before:   lockPalletAndStages,
 			Action:   stageAction(versions),
 			Flags: []cli.Flag{
 				&cli.BoolFlag{
@@ -162,6 +214,11 @@ func makeUseSubcmds(versions Versions) []*cli.Command {
 					Usage: "Download container images",
 					Value: true,
 				},
+				&cli.BoolFlag{
+					Name: "bundle-img",
+					Usage: "Save the container images required by the pallet into the staged bundle, so " +
+						"that the bundle can be applied without internet access",
+				},
 			},
 		},
 		&cli.Command{
@@ -169,11 +226,60 @@ func makeUseSubcmds(versions Versions) []*cli.Command {
 			Category: category,
 			Usage: "Builds, stages, and immediately applies a bundle of the local pallet to update the " +
 				"host to match the deployments specified by the local pallet",
+			Before: lockPalletAndStages,
 			Action: applyAction(versions),
+			Flags:  applyFlags,
 		},
 	)
 }
 
+var planFlags = append([]cli.Flag{
+	&cli.StringFlag{
+		Name:  "out",
+		Usage: "Save the plan as a JSON file at the specified path, so that it can be applied later",
+	},
+}, deplSelectionFlags...)
+
+var graphFlags = append([]cli.Flag{
+	&cli.StringFlag{
+		Name:  "format",
+		Value: fcli.GraphFormatDOT,
+		Usage: "Graph format (dot or mermaid)",
+	},
+	&cli.BoolFlag{
+		Name: "changes",
+		Usage: "Show the ordering relationships among the changes needed to update the host, " +
+			"instead of the dependency relationships among package deployments",
+	},
+}, deplSelectionFlags...)
+
+var sbomFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "format",
+		Value: fcli.SBOMFormatCycloneDX,
+		Usage: "SBOM format (cyclonedx or spdx)",
+	},
+}
+
+var applyFlags = append([]cli.Flag{
+	&cli.IntFlag{
+		Name:  "retries",
+		Usage: "Retry each failed change the specified number of times, with exponential backoff",
+	},
+}, deplSelectionFlags...)
+
+var deplSelectionFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name: "only",
+		Usage: "Only change the specified package deployment and any deployments required by it " +
+			"(can be specified multiple times)",
+	},
+	&cli.StringSliceFlag{
+		Name:  "exclude",
+		Usage: "Leave the specified package deployment untouched (can be specified multiple times)",
+	},
+}
+
 func makeUseCacheSubcmds(versions Versions) []*cli.Command {
 	const category = "Use the pallet"
 	return []*cli.Command{
@@ -181,6 +287,7 @@ func makeUseCacheSubcmds(versions Versions) []*cli.Command {
 			Name:     "cache-all",
 			Category: category,
 			Usage:    "Updates the cache with everything needed by the local pallet",
+			Before:   lockCaches,
 			Action:   cacheAllAction(versions),
 			Flags: []cli.Flag{
 				&cli.BoolFlag{
@@ -194,6 +301,7 @@ func makeUseCacheSubcmds(versions Versions) []*cli.Command {
 			Aliases:  []string{"cache-pallets"},
 			Category: category,
 			Usage:    "Updates the cache with the pallets required by the local pallet",
+			Before:   lockCaches,
 			Action:   cachePltAction(versions),
 		},
 		{
@@ -201,6 +309,7 @@ func makeUseCacheSubcmds(versions Versions) []*cli.Command {
 			Aliases:  []string{"cache-repositories"},
 			Category: category,
 			Usage:    "Updates the cache with the repos required by the local pallet",
+			Before:   lockCaches,
 			Action:   cacheRepoAction(versions),
 		},
 		{
@@ -208,6 +317,7 @@ func makeUseCacheSubcmds(versions Versions) []*cli.Command {
 			Aliases:  []string{"cache-downloads"},
 			Category: category,
 			Usage:    "Pre-downloads files to be exported by the local pallet",
+			Before:   lockCaches,
 			Action:   cacheDlAction(versions),
 		},
 		{
@@ -537,6 +647,7 @@ func makeModifySubcmds(versions Versions) []*cli.Command {
 				Aliases:  []string{"delete"},
 				Category: category,
 				Usage:    "Removes the local pallet",
+				Before:   lockForModify,
 				Action:   delAction,
 			},
 		},
@@ -578,6 +689,7 @@ func makeModifyGitSubcmds(versions Versions) []*cli.Command {
 				},
 				modifyBaseFlags,
 			),
+			Before: lockForModify,
 			Action: cloneAction(versions),
 		},
 		// TODO: add a "checkout @version_query" action; it needs a --force flag to overwrite a dirty
@@ -586,6 +698,7 @@ func makeModifyGitSubcmds(versions Versions) []*cli.Command {
 			Name:     "fetch",
 			Category: category,
 			Usage:    "Updates information about the remote release",
+			Before:   lockForModify,
 			Action:   fetchAction,
 		},
 		{
@@ -603,6 +716,7 @@ func makeModifyGitSubcmds(versions Versions) []*cli.Command {
 				},
 				modifyBaseFlags,
 			),
+			Before: lockForModify,
 			Action: pullAction(versions),
 		},
 		// TODO: add a "push" action?
@@ -650,6 +764,7 @@ func makeModifyFileSubcmds() []*cli.Command {
 			Category:  category,
 			Usage:     "Edits the specified file in the development pallet",
 			ArgsUsage: "file_path",
+			Before:    lockForModify,
 			Action:    editFileAction,
 			Flags: []cli.Flag{
 				&cli.StringFlag{
@@ -665,6 +780,7 @@ func makeModifyFileSubcmds() []*cli.Command {
 			Category:  category,
 			Usage:     "Removes the specified file in the development pallet",
 			ArgsUsage: "file_path",
+			Before:    lockForModify,
 			Action:    delFileAction,
 		},
 	}
@@ -691,6 +807,7 @@ func makeModifyPltSubcmds(versions Versions) []*cli.Command {
 					Value: true,
 				},
 			},
+			Before: lockForModify,
 			Action: addPltAction(versions),
 		},
 		// TODO: add an upgrade-plt [plt_path]... command (upgrade all if no args)
@@ -713,6 +830,7 @@ func makeModifyPltSubcmds(versions Versions) []*cli.Command {
 						"depend on them",
 				},
 			},
+			Before: lockForModify,
 			Action: delPltAction(versions),
 		},
 	}
@@ -739,6 +857,7 @@ func makeModifyRepoSubcmds(versions Versions) []*cli.Command {
 					Value: true,
 				},
 			},
+			Before: lockForModify,
 			Action: addRepoAction(versions),
 		},
 		// TODO: add an upgrade-repo [repo_path]... command (upgrade all if no args)
@@ -762,6 +881,7 @@ func makeModifyRepoSubcmds(versions Versions) []*cli.Command {
 						"depend on them",
 				},
 			},
+			Before: lockForModify,
 			Action: delRepoAction(versions),
 		},
 	}
@@ -797,6 +917,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 				},
 				modifyDeplBaseFlags,
 			),
+			Before: lockForModify,
 			Action: addDeplAction(versions),
 		},
 		{
@@ -806,6 +927,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 			Usage:     "Removes deployment from the pallet",
 			ArgsUsage: "deployment_name...",
 			Flags:     modifyDeplBaseFlags,
+			Before:    lockForModify,
 			Action:    delDeplAction(versions),
 		},
 		{
@@ -824,6 +946,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 				},
 				modifyDeplBaseFlags,
 			),
+			Before: lockForModify,
 			Action: setDeplPkgAction(versions),
 		},
 		{
@@ -848,6 +971,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 				},
 				modifyDeplBaseFlags,
 			),
+			Before: lockForModify,
 			Action: addDeplFeatAction(versions),
 		},
 		{
@@ -860,6 +984,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 			Usage:     "Disables the specified package features in the specified deployment",
 			ArgsUsage: "deployment_name feature_name...",
 			Flags:     modifyDeplBaseFlags,
+			Before:    lockForModify,
 			Action:    delDeplFeatAction(versions),
 		},
 		{
@@ -869,6 +994,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 			Usage:     "Disables the specified deployment",
 			ArgsUsage: "deployment_name",
 			Flags:     modifyDeplBaseFlags,
+			Before:    lockForModify,
 			Action:    setDeplDisabledAction(versions, true),
 		},
 		{
@@ -878,6 +1004,7 @@ func makeModifyDeplSubcmds( //nolint:funlen // this is already decomposed; it's
 			Usage:     "Enables the specified deployment",
 			ArgsUsage: "deployment_name",
 			Flags:     modifyDeplBaseFlags,
+			Before:    lockForModify,
 			Action:    setDeplDisabledAction(versions, false),
 		},
 	}
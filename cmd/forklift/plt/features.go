@@ -18,7 +18,7 @@ func lsFeatAction(c *cli.Context) error {
 		return err
 	}
 
-	return fcli.FprintPalletFeatures(0, os.Stdout, plt)
+	return fcli.FprintPalletFeatures(0, os.Stdout, c.String("output"), plt)
 }
 
 // show-feat
@@ -31,5 +31,5 @@ func showFeatAction(c *cli.Context) error {
 		return err
 	}
 
-	return fcli.FprintFeatureInfo(0, os.Stdout, plt, caches.p, c.Args().First())
+	return fcli.FprintFeatureInfo(0, os.Stdout, c.String("output"), plt, caches.p, c.Args().First())
 }
@@ -16,7 +16,7 @@ func lsImpAction(c *cli.Context) error {
 		return err
 	}
 
-	return fcli.FprintPalletImports(0, os.Stdout, plt)
+	return fcli.FprintPalletImports(0, os.Stdout, c.String("output"), plt)
 }
 
 // show-imp
@@ -30,5 +30,5 @@ func showImpAction(c *cli.Context) error {
 	}
 
 	importName := c.Args().First()
-	return fcli.FprintImportInfo(0, os.Stdout, plt, caches.p, importName)
+	return fcli.FprintImportInfo(0, os.Stdout, c.String("output"), plt, caches.p, importName)
 }
@@ -19,7 +19,7 @@ func lsPkgAction(c *cli.Context) error {
 		return err
 	}
 
-	return fcli.FprintPalletPkgs(0, os.Stdout, plt, caches.r)
+	return fcli.FprintPalletPkgs(0, os.Stdout, c.String("output"), plt, caches.r)
 }
 
 // locate-pkg
@@ -47,5 +47,5 @@ func showPkgAction(c *cli.Context) error {
 		return err
 	}
 
-	return fcli.FprintPkgInfo(0, os.Stdout, plt, caches.r, c.Args().First())
+	return fcli.FprintPkgInfo(0, os.Stdout, c.String("output"), plt, caches.r, c.Args().First())
 }
@@ -822,7 +822,7 @@ func showAction(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	return fcli.FprintPalletInfo(0, os.Stdout, plt)
+	return fcli.FprintPalletInfo(0, os.Stdout, c.String("output"), plt)
 }
 
 // check
@@ -852,6 +852,26 @@ func checkAction(versions Versions) cli.ActionFunc {
 
 // plan
 
+// planOptions returns the options for planning changes to the Docker host, as set by global flags
+// and by the deployment selection flags of the subcommand.
+func planOptions(c *cli.Context) fcli.PlanOptions {
+	return fcli.PlanOptions{
+		Parallel:          c.Bool("parallel"),
+		PrecreateNetworks: c.Bool("precreate-networks"),
+		OrphanedVolumes:   c.String("orphaned-volumes"),
+		UnownedApps:       c.String("unowned-apps"),
+		Only:              c.StringSlice("only"),
+		Exclude:           c.StringSlice("exclude"),
+	}
+}
+
+func applyOptions(c *cli.Context) fcli.ApplyOptions {
+	return fcli.ApplyOptions{
+		PlanOptions: planOptions(c),
+		Retries:     c.Int("retries"),
+	}
+}
+
 func planAction(versions Versions) cli.ActionFunc {
 	return func(c *cli.Context) error {
 		plt, caches, err := processFullBaseArgs(c.String("workspace"), processingOptions{
@@ -868,7 +888,14 @@ func planAction(versions Versions) cli.ActionFunc {
 			return err
 		}
 
-		if _, _, err = fcli.Plan(0, plt, caches.r, c.Bool("parallel")); err != nil {
+		if outputPath := c.String("out"); outputPath != "" {
+			plan, err := fcli.PlanToSave(0, plt, caches.r, planOptions(c), 0)
+			if err != nil {
+				return err
+			}
+			return fcli.WriteSavedPlan(plan, outputPath)
+		}
+		if _, _, err = fcli.Plan(0, plt, caches.r, planOptions(c)); err != nil {
 			return errors.Wrap(
 				err, "couldn't deploy local pallet (have you run `forklift plt cache` recently?)",
 			)
@@ -877,6 +904,57 @@ func planAction(versions Versions) cli.ActionFunc {
 	}
 }
 
+// graph
+
+func graphAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		plt, caches, err := processFullBaseArgs(c.String("workspace"), processingOptions{
+			requirePalletCache: true,
+			requireRepoCache:   true,
+			merge:              true,
+		})
+		if err != nil {
+			return err
+		}
+		if err = fcli.CheckDeepCompat(
+			plt, caches.p, caches.r, versions.Core(), c.Bool("ignore-tool-version"),
+		); err != nil {
+			return err
+		}
+
+		if c.Bool("changes") {
+			return fcli.FprintChangeGraph(
+				0, os.Stdout, c.String("format"), plt, caches.r, planOptions(c),
+			)
+		}
+		return fcli.FprintDeplGraph(0, os.Stdout, c.String("format"), plt, caches.r)
+	}
+}
+
+// sbom
+
+func sbomAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		plt, caches, err := processFullBaseArgs(c.String("workspace"), processingOptions{
+			requirePalletCache: true,
+			requireRepoCache:   true,
+			merge:              true,
+		})
+		if err != nil {
+			return err
+		}
+		if err = fcli.CheckDeepCompat(
+			plt, caches.p, caches.r, versions.Core(), c.Bool("ignore-tool-version"),
+		); err != nil {
+			return err
+		}
+
+		return fcli.FprintPalletSBOM(
+			0, os.Stdout, c.String("format"), plt, caches.p, caches.r, versions.Core().Tool,
+		)
+	}
+}
+
 // stage
 
 func stageAction(versions Versions) cli.ActionFunc {
@@ -903,8 +981,8 @@ func stageAction(versions Versions) cli.ActionFunc {
 		}
 		if _, err = fcli.StagePallet(
 			0, plt, stageStore, caches.staging(), c.String("exports"),
-			versions.Staging, !c.Bool("cache-img"), c.String("platform"), c.Bool("parallel"),
-			c.Bool("ignore-tool-version"),
+			versions.Staging, !c.Bool("cache-img"), c.Bool("bundle-img"), c.String("platform"),
+			c.Bool("parallel"), c.Bool("ignore-tool-version"),
 		); err != nil {
 			return err
 		}
@@ -943,7 +1021,7 @@ func applyAction(versions Versions) cli.ActionFunc {
 		}
 		index, err := fcli.StagePallet(
 			0, plt, stageStore, caches.staging(), c.String("exports"),
-			versions.Staging, false, c.String("platform"), c.Bool("parallel"),
+			versions.Staging, false, false, c.String("platform"), c.Bool("parallel"),
 			c.Bool("ignore-tool-version"),
 		)
 		if err != nil {
@@ -954,7 +1032,9 @@ func applyAction(versions Versions) cli.ActionFunc {
 		if err != nil {
 			return errors.Wrapf(err, "couldn't load staged pallet bundle %d", index)
 		}
-		if err = fcli.ApplyNextOrCurrentBundle(0, stageStore, bundle, c.Bool("parallel")); err != nil {
+		if err = fcli.ApplyNextOrCurrentBundle(
+			0, stageStore, bundle, versions.Core().Tool, applyOptions(c),
+		); err != nil {
 			return errors.Wrapf(err, "couldn't apply staged pallet bundle %d", index)
 		}
 		fmt.Fprintln(os.Stderr, "Done! You may need to reboot for some changes to take effect.")
@@ -1011,7 +1091,7 @@ func lsPltAction(c *cli.Context) error {
 		return err
 	}
 
-	return fcli.FprintRequiredPallets(0, os.Stdout, plt)
+	return fcli.FprintRequiredPallets(0, os.Stdout, c.String("output"), plt)
 }
 
 // show-plt
@@ -1024,7 +1104,9 @@ func showPltAction(c *cli.Context) error {
 		return err
 	}
 
-	return fcli.FprintRequiredPalletInfo(0, os.Stdout, plt, caches.p, c.Args().First())
+	return fcli.FprintRequiredPalletInfo(
+		0, os.Stdout, c.String("output"), plt, caches.p, c.Args().First(),
+	)
 }
 
 // show-plt-version
@@ -1173,7 +1255,7 @@ func lsPltFeatAction(c *cli.Context) error {
 	if err != nil {
 		return nil
 	}
-	return fcli.FprintPalletFeatures(0, os.Stdout, plt)
+	return fcli.FprintPalletFeatures(0, os.Stdout, c.String("output"), plt)
 }
 
 // show-plt-feat
@@ -1188,5 +1270,5 @@ func showPltFeatAction(c *cli.Context) error {
 	if err != nil {
 		return nil
 	}
-	return fcli.FprintFeatureInfo(0, os.Stdout, plt, caches.p, c.Args().Get(1))
+	return fcli.FprintFeatureInfo(0, os.Stdout, c.String("output"), plt, caches.p, c.Args().Get(1))
 }
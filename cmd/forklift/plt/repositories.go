@@ -49,7 +49,7 @@ func lsRepoAction(c *cli.Context) error {
 		return err
 	}
 
-	return fcli.FprintRequiredRepos(0, os.Stdout, plt)
+	return fcli.FprintRequiredRepos(0, os.Stdout, c.String("output"), plt)
 }
 
 // locate-repo
@@ -77,7 +77,9 @@ func showRepoAction(c *cli.Context) error {
 		return err
 	}
 
-	return fcli.FprintRequiredRepoInfo(0, os.Stdout, plt, caches.r, c.Args().First())
+	return fcli.FprintRequiredRepoInfo(
+		0, os.Stdout, c.String("output"), plt, caches.r, c.Args().First(),
+	)
 }
 
 // show-repo-version
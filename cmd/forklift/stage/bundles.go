@@ -2,6 +2,7 @@ package stage
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"slices"
@@ -32,6 +33,9 @@ func lsBunAction(versions Versions) cli.ActionFunc {
 		if err != nil {
 			return err
 		}
+		if format := c.String("output"); fcli.IsStructuredOutput(format) {
+			return fprintBundleDocuments(os.Stdout, format, store, indices, names)
+		}
 		for _, index := range indices {
 			printBundleSummary(store, index, names)
 		}
@@ -62,6 +66,21 @@ func getBundleNames(store *forklift.FSStageStore) map[int][]string {
 	return names
 }
 
+func fprintBundleDocuments(
+	out io.Writer, format string,
+	store *forklift.FSStageStore, indices []int, names map[int][]string,
+) error {
+	docs := make([]fcli.StagedBundleDocument, 0, len(indices))
+	for _, index := range indices {
+		bundle, err := store.LoadFSBundle(index)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't load staged bundle %d", index)
+		}
+		docs = append(docs, fcli.NewStagedBundleDocument(bundle, index, names[index]))
+	}
+	return fcli.FprintDocument(out, format, docs)
+}
+
 func printBundleSummary(store *forklift.FSStageStore, index int, names map[int][]string) {
 	bundle, err := store.LoadFSBundle(index)
 	if err != nil {
@@ -102,11 +121,146 @@ func showBunAction(versions Versions) cli.ActionFunc {
 		if err != nil {
 			return errors.Wrapf(err, "couldn't load staged bundle %d", index)
 		}
-		fcli.FprintStagedBundle(0, os.Stdout, store, bundle, index, getBundleNames(store)[index])
+		return fcli.FprintStagedBundle(
+			0, os.Stdout, c.String("output"), store, bundle, index, getBundleNames(store)[index],
+		)
+	}
+}
+
+// verify-bun
+
+func verifyBunAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+
+		index, err := resolveBundleIdentifier(c.Args().First(), store)
+		if err != nil {
+			return err
+		}
+		bundle, err := store.LoadFSBundle(index)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't load staged bundle %d", index)
+		}
+		mismatches, err := bundle.CheckIntegrity()
+		if err != nil {
+			return errors.Wrapf(err, "couldn't check integrity of staged pallet bundle %d", index)
+		}
+		if len(mismatches) > 0 {
+			fmt.Printf("Staged pallet bundle %d is corrupted:\n", index)
+			for _, mismatch := range mismatches {
+				fcli.BulletedPrintln(1, mismatch)
+			}
+			return errors.Errorf("staged pallet bundle %d failed its integrity check", index)
+		}
+		fmt.Printf("Staged pallet bundle %d matches its integrity manifest\n", index)
 		return nil
 	}
 }
 
+// diff-bun
+
+func diffBunAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+
+		if c.Args().Len() != 2 {
+			return errors.New("exactly two staged pallet bundles must be specified for comparison")
+		}
+		fromIndex, err := resolveBundleIdentifier(c.Args().Get(0), store)
+		if err != nil {
+			return err
+		}
+		from, err := store.LoadFSBundle(fromIndex)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't load staged bundle %d", fromIndex)
+		}
+		toIndex, err := resolveBundleIdentifier(c.Args().Get(1), store)
+		if err != nil {
+			return err
+		}
+		to, err := store.LoadFSBundle(toIndex)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't load staged bundle %d", toIndex)
+		}
+
+		diff, err := fcli.DiffBundles(from, fromIndex, to, toIndex)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't compare staged bundles %d and %d", fromIndex, toIndex)
+		}
+		return fcli.FprintBundleDiff(0, os.Stdout, c.String("output"), diff)
+	}
+}
+
+// export-bun
+
+func exportBunAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+
+		if c.Args().Len() != 2 {
+			return errors.New("a staged pallet bundle and an archive path must be specified")
+		}
+		index, err := resolveBundleIdentifier(c.Args().Get(0), store)
+		if err != nil {
+			return err
+		}
+		dlCache, err := fcli.GetDownloadCache(c.String("workspace"), false)
+		if err != nil {
+			return err
+		}
+		return fcli.ExportStagedBundle(
+			0, store, index, dlCache, c.Args().Get(1), versions.Tool, c.Bool("images"),
+		)
+	}
+}
+
+// sbom
+
+func sbomAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+
+		index, err := resolveBundleIdentifier(c.Args().First(), store)
+		if err != nil {
+			return err
+		}
+		bundle, err := store.LoadFSBundle(index)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't load staged bundle %d", index)
+		}
+		if err = fcli.CheckBundleShallowCompat(
+			bundle, versions.Tool, versions.MinSupportedBundle, c.Bool("ignore-tool-version"),
+		); err != nil {
+			return err
+		}
+		return fcli.FprintBundleSBOM(0, os.Stdout, c.String("format"), bundle, versions.Tool)
+	}
+}
+
 // locate-bun
 
 func locateBunAction(versions Versions) cli.ActionFunc {
@@ -128,6 +282,54 @@ func locateBunAction(versions Versions) cli.ActionFunc {
 	}
 }
 
+// import-bun
+
+func importBunAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+
+		name := c.String("name")
+		if name != "" {
+			if err = checkBundleName(name); err != nil {
+				return err
+			}
+		}
+		dlCache, err := fcli.GetDownloadCache(c.String("workspace"), true)
+		if err != nil {
+			return err
+		}
+		index, err := fcli.ImportStagedBundle(
+			0, store, dlCache, c.Args().First(), c.Bool("load-img"),
+		)
+		if err != nil {
+			return err
+		}
+
+		if name != "" {
+			store.Manifest.Stages.Names[name] = index
+			// The bundle was imported even if it can't be set as the next one to be applied, so we
+			// commit its name first:
+			if err = fcli.CommitStageStore(store); err != nil {
+				return err
+			}
+		}
+		if !c.Bool("next") {
+			return nil
+		}
+		// The bundle's images were either loaded from the archive or will need to be downloaded
+		// later (e.g. with `forklift stage cache-img`), since an imported bundle is usually staged on
+		// a host without internet access:
+		const skipImageCaching = true
+		return fcli.SetNextStagedBundle(
+			0, store, index, c.String("exports"), versions.Tool, versions.MinSupportedBundle,
+			skipImageCaching, c.String("platform"), c.Bool("parallel"), c.Bool("ignore-tool-version"),
+		)
+	}
+}
+
 // del-bun
 
 const knownSnippet = "last staged pallet bundle known to have been successfully applied"
@@ -183,7 +385,7 @@ func delBunAction(versions Versions) cli.ActionFunc {
 		// Note: we commit the state before deleting the stage (rather than the other way around)
 		// because it's better to accidentally leave the stage on the filesystem than to have indices
 		// of deleted bundles in our history/names/next-state.
-		if err = store.CommitState(); err != nil {
+		if err = fcli.CommitStageStore(store); err != nil {
 			return errors.Wrap(err, "couldn't commit the stage store's new state!")
 		}
 		fmt.Fprintln(os.Stderr, "Deleting the staged pallet bundle from the filesystem...")
@@ -238,3 +440,25 @@ func pruneBunAction(versions Versions) cli.ActionFunc {
 		return nil
 	}
 }
+
+// gc
+
+func gcAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+
+		if store.Manifest.Retention == nil {
+			return errors.New(
+				"the stage store has no retention policy: you first must set one, e.g. with " +
+					"`forklift stage set-retention`",
+			)
+		}
+		return fcli.GCStageStore(0, store, *store.Manifest.Retention, c.Bool("dry-run"))
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"slices"
 
 	"github.com/urfave/cli/v2"
+
+	fcli "github.com/forklift-run/forklift/internal/app/forklift/cli"
 )
 
 type Versions struct {
@@ -21,10 +23,28 @@ func MakeCmd(versions Versions) *cli.Command {
 			makeUseSubcmds(versions),
 			makeQuerySubcmds(versions),
 			makeModifySubcmds(versions),
+			makeSignSubcmds(versions),
 		),
 	}
 }
 
+// lockStageStore locks the stage store against concurrent modification by other processes.
+func lockStageStore(c *cli.Context) error {
+	return fcli.LockWorkspace(
+		c.String("workspace"), c.String("stage-store"), fcli.WorkspaceLocks{StageStore: true},
+		c.Duration("lock-timeout"),
+	)
+}
+
+// lockStageStoreAndCaches locks the stage store and the workspace's caches against concurrent
+// modification by other processes.
+func lockStageStoreAndCaches(c *cli.Context) error {
+	return fcli.LockWorkspace(
+		c.String("workspace"), c.String("stage-store"),
+		fcli.WorkspaceLocks{StageStore: true, Caches: true}, c.Duration("lock-timeout"),
+	)
+}
+
 func makeUseSubcmds(versions Versions) []*cli.Command {
 	const category = "Use the stage store"
 	return []*cli.Command{
@@ -46,17 +66,74 @@ func makeUseSubcmds(versions Versions) []*cli.Command {
 			Category: category,
 			Usage:    "Determines the changes needed to update the host for the next apply",
 			Action:   planAction(versions),
+			Flags:    planFlags,
+		},
+		{
+			Name:     "graph",
+			Category: category,
+			Usage: "Shows the dependency relationships among the package deployments of a staged " +
+				"pallet bundle, as a Graphviz DOT or Mermaid graph",
+			ArgsUsage: "bundle_index_or_name",
+			Action:    graphAction(versions),
+			Flags:     graphFlags,
 		},
 		{
 			Name:     "apply",
 			Category: category,
 			Usage: "Updates the host according to the next staged pallet, falling back to the last " +
 				"successfully-staged pallet if the next one already failed",
+			Before: lockStageStore,
 			Action: applyAction(versions),
+			Flags: append([]cli.Flag{
+				&cli.StringFlag{
+					Name: "plan",
+					Usage: "Only apply the bundle if its plan exactly matches the plan saved at the " +
+						"specified path (by `forklift stage plan --out`), using the saved plan's options",
+				},
+			}, applyFlags...),
 		},
 	}
 }
 
+var planFlags = append([]cli.Flag{
+	&cli.StringFlag{
+		Name:  "out",
+		Usage: "Save the plan as a JSON file at the specified path, so that it can be applied later",
+	},
+}, deplSelectionFlags...)
+
+var graphFlags = append([]cli.Flag{
+	&cli.StringFlag{
+		Name:  "format",
+		Value: fcli.GraphFormatDOT,
+		Usage: "Graph format (dot or mermaid)",
+	},
+	&cli.BoolFlag{
+		Name: "changes",
+		Usage: "Show the ordering relationships among the changes needed to update the host, " +
+			"instead of the dependency relationships among package deployments",
+	},
+}, deplSelectionFlags...)
+
+var applyFlags = append([]cli.Flag{
+	&cli.IntFlag{
+		Name:  "retries",
+		Usage: "Retry each failed change the specified number of times, with exponential backoff",
+	},
+}, deplSelectionFlags...)
+
+var deplSelectionFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name: "only",
+		Usage: "Only change the specified package deployment and any deployments required by it " +
+			"(can be specified multiple times)",
+	},
+	&cli.StringSliceFlag{
+		Name:  "exclude",
+		Usage: "Leave the specified package deployment untouched (can be specified multiple times)",
+	},
+}
+
 func makeQuerySubcmds(versions Versions) []*cli.Command {
 	const category = "Query the stage store"
 	return append(
@@ -73,6 +150,20 @@ func makeQuerySubcmds(versions Versions) []*cli.Command {
 				Category: category,
 				Usage:    "Shows the history of successfully-applied staged pallet bundles",
 				Action:   showHistAction(versions),
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name: "journal",
+						Usage: "Show the journal of all attempts to apply staged pallet bundles (with their " +
+							"times, durations, results, and errors) instead",
+					},
+				},
+			},
+			{
+				Name:     "check-drift",
+				Category: category,
+				Usage: "Checks whether the Docker host still matches the last successfully-applied " +
+					"staged pallet bundle",
+				Action: checkDriftAction(versions),
 			},
 			{
 				Name:     "show-next-index",
@@ -118,6 +209,39 @@ func makeQueryBunSubcmds(versions Versions) []*cli.Command {
 			ArgsUsage: "bundle_index_or_name deployment_name",
 			Action:    showBunDeplAction(versions),
 		},
+		{
+			Name:     "diff-bun",
+			Aliases:  []string{"diff-bundles"},
+			Category: category,
+			Usage: "Compares two staged pallet bundles, showing the changes from the first bundle to " +
+				"the second bundle",
+			ArgsUsage: "from_bundle_index_or_name to_bundle_index_or_name",
+			Action:    diffBunAction(versions),
+		},
+		{
+			Name:     "verify-bun",
+			Aliases:  []string{"verify-bundle"},
+			Category: category,
+			Usage: "Checks the files of the specified staged pallet bundle against the hashes recorded " +
+				"when the bundle was made, to detect corruption",
+			ArgsUsage: "bundle_index_or_name",
+			Action:    verifyBunAction(versions),
+		},
+		{
+			Name:     "sbom",
+			Category: category,
+			Usage: "Prints a software bill of materials for the specified staged pallet bundle, as a " +
+				"CycloneDX or SPDX JSON document",
+			ArgsUsage: "bundle_index_or_name",
+			Action:    sbomAction(versions),
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "format",
+					Value: fcli.SBOMFormatCycloneDX,
+					Usage: "SBOM format (cyclonedx or spdx)",
+				},
+			},
+		},
 		{
 			Name:      "locate-bun",
 			Aliases:   []string{"locate-bundle"},
@@ -126,6 +250,22 @@ func makeQueryBunSubcmds(versions Versions) []*cli.Command {
 			ArgsUsage: "bundle_index_or_name",
 			Action:    locateBunAction(versions),
 		},
+		{
+			Name:     "export-bun",
+			Aliases:  []string{"export-bundle"},
+			Category: category,
+			Usage: "Writes the specified staged pallet bundle and the downloads it requires to a " +
+				"single archive file, for transfer to a host without internet access",
+			ArgsUsage: "bundle_index_or_name archive_path",
+			Action:    exportBunAction(versions),
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name: "images",
+					Usage: "Also include the container images required by the bundle, which must " +
+						"already have been downloaded into Docker",
+				},
+			},
+		},
 		{
 			Name:     "locate-bun-depl-pkg",
 			Aliases:  []string{"locate-bundle-deployment-package"},
@@ -148,6 +288,7 @@ func makeModifySubcmds(versions Versions) []*cli.Command {
 			Usage: "Sets the specified staged pallet bundle as the next one to be applied, then " +
 				"caches required images",
 			ArgsUsage: "bundle_index_or_name",
+			Before:    lockStageStore,
 			Action:    setNextAction(versions),
 			Flags: []cli.Flag{
 				&cli.BoolFlag{
@@ -161,13 +302,47 @@ func makeModifySubcmds(versions Versions) []*cli.Command {
 			Name:     "unset-next",
 			Category: category,
 			Usage:    "Updates the store so that no staged pallet bundle will be applied next",
+			Before:   lockStageStore,
 			Action:   unsetNextAction(versions),
 		},
+		&cli.Command{
+			Name:     "set-retention",
+			Category: category,
+			Usage: "Sets the policy for which staged pallet bundles to keep when the stage store is " +
+				"garbage-collected after staging a pallet or by `forklift stage gc`; the next, " +
+				"current, and rollback staged pallet bundles are always kept",
+			Before: lockStageStore,
+			Action: setRetentionAction(versions),
+			Flags: []cli.Flag{
+				&cli.IntFlag{
+					Name:  "keep-applied",
+					Usage: "Keep the specified number of most-recently-applied staged pallet bundles",
+				},
+				&cli.BoolFlag{
+					Name:  "keep-named",
+					Usage: "Keep all staged pallet bundles which have names",
+				},
+				&cli.StringFlag{
+					Name: "keep-younger-than",
+					Usage: "Keep all staged pallet bundles staged within the specified duration " +
+						"(e.g. 168h)",
+				},
+			},
+		},
+		&cli.Command{
+			Name:     "unset-retention",
+			Category: category,
+			Usage: "Removes the stage store's retention policy, so that it won't be garbage-collected " +
+				"automatically",
+			Before: lockStageStore,
+			Action: unsetRetentionAction(versions),
+		},
 		&cli.Command{
 			Name:      "set-next-result",
 			Category:  category,
 			Usage:     "Manually records that the next staged pallet to apply was applied (un)successfully",
 			ArgsUsage: "pending|success|failure",
+			Before:    lockStageStore,
 			Action:    setNextResultAction(versions),
 		},
 	)
@@ -183,6 +358,7 @@ func makeModifyBunSubcmds(versions Versions) []*cli.Command {
 			Usage: "Assigns the specified name to the specified staged pallet bundle; if the name was " +
 				"already assigned, it's reassigned",
 			ArgsUsage: "bundle_name_to_assign bundle_index_or_name",
+			Before:    lockStageStore,
 			Action:    addBunNameAction(versions),
 		},
 		{
@@ -191,14 +367,41 @@ func makeModifyBunSubcmds(versions Versions) []*cli.Command {
 			Category:  category,
 			Usage:     "Unsets a name for a staged pallet bundle",
 			ArgsUsage: "bundle_name",
+			Before:    lockStageStore,
 			Action:    delBunNameAction(versions),
 		},
+		{
+			Name:     "import-bun",
+			Aliases:  []string{"import-bundle"},
+			Category: category,
+			Usage: "Checks and adds the pallet bundle from an archive file (made by " +
+				"`forklift stage export-bun`) to the stage store as a new staged pallet bundle",
+			ArgsUsage: "archive_path",
+			Before:    lockStageStoreAndCaches,
+			Action:    importBunAction(versions),
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "name",
+					Usage: "Assign the specified name to the new staged pallet bundle",
+				},
+				&cli.BoolFlag{
+					Name:  "next",
+					Usage: "Set the new staged pallet bundle as the next one to be applied",
+				},
+				&cli.BoolFlag{
+					Name:  "load-img",
+					Usage: "Load any container images included in the archive into Docker",
+					Value: true,
+				},
+			},
+		},
 		{
 			Name:      "del-bun",
 			Aliases:   []string{"delete-bundle"},
 			Category:  category,
 			Usage:     "Deletes the specified staged pallet bundle",
 			ArgsUsage: "bundle_index_or_name",
+			Before:    lockStageStore,
 			Action:    delBunAction(versions),
 		},
 		{
@@ -206,7 +409,101 @@ func makeModifyBunSubcmds(versions Versions) []*cli.Command {
 			Aliases:  []string{"prune-bundles"},
 			Category: category,
 			Usage:    "Deletes all staged pallet bundles not referenced in names or in the history",
+			Before:   lockStageStore,
 			Action:   pruneBunAction(versions),
 		},
+		{
+			Name:     "gc",
+			Category: category,
+			Usage: "Deletes all staged pallet bundles not kept by the stage store's retention policy, " +
+				"and removes them from the history",
+			Before: lockStageStore,
+			Action: gcAction(versions),
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Only print which staged pallet bundles would be deleted",
+				},
+			},
+		},
+	}
+}
+
+func makeSignSubcmds(versions Versions) []*cli.Command {
+	const category = "Sign staged pallet bundles"
+	return []*cli.Command{
+		{
+			Name:     "gen-sig-key",
+			Category: category,
+			Usage: "Generates an Ed25519 key pair for signing staged pallet bundles, saving the " +
+				"public key next to the private key with a .pub suffix",
+			ArgsUsage: "private_key_path",
+			Action:    genSigKeyAction,
+		},
+		{
+			Name:     "sign-bun",
+			Aliases:  []string{"sign-bundle"},
+			Category: category,
+			Usage: "Signs the canonical hash of the contents of the specified staged pallet bundle, " +
+				"saving a detached signature in the bundle",
+			ArgsUsage: "bundle_index_or_name",
+			Before:    lockStageStore,
+			Action:    signBunAction(versions),
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "key",
+					Usage:    "Sign with the Ed25519 private key in the specified PEM file",
+					Required: true,
+				},
+			},
+		},
+		{
+			Name:     "check-bun-sig",
+			Aliases:  []string{"check-bundle-signature"},
+			Category: category,
+			Usage: "Checks whether the specified staged pallet bundle has a valid signature by a " +
+				"trusted key",
+			ArgsUsage: "bundle_index_or_name",
+			Action:    checkBunSigAction(versions),
+		},
+		{
+			Name:     "show-sig-trust",
+			Category: category,
+			Usage: "Describes which keys are trusted to sign staged pallet bundles, and whether " +
+				"signatures are required",
+			Action: showSigTrustAction(versions),
+		},
+		{
+			Name:     "trust-sig-key",
+			Category: category,
+			Usage: "Trusts the Ed25519 public key in the specified PEM file to sign staged pallet " +
+				"bundles",
+			ArgsUsage: "public_key_path",
+			Before:    lockStageStore,
+			Action:    trustSigKeyAction(versions),
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "name",
+					Usage: "Name the trusted key",
+				},
+			},
+		},
+		{
+			Name:      "distrust-sig-key",
+			Category:  category,
+			Usage:     "Stops trusting the specified key to sign staged pallet bundles",
+			ArgsUsage: "key_name_or_public_key",
+			Before:    lockStageStore,
+			Action:    distrustSigKeyAction(versions),
+		},
+		{
+			Name:     "set-sig-enforcement",
+			Category: category,
+			Usage: "Sets whether `forklift stage apply` and `forklift stage set-next` should refuse " +
+				"staged pallet bundles without valid signatures by trusted keys",
+			ArgsUsage: "on|off",
+			Before:    lockStageStore,
+			Action:    setSigEnforcementAction(versions),
+		},
 	}
 }
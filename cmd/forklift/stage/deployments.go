@@ -35,7 +35,7 @@ func showBunDeplAction(versions Versions) cli.ActionFunc {
 		if err != nil {
 			return errors.Wrapf(err, "couldn't load deployment %s from bundle %d", deplName, index)
 		}
-		return fcli.FprintResolvedDepl(0, os.Stdout, bundle, resolved)
+		return fcli.FprintResolvedDepl(0, os.Stdout, c.String("output"), bundle, resolved)
 	}
 }
 
@@ -0,0 +1,53 @@
+package stage
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	fcli "github.com/forklift-run/forklift/internal/app/forklift/cli"
+)
+
+// check-drift
+
+func checkDriftAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+		current, ok := store.GetCurrent()
+		if !ok {
+			return errors.New("no staged pallet bundle has been successfully applied yet")
+		}
+		fmt.Fprintf(os.Stderr, "Checking drift from the current staged pallet bundle: %d\n", current)
+		bundle, err := store.LoadFSBundle(current)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't load staged pallet bundle %d", current)
+		}
+		if err = fcli.CheckBundleShallowCompat(
+			bundle, versions.Tool, versions.MinSupportedBundle, c.Bool("ignore-tool-version"),
+		); err != nil {
+			return err
+		}
+
+		drifts, err := fcli.CheckDrift(0, bundle, bundle, c.String("unowned-apps"))
+		if err != nil {
+			return err
+		}
+		if err = fcli.PrintDrifts(os.Stdout, drifts, c.String("output")); err != nil {
+			return err
+		}
+		if len(drifts) > 0 {
+			return errors.Errorf(
+				"the host has drifted from staged pallet bundle %d in %d ways", current, len(drifts),
+			)
+		}
+		return nil
+	}
+}
@@ -2,6 +2,8 @@ package stage
 
 import (
 	"fmt"
+	"maps"
+	"os"
 	"slices"
 	"strconv"
 
@@ -9,6 +11,7 @@ import (
 	"github.com/urfave/cli/v2"
 
 	"github.com/forklift-run/forklift/internal/app/forklift"
+	fcli "github.com/forklift-run/forklift/internal/app/forklift/cli"
 )
 
 const (
@@ -35,6 +38,9 @@ func lsBunNamesAction(versions Versions) cli.ActionFunc {
 			names = append(names, name)
 		}
 		slices.Sort(names)
+		if format := c.String("output"); fcli.IsStructuredOutput(format) {
+			return fcli.FprintDocument(os.Stdout, format, getAllBundleNames(store))
+		}
 		for _, name := range names {
 			index := store.Manifest.Stages.Names[name]
 			printNamedBundleSummary(store, name, index)
@@ -55,6 +61,28 @@ func lsBunNamesAction(versions Versions) cli.ActionFunc {
 	}
 }
 
+// getAllBundleNames returns a map from the names of staged bundles (including the special names
+// of the rollback, current, next, and pending bundles) to their indices.
+func getAllBundleNames(store *forklift.FSStageStore) map[string]int {
+	names := maps.Clone(store.Manifest.Stages.Names)
+	if names == nil {
+		names = make(map[string]int)
+	}
+	if index, ok := store.GetRollback(); ok {
+		names[rollbackStageName] = index
+	}
+	if index, ok := store.GetCurrent(); ok {
+		names[currentStageName] = index
+	}
+	if index, ok := store.GetNext(); ok {
+		names[nextStageName] = index
+	}
+	if index, ok := store.GetPending(); ok {
+		names[pendingStageName] = index
+	}
+	return names
+}
+
 func printNamedBundleSummary(store *forklift.FSStageStore, name string, index int) {
 	bundle, err := store.LoadFSBundle(index)
 	if err != nil {
@@ -89,12 +117,8 @@ func addBunNameAction(versions Versions) cli.ActionFunc {
 		}
 
 		name := c.Args().First()
-		if name == rollbackStageName || name == currentStageName ||
-			name == nextStageName || name == pendingStageName {
-			return errors.Errorf("'%s' is an automatically-set name, so it can't be set manually", name)
-		}
-		if _, err := strconv.Atoi(name); err == nil {
-			return errors.Errorf("integers cannot be used as bundle names: %s", name)
+		if err = checkBundleName(name); err != nil {
+			return err
 		}
 
 		index, err := resolveBundleIdentifier(c.Args().Get(1), store)
@@ -106,8 +130,21 @@ func addBunNameAction(versions Versions) cli.ActionFunc {
 		}
 
 		store.Manifest.Stages.Names[name] = index
-		return store.CommitState()
+		return fcli.CommitStageStore(store)
+	}
+}
+
+// checkBundleName returns an error if the name can't be manually assigned to a staged pallet
+// bundle.
+func checkBundleName(name string) error {
+	if name == rollbackStageName || name == currentStageName ||
+		name == nextStageName || name == pendingStageName {
+		return errors.Errorf("'%s' is an automatically-set name, so it can't be set manually", name)
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return errors.Errorf("integers cannot be used as bundle names: %s", name)
 	}
+	return nil
 }
 
 // del-bun-name
@@ -132,6 +169,6 @@ func delBunNameAction(versions Versions) cli.ActionFunc {
 		}
 
 		delete(store.Manifest.Stages.Names, name)
-		return store.CommitState()
+		return fcli.CommitStageStore(store)
 	}
 }
@@ -0,0 +1,259 @@
+package stage
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	fcli "github.com/forklift-run/forklift/internal/app/forklift/cli"
+)
+
+// gen-sig-key
+
+func genSigKeyAction(c *cli.Context) error {
+	privateKeyPath := c.Args().First()
+	if privateKeyPath == "" {
+		return errors.New("a path for the private key file must be specified")
+	}
+	publicKeyPath := privateKeyPath + ".pub"
+
+	key, err := forklift.GenerateSigningKey()
+	if err != nil {
+		return err
+	}
+	if err = forklift.WriteSigningKey(key, privateKeyPath, publicKeyPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Saved the private key to %s\n", privateKeyPath)
+	fmt.Fprintf(os.Stderr, "Saved the public key to %s\n", publicKeyPath)
+	return nil
+}
+
+// sign-bun
+
+func signBunAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+
+		key, err := forklift.LoadSigningKey(c.String("key"))
+		if err != nil {
+			return errors.Wrap(err, "couldn't load signing key")
+		}
+		index, err := resolveBundleIdentifier(c.Args().First(), store)
+		if err != nil {
+			return err
+		}
+		bundle, err := store.LoadFSBundle(index)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't load staged bundle %d", index)
+		}
+
+		fmt.Fprintf(os.Stderr, "Signing staged pallet bundle %d...\n", index)
+		signature, err := bundle.Sign(key)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't sign staged pallet bundle %d", index)
+		}
+		fcli.IndentedFprintf(1, os.Stderr, "Content hash: %s\n", signature.ContentHash)
+		fcli.IndentedFprintf(1, os.Stderr, "Public key: %s\n", signature.PublicKey)
+		fmt.Fprintln(os.Stderr, "Done!")
+		return nil
+	}
+}
+
+// check-bun-sig
+
+func checkBunSigAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+
+		index, err := resolveBundleIdentifier(c.Args().First(), store)
+		if err != nil {
+			return err
+		}
+		bundle, err := store.LoadFSBundle(index)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't load staged bundle %d", index)
+		}
+		signature, err := bundle.VerifySignature()
+		if err != nil {
+			return errors.Wrapf(err, "staged pallet bundle %d has no valid signature", index)
+		}
+		fmt.Printf("Staged pallet bundle %d has a valid signature:\n", index)
+		fcli.IndentedPrintf(1, "Content hash: %s\n", signature.ContentHash)
+		fcli.IndentedPrintf(1, "Public key: %s\n", signature.PublicKey)
+		key, ok := store.GetBundleTrust().FindKey(signature.PublicKey)
+		if !ok {
+			return errors.Errorf("staged pallet bundle %d is signed by an untrusted key", index)
+		}
+		fcli.IndentedPrintf(1, "Trusted key: %s\n", describeTrustedKey(key))
+		return nil
+	}
+}
+
+func describeTrustedKey(key forklift.TrustedKey) string {
+	if key.Name == "" {
+		return key.PublicKey
+	}
+	return fmt.Sprintf("%s (%s)", key.Name, key.PublicKey)
+}
+
+// show-sig-trust
+
+func showSigTrustAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+
+		config := store.GetBundleTrust()
+		if format := c.String("output"); fcli.IsStructuredOutput(format) {
+			return fcli.FprintDocument(os.Stdout, format, config)
+		}
+
+		if config.Enforce {
+			fmt.Println("Staged pallet bundles must be signed by a trusted key to be applied")
+		} else {
+			fmt.Println("Staged pallet bundles need not be signed to be applied")
+		}
+		fmt.Print("Trusted keys:")
+		if len(config.Keys) == 0 {
+			fmt.Println(" (none)")
+			return nil
+		}
+		fmt.Println()
+		for _, key := range config.Keys {
+			fcli.BulletedPrintln(1, describeTrustedKey(key))
+		}
+		return nil
+	}
+}
+
+// trust-sig-key
+
+func trustSigKeyAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+
+		publicKey, err := forklift.LoadPublicKey(c.Args().First())
+		if err != nil {
+			return errors.Wrap(err, "couldn't load public key")
+		}
+		key := forklift.TrustedKey{
+			Name:      c.String("name"),
+			PublicKey: forklift.EncodePublicKey(publicKey),
+		}
+		config := store.GetBundleTrust()
+		if existing, ok := config.FindKey(key.PublicKey); ok {
+			return errors.Errorf("key is already trusted: %s", describeTrustedKey(existing))
+		}
+		config.Keys = append(slices.Clone(config.Keys), key)
+		store.SetBundleTrust(config)
+
+		fmt.Fprintf(os.Stderr, "Trusting key %s...\n", describeTrustedKey(key))
+		if err = fcli.CommitStageStore(store); err != nil {
+			return errors.Wrap(err, "couldn't commit updated stage store state")
+		}
+		return nil
+	}
+}
+
+// distrust-sig-key
+
+func distrustSigKeyAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+
+		config := store.GetBundleTrust()
+		identifier := c.Args().First()
+		remaining := slices.DeleteFunc(slices.Clone(config.Keys), func(key forklift.TrustedKey) bool {
+			return key.PublicKey == identifier || (key.Name != "" && key.Name == identifier)
+		})
+		if len(remaining) == len(config.Keys) {
+			return errors.Errorf("no trusted key has name or public key %s", identifier)
+		}
+		config.Keys = remaining
+		if config.Enforce && len(config.Keys) == 0 {
+			fmt.Fprintln(
+				os.Stderr,
+				"Warning: no keys will be trusted, so no staged pallet bundles will be applied until "+
+					"another key is trusted or signatures are no longer required!",
+			)
+		}
+		store.SetBundleTrust(config)
+
+		fmt.Fprintf(os.Stderr, "Distrusting key %s...\n", identifier)
+		if err = fcli.CommitStageStore(store); err != nil {
+			return errors.Wrap(err, "couldn't commit updated stage store state")
+		}
+		return nil
+	}
+}
+
+// set-sig-enforcement
+
+func setSigEnforcementAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+
+		config := store.GetBundleTrust()
+		switch setting := c.Args().First(); setting {
+		case "on":
+			if len(config.Keys) == 0 {
+				return errors.New(
+					"no keys are trusted yet, so no staged pallet bundles could be applied: you first " +
+						"must trust a key, e.g. with `forklift stage trust-sig-key`",
+				)
+			}
+			config.Enforce = true
+			fmt.Fprintln(os.Stderr, "Requiring staged pallet bundles to be signed by a trusted key...")
+		case "off":
+			config.Enforce = false
+			fmt.Fprintln(os.Stderr, "No longer requiring staged pallet bundles to be signed...")
+		default:
+			return errors.Errorf("unknown setting (must be 'on' or 'off'): %s", setting)
+		}
+		store.SetBundleTrust(config)
+		if err = fcli.CommitStageStore(store); err != nil {
+			return errors.Wrap(err, "couldn't commit updated stage store state")
+		}
+		return nil
+	}
+}
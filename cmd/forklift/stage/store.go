@@ -3,7 +3,9 @@ package stage
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
@@ -18,18 +20,17 @@ var errMissingStore = errors.Errorf(
 
 func loadNextBundle(
 	wpath, sspath string, versions Versions,
-) (*forklift.FSBundle, *forklift.FSStageStore, error) {
-	store, err := getStageStore(wpath, sspath, versions)
-	if err != nil {
-		return nil, nil, err
+) (bundle *forklift.FSBundle, store *forklift.FSStageStore, index int, err error) {
+	if store, err = getStageStore(wpath, sspath, versions); err != nil {
+		return nil, nil, 0, err
 	}
 	if !store.Exists() {
-		return nil, store, errMissingStore
+		return nil, store, 0, errMissingStore
 	}
 
 	next, ok := store.GetNext()
 	if !ok {
-		return nil, store, errors.Errorf(
+		return nil, store, 0, errors.Errorf(
 			"no next staged pallet bundle to apply: you first must set a pallet to stage next, " +
 				"e.g. with `forklift stage set-next`",
 		)
@@ -39,7 +40,7 @@ func loadNextBundle(
 		current, ok := store.GetCurrent()
 		switch {
 		case !ok:
-			return nil, store, errors.Errorf(
+			return nil, store, 0, errors.Errorf(
 				"the next staged pallet bundle already failed, and no staged pallet bundle was " +
 					"applied successfully in the past, so we have no fallback!",
 			)
@@ -59,11 +60,10 @@ func loadNextBundle(
 		}
 	}
 
-	bundle, err := store.LoadFSBundle(next)
-	if err != nil {
-		return nil, store, errors.Wrapf(err, "couldn't load staged pallet bundle %d", next)
+	if bundle, err = store.LoadFSBundle(next); err != nil {
+		return nil, store, 0, errors.Wrapf(err, "couldn't load staged pallet bundle %d", next)
 	}
-	return bundle, store, nil
+	return bundle, store, next, nil
 }
 
 func getStageStore(
@@ -97,6 +97,10 @@ func showAction(versions Versions) cli.ActionFunc {
 			return errMissingStore
 		}
 
+		if format := c.String("output"); fcli.IsStructuredOutput(format) {
+			return fcli.FprintDocument(os.Stdout, format, fcli.NewStageStoreDocument(store))
+		}
+
 		indent := 0
 		fcli.IndentedPrintf(indent, "Stage store %s:\n", store.Path())
 		indent++
@@ -133,6 +137,7 @@ func showAction(versions Versions) cli.ActionFunc {
 			printRollbackSummary(indent+1, store, rollback, names[rollback])
 		}
 
+		printRetentionSummary(indent, store.Manifest.Retention)
 		return nil
 	}
 }
@@ -146,11 +151,12 @@ func printNextSummary(indent int, store *forklift.FSStageStore, index int, names
 
 	printBasicSummary(indent, bundle, names)
 	failed := store.NextFailed()
+	interrupted := store.NextInterrupted()
 	pending, hasPending := store.GetPending()
 	isPending := (hasPending && index == pending)
 	current, hasCurrent := store.GetCurrent()
 	isCurrent := (hasCurrent && index == current)
-	if failed || isPending || isCurrent {
+	if failed || interrupted || isPending || isCurrent {
 		fcli.IndentedPrint(indent, "Status: ")
 		switch {
 		case failed && hasCurrent:
@@ -163,12 +169,23 @@ func printNextSummary(indent int, store *forklift.FSStageStore, index int, names
 			fmt.Println(
 				"failed to be applied; no pallet will be applied until another pallet is staged",
 			)
+		case interrupted:
+			fmt.Println(
+				"interrupted while being applied, so it may be partially applied; will be used again " +
+					"for the next apply",
+			)
 		case isPending:
 			fmt.Println("not yet applied; will be used for the next apply")
 		case hasCurrent && index == current:
 			fmt.Println("already applied; will still be used for the next apply")
 		}
 	}
+	if applied := store.GetNextApplied(); len(applied) > 0 && !failed {
+		fcli.IndentedPrintln(indent, "Already partially applied for:")
+		for _, name := range applied {
+			fcli.BulletedPrintln(indent+1, name)
+		}
+	}
 }
 
 func printBasicSummary(indent int, bundle *forklift.FSBundle, names []string) {
@@ -215,6 +232,31 @@ func printRollbackSummary(indent int, store *forklift.FSStageStore, index int, n
 	printBasicSummary(indent, bundle, names)
 }
 
+func printRetentionSummary(indent int, policy *forklift.StageRetentionSpec) {
+	fcli.IndentedPrint(indent, "Retention policy:")
+	if policy == nil {
+		fmt.Println(" (none)")
+		return
+	}
+	fmt.Println()
+	indent++
+	fcli.BulletedPrintln(indent, "Keep the next, current, and rollback staged pallet bundles")
+	if policy.KeepApplied > 0 {
+		fcli.BulletedPrintf(
+			indent, "Keep the %d most-recently-applied staged pallet bundles\n", policy.KeepApplied,
+		)
+	}
+	if policy.KeepNamed {
+		fcli.BulletedPrintln(indent, "Keep all named staged pallet bundles")
+	}
+	if policy.KeepYoungerThan != "" {
+		fcli.BulletedPrintf(
+			indent, "Keep all staged pallet bundles staged within the last %s\n",
+			policy.KeepYoungerThan,
+		)
+	}
+}
+
 // show-hist
 
 func showHistAction(versions Versions) cli.ActionFunc {
@@ -227,7 +269,18 @@ func showHistAction(versions Versions) cli.ActionFunc {
 			return errMissingStore
 		}
 
+		if c.Bool("journal") {
+			return showApplyJournal(c.String("output"), store)
+		}
+
 		names := getBundleNames(store)
+		if format := c.String("output"); fcli.IsStructuredOutput(format) {
+			indices := slices.Clone(store.Manifest.Stages.History)
+			if index, ok := store.GetPending(); ok {
+				indices = append(indices, index)
+			}
+			return fprintBundleDocuments(os.Stdout, format, store, indices, names)
+		}
 		for _, index := range store.Manifest.Stages.History {
 			printBundleSummary(store, index, names)
 		}
@@ -238,6 +291,59 @@ func showHistAction(versions Versions) cli.ActionFunc {
 	}
 }
 
+func showApplyJournal(format string, store *forklift.FSStageStore) error {
+	records, skipped, err := store.LoadApplyJournal()
+	if err != nil {
+		return err
+	}
+	for _, skipErr := range skipped {
+		fmt.Fprintf(os.Stderr, "Warning: skipping malformed record: %s\n", skipErr.Error())
+	}
+	if fcli.IsStructuredOutput(format) {
+		if records == nil {
+			records = []forklift.ApplyRecord{}
+		}
+		return fcli.FprintDocument(os.Stdout, format, records)
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No staged pallet bundles have been applied yet")
+		return nil
+	}
+	for _, record := range records {
+		printApplyRecord(0, record)
+	}
+	return nil
+}
+
+func printApplyRecord(indent int, record forklift.ApplyRecord) {
+	fcli.IndentedPrintf(
+		indent, "%s: staged pallet bundle %d", record.Started.Format(time.RFC3339), record.Bundle,
+	)
+	if record.Fallback {
+		fmt.Print(" (as a fallback)")
+	}
+	if record.Partial {
+		fmt.Print(" (partially)")
+	}
+	fmt.Printf(": %s\n", record.Result)
+	indent++
+	fcli.IndentedPrintf(
+		indent, "Duration: %s\n", record.Finished.Sub(record.Started).Round(time.Millisecond),
+	)
+	fcli.IndentedPrintf(indent, "Forklift version: %s\n", record.ToolVersion)
+	if record.Error != "" {
+		fcli.IndentedPrintf(indent, "Error: %s\n", record.Error)
+	}
+	if len(record.FailedChanges) == 0 {
+		return
+	}
+	fcli.IndentedPrintln(indent, "Failed changes:")
+	for _, failed := range record.FailedChanges {
+		fcli.BulletedPrintf(indent+1, "%s: %s\n", failed.Change, failed.Error)
+	}
+}
+
 // show-next-index
 
 func showNextIndexAction(versions Versions) cli.ActionFunc {
@@ -254,6 +360,9 @@ func showNextIndexAction(versions Versions) cli.ActionFunc {
 		if !ok {
 			return errors.New("there is currently no staged pallet bundle to be applied next!")
 		}
+		if format := c.String("output"); fcli.IsStructuredOutput(format) {
+			return fcli.FprintDocument(os.Stdout, format, next)
+		}
 		fmt.Println(next)
 		return nil
 	}
@@ -278,7 +387,7 @@ func setNextAction(versions Versions) cli.ActionFunc {
 				"Committing update to the stage store so that no staged pallet bundle will be applied "+
 					"next...",
 			)
-			if err := store.CommitState(); err != nil {
+			if err := fcli.CommitStageStore(store); err != nil {
 				return errors.Wrap(err, "couldn't commit updated stage store state")
 			}
 			return nil
@@ -288,10 +397,6 @@ func setNextAction(versions Versions) cli.ActionFunc {
 		if err != nil {
 			return err
 		}
-		if _, err = store.LoadFSBundle(newNext); err != nil {
-			return errors.Wrapf(err, "couldn't load staged bundle %d", newNext)
-		}
-
 		if next, hasNext := store.GetNext(); hasNext {
 			fmt.Fprintf(
 				os.Stderr, "Changing the next staged pallet bundle from %d to %d...\n", next, newNext,
@@ -332,7 +437,7 @@ func unsetNextAction(versions Versions) cli.ActionFunc {
 		fmt.Fprintln(
 			os.Stderr, "Committing update to the stage store so that no stage will be applied next...",
 		)
-		if err := store.CommitState(); err != nil {
+		if err := fcli.CommitStageStore(store); err != nil {
 			return errors.Wrap(err, "couldn't commit updated stage store state")
 		}
 		return nil
@@ -405,7 +510,7 @@ func resolveBundleIdentifier(
 
 func checkAction(versions Versions) cli.ActionFunc {
 	return func(c *cli.Context) error {
-		bundle, _, err := loadNextBundle(c.String("workspace"), c.String("stage-store"), versions)
+		bundle, _, _, err := loadNextBundle(c.String("workspace"), c.String("stage-store"), versions)
 		if err != nil {
 			return err
 		}
@@ -423,9 +528,31 @@ func checkAction(versions Versions) cli.ActionFunc {
 
 // plan
 
+// planOptions returns the options for planning changes to the Docker host, as set by global flags
+// and by the deployment selection flags of the subcommand.
+func planOptions(c *cli.Context) fcli.PlanOptions {
+	return fcli.PlanOptions{
+		Parallel:          c.Bool("parallel"),
+		PrecreateNetworks: c.Bool("precreate-networks"),
+		OrphanedVolumes:   c.String("orphaned-volumes"),
+		UnownedApps:       c.String("unowned-apps"),
+		Only:              c.StringSlice("only"),
+		Exclude:           c.StringSlice("exclude"),
+	}
+}
+
+func applyOptions(c *cli.Context) fcli.ApplyOptions {
+	return fcli.ApplyOptions{
+		PlanOptions: planOptions(c),
+		Retries:     c.Int("retries"),
+	}
+}
+
 func planAction(versions Versions) cli.ActionFunc {
 	return func(c *cli.Context) error {
-		bundle, _, err := loadNextBundle(c.String("workspace"), c.String("stage-store"), versions)
+		bundle, _, index, err := loadNextBundle(
+			c.String("workspace"), c.String("stage-store"), versions,
+		)
 		if err != nil {
 			return err
 		}
@@ -434,29 +561,91 @@ func planAction(versions Versions) cli.ActionFunc {
 		); err != nil {
 			return err
 		}
-		if _, _, err = fcli.Plan(0, bundle, bundle, c.Bool("parallel")); err != nil {
+		if outputPath := c.String("out"); outputPath != "" {
+			plan, err := fcli.PlanToSave(0, bundle, bundle, planOptions(c), index)
+			if err != nil {
+				return err
+			}
+			return fcli.WriteSavedPlan(plan, outputPath)
+		}
+		if _, _, err = fcli.Plan(0, bundle, bundle, planOptions(c)); err != nil {
 			return err
 		}
 		return nil
 	}
 }
 
+// graph
+
+func graphAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+
+		index, err := resolveBundleIdentifier(c.Args().First(), store)
+		if err != nil {
+			return err
+		}
+		bundle, err := store.LoadFSBundle(index)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't load staged bundle %d", index)
+		}
+		if err = fcli.CheckBundleShallowCompat(
+			bundle, versions.Tool, versions.MinSupportedBundle, c.Bool("ignore-tool-version"),
+		); err != nil {
+			return err
+		}
+
+		if c.Bool("changes") {
+			return fcli.FprintChangeGraph(
+				0, os.Stdout, c.String("format"), bundle, bundle, planOptions(c),
+			)
+		}
+		return fcli.FprintDeplGraph(0, os.Stdout, c.String("format"), bundle, bundle)
+	}
+}
+
 // apply
 
 func applyAction(versions Versions) cli.ActionFunc {
 	return func(c *cli.Context) error {
-		bundle, store, err := loadNextBundle(c.String("workspace"), c.String("stage-store"), versions)
+		bundle, store, index, err := loadNextBundle(
+			c.String("workspace"), c.String("stage-store"), versions,
+		)
 		if err != nil {
 			return err
 		}
 		if err = fcli.CheckBundleShallowCompat(
 			bundle, versions.Tool, versions.MinSupportedBundle, c.Bool("ignore-tool-version"),
 		); err != nil {
+			fcli.RecordFailedApply(0, store, index, store.NextFailed(), versions.Tool, err)
 			return err
 		}
 		fmt.Fprintln(os.Stderr)
 
-		if err = fcli.ApplyNextOrCurrentBundle(0, store, bundle, c.Bool("parallel")); err != nil {
+		opts := applyOptions(c)
+		if planPath := c.String("plan"); planPath != "" {
+			plan, err := fcli.LoadSavedPlan(planPath)
+			if err != nil {
+				return err
+			}
+			if err = fcli.CheckSavedPlanBundle(plan, index); err != nil {
+				return err
+			}
+			opts.PlanOptions = plan.Options.PlanOptions()
+			opts.SavedPlan = &plan
+		}
+		err = fcli.ApplyNextOrCurrentBundle(0, store, bundle, versions.Tool, opts)
+		var refused *fcli.RefusedBundleError
+		if errors.As(err, &refused) {
+			return applyFallbackBundle(c, versions, store, refused.Bundle, opts, err)
+		}
+		if err != nil {
 			return err
 		}
 		fmt.Fprintln(os.Stderr, "Done!")
@@ -464,6 +653,57 @@ func applyAction(versions Versions) cli.ActionFunc {
 	}
 }
 
+// applyFallbackBundle handles a staged pallet bundle which was refused (e.g. because it failed its
+// integrity check or isn't signed by a trusted key), after the refusal was recorded: the last
+// successfully-applied staged pallet bundle (or, if that's the refused bundle, the staged pallet
+// bundle which was successfully applied before it) is applied instead, as a fallback.
+func applyFallbackBundle(
+	c *cli.Context, versions Versions, store *forklift.FSStageStore, refused int,
+	opts fcli.ApplyOptions, refusalErr error,
+) error {
+	if opts.SavedPlan != nil {
+		return errors.Wrap(
+			refusalErr, "the saved plan can only be applied to the refused staged pallet bundle, so "+
+				"no fallback will be applied",
+		)
+	}
+	fallback, ok := store.GetCurrent()
+	if !ok || fallback == refused {
+		fallback, ok = store.GetRollback()
+	}
+	if !ok || fallback == refused {
+		return errors.Wrap(
+			refusalErr,
+			"no other staged pallet bundle was applied successfully in the past, so we have no fallback!",
+		)
+	}
+	fmt.Fprintf(
+		os.Stderr, "Staged pallet bundle %d will be applied instead, as a fallback\n", fallback,
+	)
+
+	bundle, err := store.LoadFSBundle(fallback)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't load staged pallet bundle %d", fallback)
+	}
+	if err = fcli.CheckBundleShallowCompat(
+		bundle, versions.Tool, versions.MinSupportedBundle, c.Bool("ignore-tool-version"),
+	); err != nil {
+		fcli.RecordFailedApply(0, store, fallback, true, versions.Tool, err)
+		return err
+	}
+	fmt.Fprintln(os.Stderr)
+
+	if err = fcli.ApplyFallbackBundle(0, store, bundle, fallback, versions.Tool, opts); err != nil {
+		return errors.Wrapf(err, "couldn't apply fallback staged pallet bundle %d", fallback)
+	}
+	fmt.Fprintf(
+		os.Stderr,
+		"Done, but staged pallet bundle %d was refused, so it was not applied: %s\n",
+		refused, refusalErr.Error(),
+	)
+	return nil
+}
+
 // set-next-result
 
 func setNextResultAction(versions Versions) cli.ActionFunc {
@@ -481,6 +721,7 @@ func setNextResultAction(versions Versions) cli.ActionFunc {
 			store.RecordNextSuccess(true)
 		case "pending":
 			store.Manifest.Stages.NextFailed = false
+			store.Manifest.Stages.NextInterrupted = false
 		case "failure":
 			store.RecordNextSuccess(false)
 		default:
@@ -489,7 +730,76 @@ func setNextResultAction(versions Versions) cli.ActionFunc {
 			)
 		}
 		fmt.Fprintln(os.Stderr, "Committing result to the stage store...")
-		if err := store.CommitState(); err != nil {
+		if err := fcli.CommitStageStore(store); err != nil {
+			return errors.Wrap(err, "couldn't commit updated stage store state")
+		}
+		fmt.Fprintln(os.Stderr, "Done!")
+		return nil
+	}
+}
+
+// set-retention
+
+func setRetentionAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+		if !c.IsSet("keep-applied") && !c.IsSet("keep-named") && !c.IsSet("keep-younger-than") {
+			// An empty retention policy would only keep the next, current, and rollback bundles, which
+			// is probably not what the user intended:
+			return errors.New(
+				"at least one of --keep-applied, --keep-named, or --keep-younger-than must be set",
+			)
+		}
+
+		policy := forklift.StageRetentionSpec{
+			KeepApplied:     c.Int("keep-applied"),
+			KeepNamed:       c.Bool("keep-named"),
+			KeepYoungerThan: c.String("keep-younger-than"),
+		}
+		if policy.KeepApplied < 0 {
+			return errors.Errorf(
+				"number of applied staged pallet bundles to keep must not be negative: %d",
+				policy.KeepApplied,
+			)
+		}
+		if _, err = policy.ParseKeepYoungerThan(); err != nil {
+			return err
+		}
+		store.Manifest.Retention = &policy
+		fmt.Fprintln(os.Stderr, "Committing retention policy to the stage store...")
+		if err := fcli.CommitStageStore(store); err != nil {
+			return errors.Wrap(err, "couldn't commit updated stage store state")
+		}
+		fmt.Fprintln(
+			os.Stderr,
+			"Done! The retention policy will be applied whenever a pallet is staged, or when you run "+
+				"`forklift stage gc`.",
+		)
+		return nil
+	}
+}
+
+// unset-retention
+
+func unsetRetentionAction(versions Versions) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		store, err := getStageStore(c.String("workspace"), c.String("stage-store"), versions)
+		if err != nil {
+			return err
+		}
+		if !store.Exists() {
+			return errMissingStore
+		}
+
+		store.Manifest.Retention = nil
+		fmt.Fprintln(os.Stderr, "Committing removal of the retention policy to the stage store...")
+		if err := fcli.CommitStageStore(store); err != nil {
 			return errors.Wrap(err, "couldn't commit updated stage store state")
 		}
 		fmt.Fprintln(os.Stderr, "Done!")
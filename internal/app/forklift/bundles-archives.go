@@ -0,0 +1,441 @@
+package forklift
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/forklift-run/forklift/pkg/structures"
+)
+
+// Writing
+
+// WriteBundleArchive writes a zstd-compressed tar archive of the bundle to out, together with the
+// cached downloads required by the bundle. If imagesTarball is non-empty, the archive also includes
+// the file at that path as a tarball of the specified container images.
+func WriteBundleArchive(
+	out io.Writer, bundle *FSBundle, dlCache *FSDownloadCache, forkliftVersion string,
+	imagesTarball string, images []string,
+) error {
+	zw, err := zstd.NewWriter(out)
+	if err != nil {
+		return errors.Wrap(err, "couldn't initialize zstd compression")
+	}
+	w := &bundleArchiveWriter{
+		tw: tar.NewWriter(zw),
+		manifest: BundleArchiveManifest{
+			ForkliftVersion: forkliftVersion,
+			Pallet:          bundle.Manifest.Pallet,
+			Images:          images,
+			Files:           make(map[string]string),
+			Links:           make(map[string]string),
+		},
+	}
+
+	if err = w.addDir(filepath.FromSlash(bundle.FS.Path()), archivedBundleDirName); err != nil {
+		return errors.Wrap(err, "couldn't add bundle to archive")
+	}
+	downloads, err := listBundleDownloadPaths(bundle)
+	if err != nil {
+		return err
+	}
+	if len(downloads) > 0 && dlCache == nil {
+		return errors.New("the bundle requires downloads, but no download cache was provided")
+	}
+	for _, download := range downloads {
+		if err = w.addFile(
+			filepath.FromSlash(path.Join(dlCache.Path(), download)),
+			path.Join(archivedDownloadsDirName, download),
+		); err != nil {
+			return errors.Wrapf(err, "couldn't add cached download %s to archive", download)
+		}
+	}
+	if imagesTarball != "" {
+		if err = w.addFile(imagesTarball, archivedImagesFile); err != nil {
+			return errors.Wrap(err, "couldn't add container images to archive")
+		}
+	}
+
+	if err = w.addManifest(); err != nil {
+		return errors.Wrap(err, "couldn't add bundle archive manifest to archive")
+	}
+	if err = w.tw.Close(); err != nil {
+		return errors.Wrap(err, "couldn't finish writing tar archive")
+	}
+	return errors.Wrap(zw.Close(), "couldn't finish zstd compression")
+}
+
+// listBundleDownloadPaths returns the paths (relative to the root of the download cache) of all
+// downloads required by the bundle.
+func listBundleDownloadPaths(bundle *FSBundle) ([]string, error) {
+	paths := make(map[string]struct{})
+	for _, downloads := range bundle.Manifest.Downloads {
+		for _, url := range downloads.HTTPFile {
+			normalized, err := normalizeHTTPDownloadURL(url)
+			if err != nil {
+				return nil, err
+			}
+			paths[normalized] = struct{}{}
+		}
+		for _, imageName := range downloads.OCIImage {
+			normalized, err := normalizeOCIImageName(imageName)
+			if err != nil {
+				return nil, err
+			}
+			paths[normalized] = struct{}{}
+		}
+	}
+	return slices.Sorted(maps.Keys(paths)), nil
+}
+
+// bundleArchiveWriter writes files to a bundle archive, recording them in the bundle archive's
+// manifest.
+type bundleArchiveWriter struct {
+	tw       *tar.Writer
+	manifest BundleArchiveManifest
+}
+
+// addDir adds the directory at the specified path in the OS's filesystem, and everything in it, to
+// the archive at the specified archive path.
+func (w *bundleArchiveWriter) addDir(dirPath, archivePath string) error {
+	return filepath.WalkDir(dirPath, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dirPath, filePath)
+		if err != nil {
+			return err
+		}
+		name := path.Join(archivePath, filepath.ToSlash(relPath))
+		info, err := d.Info()
+		if err != nil {
+			return errors.Wrapf(err, "couldn't stat %s", filePath)
+		}
+
+		switch {
+		case d.IsDir():
+			return w.writeHeader(info, name, "")
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(filePath)
+			if err != nil {
+				return errors.Wrapf(err, "couldn't read symlink %s", filePath)
+			}
+			w.manifest.Links[name] = target
+			return w.writeHeader(info, name, target)
+		case d.Type().IsRegular():
+			return w.addFile(filePath, name)
+		default:
+			return errors.Errorf("%s has unsupported file type %s", filePath, d.Type())
+		}
+	})
+}
+
+// addFile adds the regular file at the specified path in the OS's filesystem to the archive at the
+// specified archive path.
+func (w *bundleArchiveWriter) addFile(filePath, archivePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't open %s", filePath)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "couldn't stat %s", filePath)
+	}
+	if err = w.writeHeader(info, archivePath, ""); err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(w.tw, hash), file); err != nil {
+		return errors.Wrapf(err, "couldn't write %s to archive", filePath)
+	}
+	w.manifest.Files[archivePath] = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+func (w *bundleArchiveWriter) writeHeader(info fs.FileInfo, archivePath, linkTarget string) error {
+	header, err := tar.FileInfoHeader(info, linkTarget)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't make tar header for %s", archivePath)
+	}
+	header.Name = archivePath
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	// We don't want to leak the file owners of the machine which made the archive:
+	header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+	return errors.Wrapf(w.tw.WriteHeader(header), "couldn't write tar header for %s", archivePath)
+}
+
+// addManifest adds the bundle archive manifest to the archive. It must be added after all other
+// files, so that the manifest can describe them.
+func (w *bundleArchiveWriter) addManifest() error {
+	marshaled, err := yaml.Marshal(w.manifest)
+	if err != nil {
+		return errors.Wrap(err, "couldn't marshal bundle archive manifest")
+	}
+	const perm = 0o644 // owner rw, group r, public r
+	if err = w.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     BundleArchiveManifestFile,
+		Mode:     perm,
+		Size:     int64(len(marshaled)),
+		ModTime:  time.Now(),
+	}); err != nil {
+		return errors.Wrapf(err, "couldn't write tar header for %s", BundleArchiveManifestFile)
+	}
+	_, err = w.tw.Write(marshaled)
+	return err
+}
+
+// Reading
+
+// ReadBundleArchive extracts a zstd-compressed tar archive of a bundle (made by
+// [WriteBundleArchive]) from in into the dest directory in the OS's filesystem, and checks that the
+// extracted files exactly match the files listed in the archive's manifest.
+func ReadBundleArchive(in io.Reader, dest string) (manifest BundleArchiveManifest, err error) {
+	zr, err := zstd.NewReader(in)
+	if err != nil {
+		return BundleArchiveManifest{}, errors.Wrap(err, "couldn't initialize zstd decompression")
+	}
+	defer zr.Close()
+
+	tr := tar.NewReader(zr)
+	files := make(map[string]string)
+	links := make(map[string]string)
+	hasManifest := false
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return BundleArchiveManifest{}, errors.Wrap(err, "couldn't read archive")
+		}
+		name := path.Clean(header.Name)
+		if !filepath.IsLocal(filepath.FromSlash(name)) || isUnderLink(name, links) {
+			return BundleArchiveManifest{}, errors.Errorf("archive has invalid path %s", header.Name)
+		}
+		destPath := filepath.Join(dest, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = EnsureExists(destPath); err != nil {
+				return BundleArchiveManifest{}, errors.Wrapf(err, "couldn't make directory %s", destPath)
+			}
+		case tar.TypeSymlink:
+			if err = EnsureExists(filepath.Dir(destPath)); err != nil {
+				return BundleArchiveManifest{}, errors.Wrapf(
+					err, "couldn't make directory %s", filepath.Dir(destPath),
+				)
+			}
+			if err = os.Symlink(header.Linkname, destPath); err != nil {
+				return BundleArchiveManifest{}, errors.Wrapf(err, "couldn't make symlink %s", destPath)
+			}
+			links[name] = header.Linkname
+		case tar.TypeReg:
+			if name == BundleArchiveManifestFile {
+				if manifest, err = readBundleArchiveManifest(tr); err != nil {
+					return BundleArchiveManifest{}, err
+				}
+				hasManifest = true
+				continue
+			}
+			if files[name], err = extractArchivedFile(
+				tr, destPath, header.FileInfo().Mode().Perm(),
+			); err != nil {
+				return BundleArchiveManifest{}, err
+			}
+		default:
+			return BundleArchiveManifest{}, errors.Errorf(
+				"archive has entry %s with unsupported type %c", header.Name, header.Typeflag,
+			)
+		}
+	}
+
+	if !hasManifest {
+		return BundleArchiveManifest{}, errors.Errorf(
+			"archive has no %s, so either it's incomplete or it isn't a bundle archive",
+			BundleArchiveManifestFile,
+		)
+	}
+	if err = checkArchivedEntries("file", files, manifest.Files); err != nil {
+		return BundleArchiveManifest{}, err
+	}
+	if err = checkArchivedEntries("symlink", links, manifest.Links); err != nil {
+		return BundleArchiveManifest{}, err
+	}
+	return manifest, nil
+}
+
+// isUnderLink checks whether any parent directory of the specified archive path is a symlink,
+// in which case extracting a file to that path could write it outside the destination directory.
+func isUnderLink(name string, links map[string]string) bool {
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, ok := links[dir]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func readBundleArchiveManifest(r io.Reader) (manifest BundleArchiveManifest, err error) {
+	marshaled, err := io.ReadAll(r)
+	if err != nil {
+		return BundleArchiveManifest{}, errors.Wrapf(
+			err, "couldn't read %s from archive", BundleArchiveManifestFile,
+		)
+	}
+	if err = yaml.Unmarshal(marshaled, &manifest); err != nil {
+		return BundleArchiveManifest{}, errors.Wrapf(
+			err, "couldn't parse %s from archive", BundleArchiveManifestFile,
+		)
+	}
+	return manifest, nil
+}
+
+// extractArchivedFile writes the contents of the file from r to a new file at the specified path,
+// returning the SHA-256 hash of the contents.
+func extractArchivedFile(r io.Reader, filePath string, perm fs.FileMode) (hash string, err error) {
+	if err = EnsureExists(filepath.Dir(filePath)); err != nil {
+		return "", errors.Wrapf(err, "couldn't make directory %s", filepath.Dir(filePath))
+	}
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't create %s", filePath)
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil && err == nil {
+			err = errors.Wrapf(cerr, "couldn't close %s", filePath)
+		}
+	}()
+
+	hasher := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(file, hasher), r); err != nil {
+		return "", errors.Wrapf(err, "couldn't extract %s", filePath)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// checkArchivedEntries checks that the actual entries of the specified type (mapping archive paths
+// to the SHA-256 hashes of files or the targets of symlinks) exactly match the expected entries.
+func checkArchivedEntries(entryType string, actual, expected map[string]string) error {
+	for _, name := range slices.Sorted(maps.Keys(expected)) {
+		actualValue, ok := actual[name]
+		if !ok {
+			return errors.Errorf("archive is missing %s %s", entryType, name)
+		}
+		if actualValue != expected[name] {
+			return errors.Errorf(
+				"%s %s in archive doesn't match the archive's manifest: expected %s, found %s",
+				entryType, name, expected[name], actualValue,
+			)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(actual)) {
+		if _, ok := expected[name]; !ok {
+			return errors.Errorf("archive has %s %s not listed in the archive's manifest", entryType, name)
+		}
+	}
+	return nil
+}
+
+// Importing
+
+// GetArchivedImagesPath returns the path of the tarball of container images in a bundle archive
+// which was extracted to the specified directory in the OS's filesystem.
+func GetArchivedImagesPath(extracted string) string {
+	return filepath.Join(extracted, archivedImagesFile)
+}
+
+// ImportArchivedDownloads copies the downloads required by the bundle in a bundle archive which was
+// extracted to the specified directory in the OS's filesystem into the download cache, skipping any
+// downloads which are already in the cache. It returns an error if the archive has any downloads
+// which aren't required by the bundle. It returns the number of downloads which were copied.
+func ImportArchivedDownloads(extracted string, dlCache *FSDownloadCache) (imported int, err error) {
+	bundle, err := LoadFSBundle(DirFS(filepath.ToSlash(extracted)), archivedBundleDirName)
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't load bundle from archive")
+	}
+	downloads, err := listBundleDownloadPaths(bundle)
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't determine the downloads required by the bundle")
+	}
+	required := make(structures.Set[string])
+	required.Add(downloads...)
+
+	downloadsPath := filepath.Join(extracted, archivedDownloadsDirName)
+	if !DirExists(downloadsPath) {
+		return 0, nil
+	}
+	archived := make([]string, 0, len(downloads))
+	if err = filepath.WalkDir(downloadsPath, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(downloadsPath, filePath)
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !required.Has(filepath.ToSlash(relPath)) {
+			return errors.Errorf("archive has download %s which isn't required by the bundle", relPath)
+		}
+		archived = append(archived, filepath.ToSlash(relPath))
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	for _, download := range archived {
+		cachedPath := filepath.Join(filepath.FromSlash(dlCache.Path()), filepath.FromSlash(download))
+		if _, err = os.Stat(cachedPath); err == nil {
+			continue
+		}
+		if err = EnsureExists(filepath.Dir(cachedPath)); err != nil {
+			return imported, errors.Wrapf(err, "couldn't make directory %s", filepath.Dir(cachedPath))
+		}
+		if err = copyFSFile(
+			DirFS(filepath.ToSlash(downloadsPath)), download, cachedPath, 0,
+		); err != nil {
+			return imported, errors.Wrapf(err, "couldn't copy %s into the download cache", download)
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// ImportArchivedBundle moves the bundle from a bundle archive which was extracted to the specified
+// directory in the OS's filesystem into the stage store as a new staged bundle, returning the index
+// of the new staged bundle.
+func (s *FSStageStore) ImportArchivedBundle(extracted string) (index int, err error) {
+	extractedFS := DirFS(filepath.ToSlash(extracted))
+	if _, err = LoadFSBundle(extractedFS, archivedBundleDirName); err != nil {
+		return 0, errors.Wrap(err, "couldn't load bundle from archive")
+	}
+	if index, err = s.AllocateNew(); err != nil {
+		return 0, errors.Wrap(err, "couldn't allocate a directory for staging")
+	}
+	bundlePath := filepath.FromSlash(s.GetBundlePath(index))
+	// AllocateNew makes an empty directory for the new bundle, which we replace:
+	if err = os.Remove(bundlePath); err != nil {
+		return 0, errors.Wrapf(err, "couldn't prepare %s for the imported bundle", bundlePath)
+	}
+	if err = os.Rename(filepath.Join(extracted, archivedBundleDirName), bundlePath); err != nil {
+		return 0, errors.Wrapf(err, "couldn't move imported bundle to %s", bundlePath)
+	}
+	return index, nil
+}
@@ -0,0 +1,153 @@
+package forklift
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+// newTestBundle makes a bundle in a temporary directory with the specified manifest and files
+// (keyed by path in the bundle), and loads it.
+func newTestBundle(t *testing.T, manifest BundleManifest, files map[string]string) *FSBundle {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "bundle")
+	for filePath, contents := range files {
+		writeTestFile(t, filepath.Join(dir, filepath.FromSlash(filePath)), contents)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("couldn't make bundle directory: %s", err)
+	}
+	unloaded := &FSBundle{Bundle: Bundle{Manifest: manifest}, FS: DirFS(filepath.ToSlash(dir))}
+	if err := unloaded.WriteManifestFile(); err != nil {
+		t.Fatalf("couldn't write bundle manifest: %s", err)
+	}
+	bundle, err := LoadFSBundle(DirFS(filepath.ToSlash(filepath.Dir(dir))), "bundle")
+	if err != nil {
+		t.Fatalf("couldn't load bundle: %s", err)
+	}
+	return bundle
+}
+
+func writeTestFile(t *testing.T, filePath, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		t.Fatalf("couldn't make directory for %s: %s", filePath, err)
+	}
+	if err := os.WriteFile(filePath, []byte(contents), 0o644); err != nil {
+		t.Fatalf("couldn't write %s: %s", filePath, err)
+	}
+}
+
+var testBundleFiles = map[string]string{
+	path.Join(bundledPalletDirName, "forklift-pallet.yml"):              "pallet",
+	path.Join(packagesDirName, "github.com/x/r/app/compose.yml"):        "services: {}",
+	path.Join(exportsDirName, "etc/app.conf"):                           "config",
+	path.Join(imagesDirName, "docker.io/library/alpine/latest.tar"):     "image",
+	path.Join(bundledMergedPalletDirName, "deployments/app.deploy.yml"): "deployment",
+}
+
+// newTestArchivedBundle makes a bundle which requires a file download, writes it with the download
+// to a bundle archive, and extracts the archive into a temporary directory, which is returned.
+func newTestArchivedBundle(t *testing.T) (extracted string) {
+	t.Helper()
+	bundle := newTestBundle(t, BundleManifest{
+		ForkliftVersion: "v0.1.0",
+		Pallet:          BundlePallet{Path: "github.com/x/p", Version: "v0.1.0"},
+		Downloads: map[string]BundleDeplDownloads{
+			"app": {HTTPFile: []string{"https://example.com/files/a.txt"}},
+		},
+	}, testBundleFiles)
+	dlCache := newTestDownloadCache(t)
+	writeTestFile(
+		t, filepath.Join(filepath.FromSlash(dlCache.Path()), "http-files/example.com/files/a.txt"),
+		"downloaded",
+	)
+
+	archive := &bytes.Buffer{}
+	if err := WriteBundleArchive(archive, bundle, dlCache, "v0.1.0", "", nil); err != nil {
+		t.Fatalf("couldn't write bundle archive: %s", err)
+	}
+	extracted = t.TempDir()
+	manifest, err := ReadBundleArchive(archive, extracted)
+	if err != nil {
+		t.Fatalf("couldn't read bundle archive: %s", err)
+	}
+	if manifest.Pallet.Path != "github.com/x/p" || manifest.Pallet.Version != "v0.1.0" {
+		t.Errorf("unexpected pallet in bundle archive manifest: %+v", manifest.Pallet)
+	}
+	return extracted
+}
+
+func newTestDownloadCache(t *testing.T) *FSDownloadCache {
+	t.Helper()
+	return &FSDownloadCache{FS: DirFS(filepath.ToSlash(t.TempDir()))}
+}
+
+func TestImportArchivedBundle(t *testing.T) {
+	extracted := newTestArchivedBundle(t)
+	dlCache := newTestDownloadCache(t)
+	imported, err := ImportArchivedDownloads(extracted, dlCache)
+	if err != nil {
+		t.Fatalf("couldn't import downloads: %s", err)
+	}
+	if imported != 1 {
+		t.Errorf("expected 1 imported download, got %d", imported)
+	}
+	contents, err := os.ReadFile(filepath.Join(
+		filepath.FromSlash(dlCache.Path()), "http-files/example.com/files/a.txt",
+	))
+	if err != nil || string(contents) != "downloaded" {
+		t.Errorf("expected imported download with contents 'downloaded', got '%s' (%v)", contents, err)
+	}
+	if imported, err = ImportArchivedDownloads(extracted, dlCache); err != nil || imported != 0 {
+		t.Errorf("expected already-cached downloads to be skipped, got %d (%v)", imported, err)
+	}
+
+	store := newTestStageStore(t, 1)
+	index, err := store.ImportArchivedBundle(extracted)
+	if err != nil {
+		t.Fatalf("couldn't import bundle: %s", err)
+	}
+	if index != 2 {
+		t.Errorf("expected imported bundle to be staged as bundle 2, got %d", index)
+	}
+	bundle, err := store.LoadFSBundle(index)
+	if err != nil {
+		t.Fatalf("couldn't load imported bundle: %s", err)
+	}
+	if bundle.Manifest.Pallet.Path != "github.com/x/p" {
+		t.Errorf("unexpected pallet in imported bundle: %+v", bundle.Manifest.Pallet)
+	}
+}
+
+func TestImportArchivedDownloadsUnrequired(t *testing.T) {
+	extracted := newTestArchivedBundle(t)
+	writeTestFile(
+		t, filepath.Join(extracted, archivedDownloadsDirName, "http-files/example.com/other.txt"),
+		"unrequired",
+	)
+	dlCache := newTestDownloadCache(t)
+	if _, err := ImportArchivedDownloads(extracted, dlCache); err == nil {
+		t.Fatal("expected an error for a download not required by the bundle")
+	}
+	// No downloads should be imported from a rejected archive:
+	entries, err := os.ReadDir(filepath.FromSlash(dlCache.Path()))
+	if err != nil || len(entries) > 0 {
+		t.Errorf("expected an empty download cache, got %v (%v)", entries, err)
+	}
+}
+
+func TestWriteBundleArchiveMissingDownload(t *testing.T) {
+	bundle := newTestBundle(t, BundleManifest{
+		Downloads: map[string]BundleDeplDownloads{
+			"app": {HTTPFile: []string{"https://example.com/files/a.txt"}},
+		},
+	}, testBundleFiles)
+	if err := WriteBundleArchive(
+		&bytes.Buffer{}, bundle, newTestDownloadCache(t), "v0.1.0", "", nil,
+	); err == nil {
+		t.Error("expected an error for a download missing from the cache")
+	}
+}
@@ -0,0 +1,172 @@
+package forklift
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// integrityCheckedDirNames lists the directories of a bundle whose contents are recorded in the
+// bundle's integrity manifest.
+var integrityCheckedDirNames = []string{
+	bundledPalletDirName, bundledMergedPalletDirName, packagesDirName, exportsDirName,
+}
+
+// hashedIntegrityCheckedDirNames lists the additional directories of a bundle whose contents are
+// recorded in the bundle's integrity manifest if the bundle manifest is also recorded there (as
+// indicated by [BundleManifest.Hashed]).
+var hashedIntegrityCheckedDirNames = []string{imagesDirName}
+
+// WriteIntegrityManifest hashes all files in the bundle's checked directories (as well as, if the
+// bundle manifest has [BundleManifest.Hashed] set, the bundle manifest itself), and it saves the
+// hashes as the bundle's integrity manifest. It must be called again whenever the bundle manifest
+// or any file in the checked directories is changed.
+func (b *FSBundle) WriteIntegrityManifest() error {
+	manifest, err := b.computeIntegrityManifest()
+	if err != nil {
+		return err
+	}
+	marshaled, err := yaml.Marshal(manifest)
+	if err != nil {
+		return errors.Wrap(err, "couldn't marshal bundle integrity manifest")
+	}
+	outputPath := path.Join(b.FS.Path(), BundleIntegrityManifestFile)
+	const perm = 0o644 // owner rw, group r, public r
+	if err = os.WriteFile(filepath.FromSlash(outputPath), marshaled, perm); err != nil {
+		return errors.Wrapf(err, "couldn't save bundle integrity manifest to %s", outputPath)
+	}
+	return nil
+}
+
+func (b *FSBundle) computeIntegrityManifest() (BundleIntegrityManifest, error) {
+	manifest := BundleIntegrityManifest{
+		Files: make(map[string]string),
+		Links: make(map[string]string),
+	}
+	bundlePath := filepath.FromSlash(b.FS.Path())
+	dirNames := integrityCheckedDirNames
+	// Bundles made by older versions of Forklift didn't record the bundle manifest or the bundled
+	// container images in their integrity manifests:
+	if b.Manifest.Hashed {
+		hash, err := hashFile(filepath.Join(bundlePath, BundleManifestFile))
+		if err != nil {
+			return BundleIntegrityManifest{}, err
+		}
+		manifest.Files[BundleManifestFile] = hash
+		dirNames = slices.Concat(dirNames, hashedIntegrityCheckedDirNames)
+	}
+	for _, dirName := range dirNames {
+		dirPath := filepath.Join(bundlePath, dirName)
+		if !DirExists(dirPath) {
+			continue
+		}
+		if err := filepath.WalkDir(dirPath, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			relPath, err := filepath.Rel(bundlePath, filePath)
+			if err != nil {
+				return err
+			}
+			name := filepath.ToSlash(relPath)
+
+			switch {
+			case d.IsDir():
+				return nil
+			case d.Type()&fs.ModeSymlink != 0:
+				target, err := os.Readlink(filePath)
+				if err != nil {
+					return errors.Wrapf(err, "couldn't read symlink %s", filePath)
+				}
+				manifest.Links[name] = filepath.ToSlash(target)
+			case d.Type().IsRegular():
+				if manifest.Files[name], err = hashFile(filePath); err != nil {
+					return err
+				}
+			default:
+				return errors.Errorf("%s has unsupported file type %s", filePath, d.Type())
+			}
+			return nil
+		}); err != nil {
+			return BundleIntegrityManifest{}, errors.Wrapf(err, "couldn't hash files in %s", dirPath)
+		}
+	}
+	return manifest, nil
+}
+
+// LoadIntegrityManifest loads the bundle's integrity manifest, if it exists. Bundles made by older
+// versions of Forklift don't have integrity manifests.
+func (b *FSBundle) LoadIntegrityManifest() (manifest BundleIntegrityManifest, ok bool, err error) {
+	marshaled, err := fs.ReadFile(b.FS, BundleIntegrityManifestFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return BundleIntegrityManifest{}, false, nil
+		}
+		return BundleIntegrityManifest{}, false, errors.Wrapf(
+			err, "couldn't read bundle integrity manifest %s/%s", b.FS.Path(),
+			BundleIntegrityManifestFile,
+		)
+	}
+	if err = yaml.Unmarshal(marshaled, &manifest); err != nil {
+		return BundleIntegrityManifest{}, false, errors.Wrap(
+			err, "couldn't parse bundle integrity manifest",
+		)
+	}
+	return manifest, true, nil
+}
+
+// CheckIntegrity compares the bundle manifest and the files in the bundle's checked directories
+// against the bundle's integrity manifest, returning a description of every mismatch (i.e. every
+// file which is missing, modified, or unexpected). It returns an error if the bundle has no
+// integrity manifest.
+func (b *FSBundle) CheckIntegrity() (mismatches []string, err error) {
+	expected, ok, err := b.LoadIntegrityManifest()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if b.Manifest.Hashed {
+			return nil, errors.Errorf(
+				"bundle %s was made with an integrity manifest, but its integrity manifest is missing",
+				b.FS.Path(),
+			)
+		}
+		return nil, errors.Errorf(
+			"bundle %s has no integrity manifest (maybe it was made by an older version of Forklift?)",
+			b.FS.Path(),
+		)
+	}
+	actual, err := b.computeIntegrityManifest()
+	if err != nil {
+		return nil, err
+	}
+	mismatches = append(mismatches, diffIntegrityEntries("file", expected.Files, actual.Files)...)
+	mismatches = append(mismatches, diffIntegrityEntries("symlink", expected.Links, actual.Links)...)
+	return mismatches, nil
+}
+
+func diffIntegrityEntries(entryType string, expected, actual map[string]string) []string {
+	var mismatches []string
+	for _, name := range slices.Sorted(maps.Keys(expected)) {
+		actualValue, ok := actual[name]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("%s %s is missing", entryType, name))
+		case actualValue != expected[name]:
+			mismatches = append(mismatches, fmt.Sprintf("%s %s was modified", entryType, name))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(actual)) {
+		if _, ok := expected[name]; !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%s %s is unexpected", entryType, name))
+		}
+	}
+	return mismatches
+}
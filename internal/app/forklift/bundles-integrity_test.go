@@ -0,0 +1,106 @@
+package forklift
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		modify   func(t *testing.T, bundlePath string)
+		expected []string
+	}{
+		{
+			name:   "unchanged",
+			modify: func(t *testing.T, bundlePath string) {},
+		},
+		{
+			name: "modified file",
+			modify: func(t *testing.T, bundlePath string) {
+				writeTestFile(t, filepath.Join(bundlePath, exportsDirName, "etc/app.conf"), "changed")
+			},
+			expected: []string{"file exports/etc/app.conf was modified"},
+		},
+		{
+			name: "missing file",
+			modify: func(t *testing.T, bundlePath string) {
+				if err := os.Remove(filepath.Join(bundlePath, exportsDirName, "etc/app.conf")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []string{"file exports/etc/app.conf is missing"},
+		},
+		{
+			name: "unexpected file",
+			modify: func(t *testing.T, bundlePath string) {
+				writeTestFile(t, filepath.Join(bundlePath, packagesDirName, "extra.yml"), "extra")
+			},
+			expected: []string{"file packages/extra.yml is unexpected"},
+		},
+		{
+			name: "modified image",
+			modify: func(t *testing.T, bundlePath string) {
+				writeTestFile(
+					t, filepath.Join(bundlePath, imagesDirName, "docker.io/library/alpine/latest.tar"),
+					"changed",
+				)
+			},
+			expected: []string{"file images/docker.io/library/alpine/latest.tar was modified"},
+		},
+		{
+			name: "modified bundle manifest",
+			modify: func(t *testing.T, bundlePath string) {
+				writeTestFile(t, filepath.Join(bundlePath, BundleManifestFile), "hashed: true\n")
+			},
+			expected: []string{"file forklift-bundle.yml was modified"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			bundle := newTestBundle(t, BundleManifest{Hashed: true}, testBundleFiles)
+			if err := bundle.WriteIntegrityManifest(); err != nil {
+				t.Fatalf("couldn't write integrity manifest: %s", err)
+			}
+			test.modify(t, filepath.FromSlash(bundle.FS.Path()))
+
+			mismatches, err := bundle.CheckIntegrity()
+			if err != nil {
+				t.Fatalf("couldn't check integrity: %s", err)
+			}
+			if !slices.Equal(mismatches, test.expected) {
+				t.Errorf("expected mismatches %v, got %v", test.expected, mismatches)
+			}
+		})
+	}
+}
+
+func TestCheckIntegrityUnhashedManifest(t *testing.T) {
+	// Bundles made by older versions of Forklift didn't record the bundle manifest or the bundled
+	// images in their integrity manifests, so changes to them aren't detected:
+	bundle := newTestBundle(t, BundleManifest{}, testBundleFiles)
+	if err := bundle.WriteIntegrityManifest(); err != nil {
+		t.Fatalf("couldn't write integrity manifest: %s", err)
+	}
+	bundlePath := filepath.FromSlash(bundle.FS.Path())
+	writeTestFile(t, filepath.Join(bundlePath, imagesDirName, "extra.tar"), "image")
+	writeTestFile(t, filepath.Join(bundlePath, BundleManifestFile), "forklift-version: v0.1.0\n")
+
+	mismatches, err := bundle.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("couldn't check integrity: %s", err)
+	}
+	if len(mismatches) > 0 {
+		t.Errorf("expected no mismatches, got %v", mismatches)
+	}
+}
+
+func TestCheckIntegrityMissingManifest(t *testing.T) {
+	for _, hashed := range []bool{false, true} {
+		bundle := newTestBundle(t, BundleManifest{Hashed: hashed}, testBundleFiles)
+		if _, err := bundle.CheckIntegrity(); err == nil {
+			t.Errorf("expected an error for a missing integrity manifest (hashed: %t)", hashed)
+		}
+	}
+}
@@ -17,6 +17,9 @@ const (
 	// exportsDirName is the name of the directory containing exported files for all package
 	// deployments, collected together.
 	exportsDirName = "exports"
+	// imagesDirName is the name of the directory containing tarballs of the container images used by
+	// all package deployments, if the bundle includes container images.
+	imagesDirName = "images"
 )
 
 // A FSBundle is a Forklift pallet bundle stored at the root of a [fs.FS] filesystem.
@@ -28,8 +31,8 @@ type FSBundle struct {
 }
 
 // A Bundle is a Forklift pallet bundle, a complete compilation of all files (except container
-// images) needed for a pallet to be applied to a Docker host. Required repos & pallets are included
-// directly in the bundle.
+// images, which are only optionally included) needed for a pallet to be applied to a Docker host.
+// Required repos & pallets are included directly in the bundle.
 type Bundle struct {
 	// Manifest is the Forklift bundle manifest for the pallet bundle.
 	Manifest BundleManifest
@@ -64,6 +67,9 @@ type BundleManifest struct {
 	// Exports lists the exposed paths of resources created by the bundle's deployments. Keys are
 	// names of the bundle's deployments which provide resources.
 	Exports map[string]BundleDeplExports `yaml:"exports,omitempty"`
+	// Hashed indicates that the bundle was made with an integrity manifest, so that the bundle must
+	// be treated as corrupted if its integrity manifest is missing.
+	Hashed bool `yaml:"hashed,omitempty"`
 }
 
 // BundlePallet describes a bundle's bundled pallet.
@@ -73,6 +79,8 @@ type BundlePallet struct {
 	Path string `yaml:"path"`
 	// Version is the version or pseudoversion of the bundled pallet, if one can be determined.
 	Version string `yaml:"version"`
+	// Commit is the full hash of the Git commit of the bundled pallet, if one can be determined.
+	Commit string `yaml:"commit,omitempty"`
 	// Clean indicates whether the bundled pallet has been determined to have no changes beyond its
 	// latest Git commit, if the pallet is version-controlled with Git. This does not account for
 	// overrides of required repos/pallets - those should be checked in BundleInclusions instead.
@@ -162,3 +170,81 @@ type BundleDeplComposeApp struct {
 	// RequiredNetworks lists the names of the networks required by the Docker Compose app.
 	RequiredNetworks []string `yaml:"required-networks,omitempty"`
 }
+
+// Bundle archives
+
+const (
+	// BundleArchiveManifestFile is the name of the file describing the contents of a bundle archive.
+	BundleArchiveManifestFile = "forklift-bundle-archive.yml"
+	// archivedBundleDirName is the name of the directory in a bundle archive containing the bundle.
+	archivedBundleDirName = "bundle"
+	// archivedDownloadsDirName is the name of the directory in a bundle archive containing cached
+	// downloads required by the bundle, at the same paths as in the download cache.
+	archivedDownloadsDirName = "downloads"
+	// archivedImagesFile is the name of the file in a bundle archive containing container images
+	// required by the bundle, as a tarball in the format of `docker image save`.
+	archivedImagesFile = "images.tar"
+)
+
+// A BundleArchiveManifest describes the contents of a bundle archive, a single file containing a
+// pallet bundle together with the downloads (and, optionally, the container images) it needs, so
+// that the bundle can be staged on a host without internet access.
+type BundleArchiveManifest struct {
+	// ForkliftVersion is the version of the Forklift tool which created the bundle archive.
+	ForkliftVersion string `yaml:"forklift-version"`
+	// Pallet describes the pallet in the archived bundle.
+	Pallet BundlePallet `yaml:"pallet"`
+	// Images lists the container images included in the bundle archive.
+	Images []string `yaml:"images,omitempty"`
+	// Files maps the path of every regular file in the bundle archive (other than the bundle archive
+	// manifest) to the SHA-256 hash of its contents.
+	Files map[string]string `yaml:"files"`
+	// Links maps the path of every symbolic link in the bundle archive to the link's target.
+	Links map[string]string `yaml:"links,omitempty"`
+}
+
+// Bundle signatures
+
+const (
+	// BundleSignatureFile is the name of the file containing the detached signature of a pallet
+	// bundle, in the bundle's directory. The signature file itself isn't part of the bundle's signed
+	// contents.
+	BundleSignatureFile = "forklift-bundle-signature.yml"
+	// BundleSignatureAlgorithmEd25519 is the name of the Ed25519 signature algorithm for pallet
+	// bundle signatures.
+	BundleSignatureAlgorithmEd25519 = "ed25519"
+)
+
+// A BundleSignature is a detached signature over the canonical content hash of a pallet bundle.
+type BundleSignature struct {
+	// Algorithm is the signature algorithm. Currently only "ed25519" is supported.
+	Algorithm string `yaml:"algorithm"`
+	// PublicKey is the base64-encoded public key corresponding to the private key which made the
+	// signature.
+	PublicKey string `yaml:"public-key"`
+	// ContentHash is the hex-encoded canonical SHA-256 hash of the bundle's contents, for
+	// informational purposes; the signature is always checked against a freshly-computed hash.
+	ContentHash string `yaml:"content-hash"`
+	// Signature is the base64-encoded signature over the canonical content hash of the bundle.
+	Signature string `yaml:"signature"`
+}
+
+// Bundle integrity manifests
+
+// BundleIntegrityManifestFile is the name of the file listing the SHA-256 hashes of the bundled
+// files in each Forklift pallet bundle.
+const BundleIntegrityManifestFile = "forklift-bundle-hashes.yml"
+
+// A BundleIntegrityManifest records the contents of the bundle manifest, the bundled pallet, the
+// bundled merged pallet, the bundled packages, the bundled file exports, and the bundled container
+// images of a Forklift pallet bundle, so that the bundle can be checked for corruption (e.g. by an
+// interrupted write or by storage degradation).
+type BundleIntegrityManifest struct {
+	// Files maps the path (relative to the root of the bundle) of the bundle manifest and of every
+	// regular file in the bundle's checked directories to the hex-encoded SHA-256 hash of its
+	// contents.
+	Files map[string]string `yaml:"files"`
+	// Links maps the path (relative to the root of the bundle) of every symbolic link in the
+	// bundle's checked directories to the link's target.
+	Links map[string]string `yaml:"links,omitempty"`
+}
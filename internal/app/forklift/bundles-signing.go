@@ -0,0 +1,331 @@
+package forklift
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Content hashing
+
+// bundleContentHashVersion identifies the format used to serialize a bundle's contents for the
+// canonical content hash, so that the format can be changed in the future without ambiguity.
+const bundleContentHashVersion = "forklift-bundle-content-v1"
+
+// ComputeContentHash computes the canonical SHA-256 hash of the bundle's contents, i.e. the paths
+// of all directories, regular files, and symlinks in the bundle (except for the bundle's signature
+// file), the contents of all regular files, and the targets of all symlinks. The hash is returned
+// as a hex-encoded string.
+func (b *FSBundle) ComputeContentHash() (string, error) {
+	hasher := sha256.New()
+	fmt.Fprintln(hasher, bundleContentHashVersion)
+	bundlePath := filepath.FromSlash(b.FS.Path())
+	if err := filepath.WalkDir(bundlePath, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(bundlePath, filePath)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(relPath)
+
+		switch {
+		case name == "." || name == BundleSignatureFile:
+			return nil
+		case d.IsDir():
+			fmt.Fprintf(hasher, "dir %q\n", name)
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(filePath)
+			if err != nil {
+				return errors.Wrapf(err, "couldn't read symlink %s", filePath)
+			}
+			fmt.Fprintf(hasher, "symlink %q %q\n", name, filepath.ToSlash(target))
+		case d.Type().IsRegular():
+			fileHash, err := hashFile(filePath)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hasher, "file %q %s\n", name, fileHash)
+		default:
+			return errors.Errorf("%s has unsupported file type %s", filePath, d.Type())
+		}
+		return nil
+	}); err != nil {
+		return "", errors.Wrapf(err, "couldn't hash contents of bundle %s", b.FS.Path())
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// hashFile returns the hex-encoded SHA-256 hash of the contents of the file at the specified path
+// in the OS's filesystem.
+func hashFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't open %s", filePath)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	hasher := sha256.New()
+	if _, err = io.Copy(hasher, file); err != nil {
+		return "", errors.Wrapf(err, "couldn't read %s", filePath)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Signing
+
+// bundleSignaturePayload returns the message which is signed to make a signature of a bundle with
+// the specified canonical content hash.
+func bundleSignaturePayload(contentHash string) []byte {
+	return []byte(bundleContentHashVersion + ":" + contentHash)
+}
+
+// Sign makes a signature over the bundle's canonical content hash with the specified private key,
+// and saves it as the bundle's signature file (replacing any existing signature).
+func (b *FSBundle) Sign(key ed25519.PrivateKey) (BundleSignature, error) {
+	contentHash, err := b.ComputeContentHash()
+	if err != nil {
+		return BundleSignature{}, err
+	}
+	publicKey, ok := key.Public().(ed25519.PublicKey)
+	if !ok {
+		return BundleSignature{}, errors.New("couldn't determine public key of signing key")
+	}
+	signature := BundleSignature{
+		Algorithm:   BundleSignatureAlgorithmEd25519,
+		PublicKey:   EncodePublicKey(publicKey),
+		ContentHash: contentHash,
+		Signature: base64.StdEncoding.EncodeToString(
+			ed25519.Sign(key, bundleSignaturePayload(contentHash)),
+		),
+	}
+	marshaled, err := yaml.Marshal(signature)
+	if err != nil {
+		return BundleSignature{}, errors.Wrap(err, "couldn't marshal bundle signature")
+	}
+	outputPath := path.Join(b.FS.Path(), BundleSignatureFile)
+	const perm = 0o644 // owner rw, group r, public r
+	if err = os.WriteFile(filepath.FromSlash(outputPath), marshaled, perm); err != nil {
+		return BundleSignature{}, errors.Wrapf(err, "couldn't save bundle signature to %s", outputPath)
+	}
+	return signature, nil
+}
+
+// LoadSignature loads the bundle's signature file, if it exists.
+func (b *FSBundle) LoadSignature() (signature BundleSignature, ok bool, err error) {
+	marshaled, err := fs.ReadFile(b.FS, BundleSignatureFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return BundleSignature{}, false, nil
+		}
+		return BundleSignature{}, false, errors.Wrapf(
+			err, "couldn't read bundle signature file %s/%s", b.FS.Path(), BundleSignatureFile,
+		)
+	}
+	if err = yaml.Unmarshal(marshaled, &signature); err != nil {
+		return BundleSignature{}, false, errors.Wrap(err, "couldn't parse bundle signature")
+	}
+	return signature, true, nil
+}
+
+// VerifySignature checks that the bundle has a signature, and that the signature is valid for the
+// bundle's current contents. It does not check whether the signature's key is trusted.
+func (b *FSBundle) VerifySignature() (BundleSignature, error) {
+	signature, ok, err := b.LoadSignature()
+	if err != nil {
+		return BundleSignature{}, err
+	}
+	if !ok {
+		return BundleSignature{}, errors.Errorf("bundle %s is not signed", b.FS.Path())
+	}
+	if signature.Algorithm != BundleSignatureAlgorithmEd25519 {
+		return signature, errors.Errorf(
+			"bundle signature has unsupported algorithm %s", signature.Algorithm,
+		)
+	}
+	publicKey, err := DecodePublicKey(signature.PublicKey)
+	if err != nil {
+		return signature, errors.Wrap(err, "couldn't parse bundle signature's public key")
+	}
+	rawSignature, err := base64.StdEncoding.DecodeString(signature.Signature)
+	if err != nil {
+		return signature, errors.Wrap(err, "couldn't decode bundle signature")
+	}
+	contentHash, err := b.ComputeContentHash()
+	if err != nil {
+		return signature, err
+	}
+	if !ed25519.Verify(publicKey, bundleSignaturePayload(contentHash), rawSignature) {
+		return signature, errors.Errorf(
+			"bundle signature is invalid: either the bundle was modified after it was signed (its "+
+				"content hash is now %s), or the signature was corrupted",
+			contentHash,
+		)
+	}
+	return signature, nil
+}
+
+// FindKey looks up the trusted key with the specified base64-encoded public key.
+func (c BundleTrustConfig) FindKey(publicKey string) (key TrustedKey, ok bool) {
+	for _, key := range c.Keys {
+		if key.PublicKey == publicKey {
+			return key, true
+		}
+	}
+	return TrustedKey{}, false
+}
+
+// CheckBundle checks that the bundle has a valid signature by a trusted key, returning that key.
+func (c BundleTrustConfig) CheckBundle(bundle *FSBundle) (TrustedKey, error) {
+	signature, err := bundle.VerifySignature()
+	if err != nil {
+		return TrustedKey{}, err
+	}
+	key, ok := c.FindKey(signature.PublicKey)
+	if !ok {
+		return TrustedKey{}, errors.Errorf(
+			"bundle is signed by untrusted key %s", signature.PublicKey,
+		)
+	}
+	return key, nil
+}
+
+// Signing keys
+
+const (
+	privateKeyPEMType = "PRIVATE KEY"
+	publicKeyPEMType  = "PUBLIC KEY"
+)
+
+// GenerateSigningKey generates a new Ed25519 private key for signing bundles.
+func GenerateSigningKey() (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't generate Ed25519 key")
+	}
+	return key, nil
+}
+
+// WriteSigningKey saves the private key as a PEM-encoded PKCS #8 file (which is only readable by
+// the file's owner) at the specified path, and it saves the corresponding public key as a
+// PEM-encoded PKIX file at the specified public key path. These are the same formats as used by
+// `openssl genpkey -algorithm ed25519` and `openssl pkey -pubout`. Existing files are never
+// overwritten.
+func WriteSigningKey(key ed25519.PrivateKey, privateKeyPath, publicKeyPath string) error {
+	marshaledPrivate, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return errors.Wrap(err, "couldn't marshal private key")
+	}
+	marshaledPublic, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return errors.Wrap(err, "couldn't marshal public key")
+	}
+	const privatePerm = 0o600 // owner rw
+	if err = writeNewPEMFile(
+		privateKeyPath, privateKeyPEMType, marshaledPrivate, privatePerm,
+	); err != nil {
+		return err
+	}
+	const publicPerm = 0o644 // owner rw, group r, public r
+	return writeNewPEMFile(publicKeyPath, publicKeyPEMType, marshaledPublic, publicPerm)
+}
+
+func writeNewPEMFile(filePath, blockType string, data []byte, perm fs.FileMode) (err error) {
+	file, err := os.OpenFile(
+		filepath.FromSlash(filePath), os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm,
+	)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't create %s", filePath)
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil && err == nil {
+			err = errors.Wrapf(cerr, "couldn't close %s", filePath)
+		}
+	}()
+	return errors.Wrapf(
+		pem.Encode(file, &pem.Block{Type: blockType, Bytes: data}), "couldn't write %s", filePath,
+	)
+}
+
+// LoadSigningKey loads an Ed25519 private key from a PEM-encoded PKCS #8 file at the specified
+// path.
+func LoadSigningKey(filePath string) (ed25519.PrivateKey, error) {
+	data, err := readPEMFile(filePath, privateKeyPEMType)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't parse private key in %s", filePath)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.Errorf("private key in %s is not an Ed25519 key", filePath)
+	}
+	return key, nil
+}
+
+// LoadPublicKey loads an Ed25519 public key from a PEM-encoded PKIX file at the specified path.
+func LoadPublicKey(filePath string) (ed25519.PublicKey, error) {
+	data, err := readPEMFile(filePath, publicKeyPEMType)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKIXPublicKey(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't parse public key in %s", filePath)
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("public key in %s is not an Ed25519 key", filePath)
+	}
+	return key, nil
+}
+
+func readPEMFile(filePath, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.FromSlash(filePath))
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't read %s", filePath)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, errors.Errorf("%s doesn't contain a PEM-encoded %s", filePath, blockType)
+	}
+	return block.Bytes, nil
+}
+
+// EncodePublicKey encodes the Ed25519 public key in base64, as used in bundle signatures and in the
+// bundle trust config.
+func EncodePublicKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// DecodePublicKey decodes a base64-encoded Ed25519 public key.
+func DecodePublicKey(encoded string) (ed25519.PublicKey, error) {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't decode public key")
+	}
+	if len(decoded) != ed25519.PublicKeySize {
+		return nil, errors.Errorf(
+			"public key has length %d, but Ed25519 public keys must have length %d",
+			len(decoded), ed25519.PublicKeySize,
+		)
+	}
+	return ed25519.PublicKey(decoded), nil
+}
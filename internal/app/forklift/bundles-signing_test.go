@@ -0,0 +1,135 @@
+package forklift
+
+import (
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+)
+
+func newTestSigningKey(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+	key, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("couldn't generate signing key: %s", err)
+	}
+	return key, EncodePublicKey(key.Public().(ed25519.PublicKey))
+}
+
+func TestCheckBundleTrust(t *testing.T) {
+	trustedKey, trustedPublicKey := newTestSigningKey(t)
+	untrustedKey, _ := newTestSigningKey(t)
+	config := BundleTrustConfig{
+		Enforce: true,
+		Keys:    []TrustedKey{{Name: "release", PublicKey: trustedPublicKey}},
+	}
+
+	for _, test := range []struct {
+		name    string
+		key     ed25519.PrivateKey
+		modify  func(t *testing.T, bundlePath string)
+		trusted bool
+	}{
+		{name: "signed by trusted key", key: trustedKey, trusted: true},
+		{name: "signed by untrusted key", key: untrustedKey},
+		{name: "unsigned"},
+		{
+			name: "modified after signing",
+			key:  trustedKey,
+			modify: func(t *testing.T, bundlePath string) {
+				writeTestFile(t, filepath.Join(bundlePath, exportsDirName, "etc/app.conf"), "changed")
+			},
+		},
+		{
+			name: "file added after signing",
+			key:  trustedKey,
+			modify: func(t *testing.T, bundlePath string) {
+				writeTestFile(t, filepath.Join(bundlePath, exportsDirName, "etc/extra.conf"), "extra")
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			bundle := newTestBundle(t, BundleManifest{}, testBundleFiles)
+			if test.key != nil {
+				if _, err := bundle.Sign(test.key); err != nil {
+					t.Fatalf("couldn't sign bundle: %s", err)
+				}
+			}
+			if test.modify != nil {
+				test.modify(t, filepath.FromSlash(bundle.FS.Path()))
+			}
+
+			key, err := config.CheckBundle(bundle)
+			switch {
+			case test.trusted && err != nil:
+				t.Errorf("expected bundle to be trusted, got error: %s", err)
+			case test.trusted && key.Name != "release":
+				t.Errorf("expected bundle to be signed by key release, got %+v", key)
+			case !test.trusted && err == nil:
+				t.Error("expected bundle not to be trusted")
+			}
+		})
+	}
+}
+
+func TestSigningKeyFiles(t *testing.T) {
+	key, publicKey := newTestSigningKey(t)
+	dir := t.TempDir()
+	privateKeyPath := filepath.Join(dir, "key")
+	publicKeyPath := filepath.Join(dir, "key.pub")
+	if err := WriteSigningKey(key, privateKeyPath, publicKeyPath); err != nil {
+		t.Fatalf("couldn't write signing key: %s", err)
+	}
+	if err := WriteSigningKey(key, privateKeyPath, publicKeyPath); err == nil {
+		t.Error("expected an error for overwriting an existing signing key")
+	}
+
+	loadedKey, err := LoadSigningKey(privateKeyPath)
+	if err != nil {
+		t.Fatalf("couldn't load signing key: %s", err)
+	}
+	if !loadedKey.Equal(key) {
+		t.Error("loaded signing key doesn't match the written signing key")
+	}
+	loadedPublicKey, err := LoadPublicKey(publicKeyPath)
+	if err != nil {
+		t.Fatalf("couldn't load public key: %s", err)
+	}
+	if EncodePublicKey(loadedPublicKey) != publicKey {
+		t.Error("loaded public key doesn't match the written public key")
+	}
+	if _, err = DecodePublicKey("not a key"); err == nil {
+		t.Error("expected an error for decoding an invalid public key")
+	}
+}
+
+func TestStageStoreBundleTrust(t *testing.T) {
+	_, publicKey := newTestSigningKey(t)
+	store := newTestStageStore(t)
+	if config := store.GetBundleTrust(); config.Enforce || len(config.Keys) > 0 {
+		t.Errorf("expected an empty bundle trust config, got %+v", config)
+	}
+
+	store.SetBundleTrust(BundleTrustConfig{
+		Enforce: true,
+		Keys:    []TrustedKey{{Name: "release", PublicKey: publicKey}},
+	})
+	if err := store.CommitState(); err != nil {
+		t.Fatalf("couldn't commit stage store: %s", err)
+	}
+	reloaded, err := LoadFSStageStore(DirFS(filepath.ToSlash(filepath.Dir(store.Path()))), "stages")
+	if err != nil {
+		t.Fatalf("couldn't reload stage store: %s", err)
+	}
+	config := reloaded.GetBundleTrust()
+	if !config.Enforce {
+		t.Error("expected bundle trust to be enforced")
+	}
+	if _, ok := config.FindKey(publicKey); !ok {
+		t.Errorf("expected key %s to be trusted, got %+v", publicKey, config.Keys)
+	}
+
+	reloaded.SetBundleTrust(BundleTrustConfig{})
+	if reloaded.Manifest.BundleTrust != nil {
+		t.Errorf("expected an empty bundle trust config to be removed from the stage store manifest")
+	}
+}
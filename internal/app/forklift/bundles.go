@@ -456,12 +456,67 @@ func (b *FSBundle) WriteRepoDefFile() error {
 	)
 }
 
+// FSBundle: Images
+
+// getBundledImagePath returns the path of the tarball of the container image with the specified
+// name, relative to the bundle's images directory. Unlike image names in the download cache, image
+// names in Compose apps need not be fully-qualified (e.g. "nginx:1.27" is allowed).
+func getBundledImagePath(imageName string) (string, error) {
+	return getImageTarballPath(imageName)
+}
+
+func (b *FSBundle) getImagesPath() string {
+	return path.Join(b.FS.Path(), imagesDirName)
+}
+
+// ListImages returns the names of the container images used by the Compose apps of all package
+// deployments in the bundle.
+func (b *FSBundle) ListImages() []string {
+	images := make(structures.Set[string])
+	for _, exports := range b.Manifest.Exports {
+		images.Add(exports.ComposeApp.Images...)
+	}
+	return slices.Sorted(images.All())
+}
+
+// GetImagePath returns the path where a tarball of the container image with the specified name
+// should be stored in the bundle's filesystem, if the bundle includes the image.
+func (b *FSBundle) GetImagePath(imageName string) (string, error) {
+	imagePath, err := getBundledImagePath(imageName)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(b.getImagesPath(), imagePath), nil
+}
+
+// HasImage checks whether the bundle includes a tarball of the container image with the specified
+// name.
+func (b *FSBundle) HasImage(imageName string) (bool, error) {
+	imagePath, err := getBundledImagePath(imageName)
+	if err != nil {
+		return false, err
+	}
+	if _, err = fs.Stat(b.FS, path.Join(imagesDirName, imagePath)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 // FSBundle: Exports
 
 func (b *FSBundle) getExportsPath() string {
 	return path.Join(b.FS.Path(), exportsDirName)
 }
 
+// ReadFileExport returns the contents of the file exported to the specified target path in the
+// bundle.
+func (b *FSBundle) ReadFileExport(target string) ([]byte, error) {
+	return fs.ReadFile(b.FS, path.Join(exportsDirName, strings.TrimPrefix(target, "/")))
+}
+
 func (b *FSBundle) WriteFileExports(dlCache *FSDownloadCache) error {
 	if err := EnsureExists(filepath.FromSlash(b.getExportsPath())); err != nil {
 		return errors.Wrapf(err, "couldn't make directory for all file exports")
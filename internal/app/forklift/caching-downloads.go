@@ -106,14 +106,24 @@ func (c *FSDownloadCache) GetOCIImagePath(imageName string) (string, error) {
 }
 
 func normalizeOCIImageName(rawImageName string) (string, error) {
-	ref, err := name.ParseReference(rawImageName, name.StrictValidation)
+	imagePath, err := getImageTarballPath(rawImageName, name.StrictValidation)
+	if err != nil {
+		return "", err
+	}
+	return path.Join("oci-image-fs-tarballs", imagePath), nil
+}
+
+// getImageTarballPath returns a relative path for a tarball of the container image with the
+// specified name, parsed with the specified options.
+func getImageTarballPath(rawImageName string, opts ...name.Option) (string, error) {
+	ref, err := name.ParseReference(rawImageName, opts...)
 	if err != nil {
 		return "", errors.Wrapf(err, "couldn't parse image name: %s", rawImageName)
 	}
 	parsed := ref.Name()
 
 	parsed = strings.ReplaceAll(parsed, ":", "/") // turn the tag into a directory
-	return path.Join("oci-image-fs-tarballs", parsed+".tar"), nil
+	return parsed + ".tar", nil
 }
 
 // HasOCIImage checks whether the OCI container image with the specified image name is stored in the
@@ -63,6 +63,7 @@ func DownloadExportFiles(
 		}
 		if ok {
 			IndentedFprintf(indent, os.Stderr, "Skipped already-cached file download: %s\n", url)
+			emitDownloadCached(httpFileResource, url)
 			continue
 		}
 		newHTTP = append(newHTTP, url)
@@ -78,6 +79,7 @@ func DownloadExportFiles(
 		}
 		if ok {
 			IndentedFprintf(indent, os.Stderr, "Skipped already-cached OCI image download: %s\n", url)
+			emitDownloadCached(ociImageResource, url)
 			continue
 		}
 		newOCI = append(newOCI, url)
@@ -152,7 +154,12 @@ func downloadParallel(
 			if err != nil {
 				return errors.Wrapf(err, "couldn't determine path to cache download for %s", url)
 			}
-			if err = downloadFile(egctx, url, outputPath, hc); err != nil {
+			finish := startDownloadEvents(
+				eventDownloadStarted, eventDownloadFinished, httpFileResource, url,
+			)
+			err = downloadFile(egctx, url, outputPath, hc)
+			finish(downloadSucceeded, err)
+			if err != nil {
 				return errors.Wrapf(err, "couldn't download %s", url)
 			}
 			IndentedFprintf(indent, os.Stderr, "Downloaded %s\n", url)
@@ -166,7 +173,12 @@ func downloadParallel(
 			if err != nil {
 				return errors.Wrapf(err, "couldn't determine path to cache download for %s", imageName)
 			}
-			if err = downloadOCIImage(egctx, imageName, outputPath, platform); err != nil {
+			finish := startDownloadEvents(
+				eventDownloadStarted, eventDownloadFinished, ociImageResource, imageName,
+			)
+			err = downloadOCIImage(egctx, imageName, outputPath, platform)
+			finish(downloadSucceeded, err)
+			if err != nil {
 				return errors.Wrapf(err, "couldn't download %s", imageName)
 			}
 			IndentedFprintf(indent, os.Stderr, "Downloaded %s\n", imageName)
@@ -189,7 +201,12 @@ func downloadSerial(
 		if err != nil {
 			return errors.Wrapf(err, "couldn't determine path to cache download for %s", url)
 		}
-		if err = downloadFile(context.Background(), url, outputPath, hc); err != nil {
+		finish := startDownloadEvents(
+			eventDownloadStarted, eventDownloadFinished, httpFileResource, url,
+		)
+		err = downloadFile(context.Background(), url, outputPath, hc)
+		finish(downloadSucceeded, err)
+		if err != nil {
 			return errors.Wrapf(err, "couldn't download %s", url)
 		}
 		IndentedFprintf(indent, os.Stderr, "Downloaded %s\n", url)
@@ -202,7 +219,12 @@ func downloadSerial(
 		if err != nil {
 			return errors.Wrapf(err, "couldn't determine path to cache download for %s", imageName)
 		}
-		if err = downloadOCIImage(context.Background(), imageName, outputPath, platform); err != nil {
+		finish := startDownloadEvents(
+			eventDownloadStarted, eventDownloadFinished, ociImageResource, imageName,
+		)
+		err = downloadOCIImage(context.Background(), imageName, outputPath, platform)
+		finish(downloadSucceeded, err)
+		if err != nil {
 			return errors.Wrapf(err, "couldn't download %s", imageName)
 		}
 		IndentedFprintf(indent, os.Stderr, "Downloaded %s\n", imageName)
@@ -241,7 +263,7 @@ func downloadFile(ctx context.Context, url, outputPath string, hc *http.Client)
 		}
 	}()
 
-	_, err = io.Copy(file, res.Body)
+	_, err = io.Copy(file, &progressReader{r: res.Body, source: url, total: res.ContentLength})
 	if err != nil {
 		return errors.Wrapf(err, "couldn't download %s to %s", url, tmpPath)
 	}
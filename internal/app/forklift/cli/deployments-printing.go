@@ -13,11 +13,16 @@ import (
 	"github.com/forklift-run/forklift/pkg/core"
 )
 
-func FprintPalletDepls(indent int, out io.Writer, pallet *forklift.FSPallet) error {
+func FprintPalletDepls(
+	indent int, out io.Writer, format string, pallet *forklift.FSPallet,
+) error {
 	depls, err := pallet.LoadDepls("**/*")
 	if err != nil {
 		return err
 	}
+	if IsStructuredOutput(format) {
+		return FprintDocument(out, format, newDeplSummaryDocuments(depls))
+	}
 	for _, depl := range depls {
 		IndentedFprintf(indent, out, "%s\n", depl.Name)
 	}
@@ -25,7 +30,7 @@ func FprintPalletDepls(indent int, out io.Writer, pallet *forklift.FSPallet) err
 }
 
 func FprintDeplInfo(
-	indent int, out io.Writer,
+	indent int, out io.Writer, format string,
 	pallet *forklift.FSPallet, cache forklift.PathedRepoCache, deplName string,
 ) error {
 	depl, err := pallet.LoadDepl(deplName)
@@ -39,15 +44,24 @@ func FprintDeplInfo(
 	if err != nil {
 		return errors.Wrapf(err, "couldn't resolve package deployment %s", depl.Name)
 	}
-	if err = FprintResolvedDepl(indent, out, cache, resolved); err != nil {
+	if err = FprintResolvedDepl(indent, out, format, cache, resolved); err != nil {
 		return errors.Wrapf(err, "couldn't print resolved package deployment %s", depl.Name)
 	}
 	return nil
 }
 
 func FprintResolvedDepl(
-	indent int, out io.Writer, cache forklift.PathedRepoCache, resolved *forklift.ResolvedDepl,
+	indent int, out io.Writer, format string,
+	cache forklift.PathedRepoCache, resolved *forklift.ResolvedDepl,
 ) error {
+	if IsStructuredOutput(format) {
+		doc, err := newDeplDocument(resolved)
+		if err != nil {
+			return err
+		}
+		return FprintDocument(out, format, doc)
+	}
+
 	if err := fprintDepl(indent, out, cache, resolved); err != nil {
 		return err
 	}
@@ -0,0 +1,594 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	"github.com/forklift-run/forklift/pkg/structures"
+)
+
+// A BundleDiffDocument describes the differences between two staged pallet bundles.
+type BundleDiffDocument struct {
+	// From is the index of the bundle being compared from.
+	From int `yaml:"from"`
+	// To is the index of the bundle being compared to.
+	To int `yaml:"to"`
+	// Pallet describes the change in the bundled pallet, if it changed.
+	Pallet *BundlePalletChange `yaml:"pallet,omitempty"`
+	// Deployments describes the package deployments which were added, removed, or changed.
+	Deployments DeplsDiffDocument `yaml:"deployments"`
+	// Repos describes the repos whose required versions were added, removed, or changed.
+	Repos ReposDiffDocument `yaml:"repositories"`
+	// Images describes the container images of Compose apps which were added or removed.
+	Images SetDiffDocument `yaml:"images"`
+	// HTTPFiles describes the HTTP files to download which were added or removed.
+	HTTPFiles SetDiffDocument `yaml:"http-files"`
+	// OCIImages describes the OCI images to download which were added or removed.
+	OCIImages SetDiffDocument `yaml:"oci-images"`
+	// Exports describes the exported files which were added, removed, or changed.
+	Exports FilesDiffDocument `yaml:"exports"`
+	// ComposeFiles describes the changes to the Compose files of package deployments present in
+	// both bundles.
+	ComposeFiles []FileDiffDocument `yaml:"compose-files,omitempty"`
+}
+
+// A BundlePalletChange describes the change in the pallet bundled in a staged pallet bundle.
+type BundlePalletChange struct {
+	// From describes the pallet in the bundle being compared from.
+	From forklift.BundlePallet `yaml:"from"`
+	// To describes the pallet in the bundle being compared to.
+	To forklift.BundlePallet `yaml:"to"`
+}
+
+// A SetDiffDocument describes the items which were added to or removed from a set.
+type SetDiffDocument struct {
+	// Added is a list of the items which were added.
+	Added []string `yaml:"added,omitempty"`
+	// Removed is a list of the items which were removed.
+	Removed []string `yaml:"removed,omitempty"`
+}
+
+// Empty returns true if no items were added or removed.
+func (d SetDiffDocument) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// A DeplsDiffDocument describes the package deployments which differ between two bundles.
+type DeplsDiffDocument struct {
+	// Added describes the package deployments which were added.
+	Added []DeplSummaryDocument `yaml:"added,omitempty"`
+	// Removed describes the package deployments which were removed.
+	Removed []DeplSummaryDocument `yaml:"removed,omitempty"`
+	// Changed describes the package deployments whose definitions were changed.
+	Changed []DeplChangeDocument `yaml:"changed,omitempty"`
+}
+
+// Empty returns true if no package deployments were added, removed, or changed.
+func (d DeplsDiffDocument) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// A DeplChangeDocument describes the changes to the definition of a package deployment.
+type DeplChangeDocument struct {
+	// Name is the name of the package deployment.
+	Name string `yaml:"name"`
+	// Package describes the change of the deployed package, if it changed.
+	Package *StringChange `yaml:"package,omitempty"`
+	// Features describes the enabled features which were added or removed.
+	Features SetDiffDocument `yaml:"features,omitempty"`
+	// Disabled describes the change of whether the package deployment is disabled, if it changed.
+	Disabled *BoolChange `yaml:"disabled,omitempty"`
+}
+
+// A StringChange describes a change of a string value.
+type StringChange struct {
+	// From is the value in the bundle being compared from.
+	From string `yaml:"from"`
+	// To is the value in the bundle being compared to.
+	To string `yaml:"to"`
+}
+
+// A BoolChange describes a change of a boolean value.
+type BoolChange struct {
+	// From is the value in the bundle being compared from.
+	From bool `yaml:"from"`
+	// To is the value in the bundle being compared to.
+	To bool `yaml:"to"`
+}
+
+// A ReposDiffDocument describes the repos which differ between two bundles.
+type ReposDiffDocument struct {
+	// Added describes the repos which were added, with their required versions.
+	Added []RepoVersionDocument `yaml:"added,omitempty"`
+	// Removed describes the repos which were removed, with their required versions.
+	Removed []RepoVersionDocument `yaml:"removed,omitempty"`
+	// Changed describes the repos whose required versions were changed.
+	Changed []RepoVersionChange `yaml:"changed,omitempty"`
+}
+
+// Empty returns true if no repos were added, removed, or changed.
+func (d ReposDiffDocument) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// A RepoVersionDocument describes the required version of a repo.
+type RepoVersionDocument struct {
+	// Path is the path of the repo.
+	Path string `yaml:"path"`
+	// Version is the required version of the repo.
+	Version string `yaml:"version"`
+}
+
+// A RepoVersionChange describes a change of the required version of a repo.
+type RepoVersionChange struct {
+	// Path is the path of the repo.
+	Path string `yaml:"path"`
+	// From is the required version of the repo in the bundle being compared from.
+	From string `yaml:"from"`
+	// To is the required version of the repo in the bundle being compared to.
+	To string `yaml:"to"`
+}
+
+// A FilesDiffDocument describes the files which differ between two bundles.
+type FilesDiffDocument struct {
+	// Added is a list of the paths of the files which were added.
+	Added []string `yaml:"added,omitempty"`
+	// Removed is a list of the paths of the files which were removed.
+	Removed []string `yaml:"removed,omitempty"`
+	// Changed describes the files whose contents were changed.
+	Changed []FileDiffDocument `yaml:"changed,omitempty"`
+}
+
+// Empty returns true if no files were added, removed, or changed.
+func (d FilesDiffDocument) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// A FileDiffDocument describes the change to the contents of a file.
+type FileDiffDocument struct {
+	// Path is the path of the file.
+	Path string `yaml:"path"`
+	// Binary is whether either version of the file is not a text file, in which case no diff is
+	// provided.
+	Binary bool `yaml:"binary,omitempty"`
+	// Diff is a unified diff of the file's contents, if it's a text file.
+	Diff string `yaml:"diff,omitempty"`
+}
+
+// diffContextLines is the number of lines of unchanged context around each change in unified
+// diffs.
+const diffContextLines = 3
+
+// DiffBundles determines the differences between two staged pallet bundles.
+func DiffBundles(
+	from *forklift.FSBundle, fromIndex int, to *forklift.FSBundle, toIndex int,
+) (BundleDiffDocument, error) {
+	diff := BundleDiffDocument{
+		From:        fromIndex,
+		To:          toIndex,
+		Deployments: diffBundleDepls(from.Manifest.Deploys, to.Manifest.Deploys),
+		Repos:       diffBundleRepos(from.Manifest.Includes.Repos, to.Manifest.Includes.Repos),
+	}
+	if from.Manifest.Pallet != to.Manifest.Pallet {
+		diff.Pallet = &BundlePalletChange{From: from.Manifest.Pallet, To: to.Manifest.Pallet}
+	}
+
+	diff.Images = diffSets(
+		listBundleImages(from.Manifest.Exports), listBundleImages(to.Manifest.Exports),
+	)
+	fromHTTPFiles, fromOCIImages := listBundleDownloads(from.Manifest.Downloads)
+	toHTTPFiles, toOCIImages := listBundleDownloads(to.Manifest.Downloads)
+	diff.HTTPFiles = diffSets(fromHTTPFiles, toHTTPFiles)
+	diff.OCIImages = diffSets(fromOCIImages, toOCIImages)
+
+	var err error
+	if diff.Exports, err = diffBundleFileExports(from, to); err != nil {
+		return BundleDiffDocument{}, errors.Wrap(err, "couldn't compare exported files")
+	}
+	if diff.ComposeFiles, err = diffBundleComposeFiles(from, to); err != nil {
+		return BundleDiffDocument{}, errors.Wrap(err, "couldn't compare Compose files")
+	}
+	return diff, nil
+}
+
+func newStringSet(items ...string) structures.Set[string] {
+	set := make(structures.Set[string])
+	set.Add(items...)
+	return set
+}
+
+func diffSets(from, to structures.Set[string]) SetDiffDocument {
+	return SetDiffDocument{
+		Added:   slices.Sorted(to.Difference(from).All()),
+		Removed: slices.Sorted(from.Difference(to).All()),
+	}
+}
+
+func diffBundleDepls(from, to map[string]forklift.DeplDef) (diff DeplsDiffDocument) {
+	for _, name := range slices.Sorted(maps.Keys(to)) {
+		if _, ok := from[name]; !ok {
+			diff.Added = append(diff.Added, DeplSummaryDocument{Name: name, Def: to[name]})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(from)) {
+		toDef, ok := to[name]
+		if !ok {
+			diff.Removed = append(diff.Removed, DeplSummaryDocument{Name: name, Def: from[name]})
+			continue
+		}
+		fromDef := from[name]
+		change := DeplChangeDocument{
+			Name: name,
+			Features: diffSets(
+				newStringSet(fromDef.Features...), newStringSet(toDef.Features...),
+			),
+		}
+		if fromDef.Package != toDef.Package {
+			change.Package = &StringChange{From: fromDef.Package, To: toDef.Package}
+		}
+		if fromDef.Disabled != toDef.Disabled {
+			change.Disabled = &BoolChange{From: fromDef.Disabled, To: toDef.Disabled}
+		}
+		if change.Package == nil && change.Features.Empty() && change.Disabled == nil {
+			continue
+		}
+		diff.Changed = append(diff.Changed, change)
+	}
+	return diff
+}
+
+func diffBundleRepos(
+	from, to map[string]forklift.BundleRepoInclusion,
+) (diff ReposDiffDocument) {
+	for _, path := range slices.Sorted(maps.Keys(to)) {
+		if _, ok := from[path]; !ok {
+			diff.Added = append(diff.Added, RepoVersionDocument{
+				Path: path, Version: to[path].Req.VersionLock.Version,
+			})
+		}
+	}
+	for _, path := range slices.Sorted(maps.Keys(from)) {
+		fromVersion := from[path].Req.VersionLock.Version
+		toInclusion, ok := to[path]
+		if !ok {
+			diff.Removed = append(diff.Removed, RepoVersionDocument{Path: path, Version: fromVersion})
+			continue
+		}
+		if toVersion := toInclusion.Req.VersionLock.Version; fromVersion != toVersion {
+			diff.Changed = append(diff.Changed, RepoVersionChange{
+				Path: path, From: fromVersion, To: toVersion,
+			})
+		}
+	}
+	return diff
+}
+
+func listBundleImages(exports map[string]forklift.BundleDeplExports) structures.Set[string] {
+	images := make(structures.Set[string])
+	for _, depl := range exports {
+		images.Add(depl.ComposeApp.Images...)
+	}
+	return images
+}
+
+func listBundleDownloads(
+	downloads map[string]forklift.BundleDeplDownloads,
+) (httpFiles, ociImages structures.Set[string]) {
+	httpFiles = make(structures.Set[string])
+	ociImages = make(structures.Set[string])
+	for _, depl := range downloads {
+		httpFiles.Add(depl.HTTPFile...)
+		ociImages.Add(depl.OCIImage...)
+	}
+	return httpFiles, ociImages
+}
+
+func listBundleFileExports(exports map[string]forklift.BundleDeplExports) structures.Set[string] {
+	targets := make(structures.Set[string])
+	for _, depl := range exports {
+		targets.Add(depl.File...)
+	}
+	return targets
+}
+
+func diffBundleFileExports(from, to *forklift.FSBundle) (diff FilesDiffDocument, err error) {
+	fromTargets := listBundleFileExports(from.Manifest.Exports)
+	toTargets := listBundleFileExports(to.Manifest.Exports)
+	targets := diffSets(fromTargets, toTargets)
+	diff.Added = targets.Added
+	diff.Removed = targets.Removed
+	for _, target := range slices.Sorted(fromTargets.All()) {
+		if !toTargets.Has(target) {
+			continue
+		}
+		fromContents, err := from.ReadFileExport(target)
+		if err != nil {
+			return FilesDiffDocument{}, errors.Wrapf(
+				err, "couldn't read exported file %s from bundle %s", target, from.FS.Path(),
+			)
+		}
+		toContents, err := to.ReadFileExport(target)
+		if err != nil {
+			return FilesDiffDocument{}, errors.Wrapf(
+				err, "couldn't read exported file %s from bundle %s", target, to.FS.Path(),
+			)
+		}
+		if fileDiff, changed := diffFiles(target, target, fromContents, toContents); changed {
+			diff.Changed = append(diff.Changed, fileDiff)
+		}
+	}
+	return diff, nil
+}
+
+// diffBundleComposeFiles compares the Compose files of the package deployments present in both
+// bundles.
+func diffBundleComposeFiles(from, to *forklift.FSBundle) (diffs []FileDiffDocument, err error) {
+	for _, deplName := range slices.Sorted(maps.Keys(from.Manifest.Deploys)) {
+		if _, ok := to.Manifest.Deploys[deplName]; !ok {
+			continue
+		}
+		fromFiles, err := loadBundleComposeFiles(from, deplName)
+		if err != nil {
+			return nil, err
+		}
+		toFiles, err := loadBundleComposeFiles(to, deplName)
+		if err != nil {
+			return nil, err
+		}
+		files := newStringSet(slices.Collect(maps.Keys(fromFiles))...)
+		files.Add(slices.Collect(maps.Keys(toFiles))...)
+		for _, file := range slices.Sorted(files.All()) {
+			fromContents, inFrom := fromFiles[file]
+			toContents, inTo := toFiles[file]
+			fromPath := fmt.Sprintf("%s: %s", deplName, file)
+			toPath := fromPath
+			if !inFrom {
+				fromPath = "/dev/null"
+			}
+			if !inTo {
+				toPath = "/dev/null"
+			}
+			fileDiff, changed := diffFiles(fromPath, toPath, fromContents, toContents)
+			if !changed {
+				continue
+			}
+			fileDiff.Path = fmt.Sprintf("%s: %s", deplName, file)
+			diffs = append(diffs, fileDiff)
+		}
+	}
+	return diffs, nil
+}
+
+// loadBundleComposeFiles returns the contents of the Compose files of the package deployment in
+// the bundle, keyed by the paths of the files in the deployed package.
+func loadBundleComposeFiles(
+	bundle *forklift.FSBundle, deplName string,
+) (files map[string][]byte, err error) {
+	depl, err := bundle.LoadResolvedDepl(deplName)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err, "couldn't resolve package deployment %s in bundle %s", deplName, bundle.FS.Path(),
+		)
+	}
+	composeFiles, err := depl.GetComposeFilenames()
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't determine Compose files of deployment %s", deplName)
+	}
+	files = make(map[string][]byte)
+	for _, file := range composeFiles {
+		if file == "" {
+			continue
+		}
+		if files[file], err = fs.ReadFile(depl.Pkg.FS, file); err != nil {
+			return nil, errors.Wrapf(
+				err, "couldn't read Compose file %s of package deployment %s in bundle %s",
+				file, deplName, bundle.FS.Path(),
+			)
+		}
+	}
+	return files, nil
+}
+
+// diffFiles compares two versions of a file, returning false if their contents are identical.
+func diffFiles(fromPath, toPath string, from, to []byte) (diff FileDiffDocument, changed bool) {
+	if bytes.Equal(from, to) {
+		return FileDiffDocument{}, false
+	}
+	diff.Path = toPath
+	if !isText(from) || !isText(to) {
+		diff.Binary = true
+		return diff, true
+	}
+	// GetUnifiedDiffString only returns an error if it couldn't write to its internal buffer:
+	diff.Diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitDiffLines(from),
+		B:        splitDiffLines(to),
+		FromFile: fromPath,
+		ToFile:   toPath,
+		Context:  diffContextLines,
+	})
+	return diff, true
+}
+
+// splitDiffLines splits the contents into lines for a unified diff, each ending with a newline.
+func splitDiffLines(contents []byte) []string {
+	if len(contents) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(contents), "\n")
+	if last := len(lines) - 1; lines[last] == "" {
+		lines = lines[:last]
+	} else {
+		lines[last] += "\n"
+	}
+	return lines
+}
+
+// isText returns true if the contents look like text, rather than binary data.
+func isText(contents []byte) bool {
+	return !bytes.Contains(contents, []byte{0}) && utf8.Valid(contents)
+}
+
+// Printing
+
+// FprintBundleDiff prints the differences between two staged pallet bundles.
+func FprintBundleDiff(indent int, out io.Writer, format string, diff BundleDiffDocument) error {
+	if IsStructuredOutput(format) {
+		return FprintDocument(out, format, diff)
+	}
+
+	IndentedFprintf(indent, out, "Changes from staged pallet bundle %d to %d:\n", diff.From, diff.To)
+	indent++
+
+	IndentedFprint(indent, out, "Pallet:")
+	if diff.Pallet == nil {
+		_, _ = fmt.Fprintln(out, " (unchanged)")
+	} else {
+		_, _ = fmt.Fprintln(out)
+		fprintBundlePalletChange(indent+1, out, *diff.Pallet)
+	}
+
+	IndentedFprint(indent, out, "Deploys:")
+	if diff.Deployments.Empty() {
+		_, _ = fmt.Fprintln(out, " (unchanged)")
+	} else {
+		_, _ = fmt.Fprintln(out)
+		fprintDeplsDiff(indent+1, out, diff.Deployments)
+	}
+
+	IndentedFprint(indent, out, "Repos:")
+	if diff.Repos.Empty() {
+		_, _ = fmt.Fprintln(out, " (unchanged)")
+	} else {
+		_, _ = fmt.Fprintln(out)
+		fprintReposDiff(indent+1, out, diff.Repos)
+	}
+
+	fprintSetDiff(indent, out, "Compose App Images", diff.Images)
+	fprintSetDiff(indent, out, "Downloaded HTTP Files", diff.HTTPFiles)
+	fprintSetDiff(indent, out, "Downloaded OCI Images", diff.OCIImages)
+
+	IndentedFprint(indent, out, "Exported Files:")
+	if diff.Exports.Empty() {
+		_, _ = fmt.Fprintln(out, " (unchanged)")
+	} else {
+		_, _ = fmt.Fprintln(out)
+		fprintFilesDiff(indent+1, out, diff.Exports)
+	}
+
+	IndentedFprint(indent, out, "Compose Files:")
+	if len(diff.ComposeFiles) == 0 {
+		_, _ = fmt.Fprintln(out, " (unchanged)")
+	} else {
+		_, _ = fmt.Fprintln(out)
+		for _, fileDiff := range diff.ComposeFiles {
+			fprintFileDiff(indent+1, out, fileDiff)
+		}
+	}
+	return nil
+}
+
+func fprintBundlePalletChange(indent int, out io.Writer, change BundlePalletChange) {
+	if change.From.Path != change.To.Path {
+		IndentedFprintf(indent, out, "Path: %s -> %s\n", change.From.Path, change.To.Path)
+	} else {
+		IndentedFprintf(indent, out, "Path: %s\n", change.To.Path)
+	}
+	IndentedFprintf(
+		indent, out, "Version: %s -> %s\n",
+		describeBundlePalletVersion(change.From), describeBundlePalletVersion(change.To),
+	)
+}
+
+func describeBundlePalletVersion(pallet forklift.BundlePallet) string {
+	if pallet.Clean {
+		return pallet.Version
+	}
+	return pallet.Version + " (includes uncommitted changes)"
+}
+
+func fprintSetDiff(indent int, out io.Writer, name string, diff SetDiffDocument) {
+	IndentedFprintf(indent, out, "%s:", name)
+	if diff.Empty() {
+		_, _ = fmt.Fprintln(out, " (unchanged)")
+		return
+	}
+	_, _ = fmt.Fprintln(out)
+	for _, item := range diff.Added {
+		IndentedFprintf(indent+1, out, "+ %s\n", item)
+	}
+	for _, item := range diff.Removed {
+		IndentedFprintf(indent+1, out, "- %s\n", item)
+	}
+}
+
+func fprintDeplsDiff(indent int, out io.Writer, diff DeplsDiffDocument) {
+	for _, depl := range diff.Added {
+		IndentedFprintf(indent, out, "+ %s: %s\n", depl.Name, depl.Def.Package)
+	}
+	for _, depl := range diff.Removed {
+		IndentedFprintf(indent, out, "- %s: %s\n", depl.Name, depl.Def.Package)
+	}
+	for _, change := range diff.Changed {
+		IndentedFprintf(indent, out, "~ %s:\n", change.Name)
+		if change.Package != nil {
+			IndentedFprintf(
+				indent+1, out, "Package: %s -> %s\n", change.Package.From, change.Package.To,
+			)
+		}
+		if !change.Features.Empty() {
+			fprintSetDiff(indent+1, out, "Features", change.Features)
+		}
+		if change.Disabled != nil {
+			IndentedFprintf(
+				indent+1, out, "Disabled: %t -> %t\n", change.Disabled.From, change.Disabled.To,
+			)
+		}
+	}
+}
+
+func fprintReposDiff(indent int, out io.Writer, diff ReposDiffDocument) {
+	for _, repo := range diff.Added {
+		IndentedFprintf(indent, out, "+ %s@%s\n", repo.Path, repo.Version)
+	}
+	for _, repo := range diff.Removed {
+		IndentedFprintf(indent, out, "- %s@%s\n", repo.Path, repo.Version)
+	}
+	for _, change := range diff.Changed {
+		IndentedFprintf(indent, out, "~ %s: %s -> %s\n", change.Path, change.From, change.To)
+	}
+}
+
+func fprintFilesDiff(indent int, out io.Writer, diff FilesDiffDocument) {
+	for _, file := range diff.Added {
+		IndentedFprintf(indent, out, "+ %s\n", file)
+	}
+	for _, file := range diff.Removed {
+		IndentedFprintf(indent, out, "- %s\n", file)
+	}
+	for _, fileDiff := range diff.Changed {
+		fprintFileDiff(indent, out, fileDiff)
+	}
+}
+
+func fprintFileDiff(indent int, out io.Writer, diff FileDiffDocument) {
+	if diff.Binary {
+		IndentedFprintf(indent, out, "~ %s (binary file differs)\n", diff.Path)
+		return
+	}
+	IndentedFprintf(indent, out, "~ %s:\n", diff.Path)
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(diff.Diff, "\n"), "\n") {
+		IndentedFprint(indent+1, out, line)
+	}
+	_, _ = fmt.Fprintln(out)
+}
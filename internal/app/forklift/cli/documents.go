@@ -0,0 +1,454 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	"github.com/forklift-run/forklift/internal/clients/docker"
+	"github.com/forklift-run/forklift/pkg/core"
+)
+
+// Structured output
+
+const (
+	// OutputText is the output format of human-readable text.
+	OutputText = "text"
+	// OutputJSON is the output format of JSON documents.
+	OutputJSON = "json"
+	// OutputYAML is the output format of YAML documents.
+	OutputYAML = "yaml"
+)
+
+// CheckOutputFormat returns an error if the output format is unknown.
+func CheckOutputFormat(format string) error {
+	switch format {
+	default:
+		return errors.Errorf("unknown output format '%s' (must be text, json, or yaml)", format)
+	case "", OutputText, OutputJSON, OutputYAML:
+		return nil
+	}
+}
+
+// IsStructuredOutput returns true if the output format is a structured (i.e. machine-readable)
+// document format.
+func IsStructuredOutput(format string) bool {
+	return format == OutputJSON || format == OutputYAML
+}
+
+// OutputSchemaVersion is the version of the schemas of all structured output documents, as
+// described in docs/structured-output.md. It must be incremented whenever a field of any document
+// is removed, renamed, or changed in meaning (but not when a field is added).
+const OutputSchemaVersion = 1
+
+// A documentEnvelope wraps every structured output document, so that consumers of the output can
+// check which version of the document schemas it follows.
+type documentEnvelope struct {
+	// SchemaVersion is the version of the document schemas, i.e. OutputSchemaVersion.
+	SchemaVersion int `yaml:"schema-version"`
+	// Document is the document.
+	Document any `yaml:"document"`
+}
+
+// FprintDocument prints the document in the specified structured output format, wrapped in an
+// envelope recording the version of the document schemas. JSON documents use the same field names
+// as YAML documents, which are the field names used in the YAML files defining pallets,
+// repositories, packages, deployments, bundles, and stage stores.
+func FprintDocument(out io.Writer, format string, doc any) error {
+	marshaled, err := yaml.Marshal(documentEnvelope{
+		SchemaVersion: OutputSchemaVersion,
+		Document:      doc,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "couldn't serialize %T as yaml document", doc)
+	}
+	switch format {
+	default:
+		return errors.Errorf("unknown structured output format '%s'", format)
+	case OutputYAML:
+		_, _ = out.Write(marshaled)
+		return nil
+	case OutputJSON:
+	}
+
+	// We convert from YAML so that the JSON document has the same field names as the YAML document
+	var unmarshaled any
+	if err = yaml.Unmarshal(marshaled, &unmarshaled); err != nil {
+		return errors.Wrapf(err, "couldn't deserialize %T from yaml document", doc)
+	}
+	if marshaled, err = json.MarshalIndent(unmarshaled, "", "  "); err != nil {
+		return errors.Wrapf(err, "couldn't serialize %T as json document", doc)
+	}
+	_, _ = fmt.Fprintln(out, string(marshaled))
+	return nil
+}
+
+// Pallets
+
+// A PalletDocument describes a pallet.
+type PalletDocument struct {
+	// Path is the pallet path of the pallet.
+	Path string `yaml:"path"`
+	// Version is the version of the pallet, if it's known.
+	Version string `yaml:"version,omitempty"`
+	// Location is the path of the pallet in the filesystem.
+	Location string `yaml:"location"`
+	// Def is the pallet's definition.
+	Def forklift.PalletDef `yaml:"definition"`
+	// Pkgs is a list of the paths of the packages provided by the pallet.
+	Pkgs []string `yaml:"packages,omitempty"`
+	// Depls is a list of the package deployments declared by the pallet.
+	Depls []DeplSummaryDocument `yaml:"deployments,omitempty"`
+	// Features is a list of the names of the features provided by the pallet.
+	Features []string `yaml:"features,omitempty"`
+}
+
+func newPalletDocument(pallet *forklift.FSPallet, withContents bool) (PalletDocument, error) {
+	doc := PalletDocument{
+		Path:     pallet.Path(),
+		Version:  pallet.Version,
+		Location: pallet.FS.Path(),
+		Def:      pallet.Def,
+	}
+	if !withContents {
+		return doc, nil
+	}
+
+	pkgs, err := pallet.LoadFSPkgs("**")
+	if err != nil {
+		return PalletDocument{}, errors.Wrapf(
+			err, "couldn't load packages provided by pallet %s", pallet.Path(),
+		)
+	}
+	doc.Pkgs = newPkgPaths(pkgs)
+	depls, err := pallet.LoadDepls("**/*")
+	if err != nil {
+		return PalletDocument{}, errors.Wrapf(
+			err, "couldn't load package deployments of pallet %s", pallet.Path(),
+		)
+	}
+	doc.Depls = newDeplSummaryDocuments(depls)
+	features, err := pallet.LoadFeatures("**/*")
+	if err != nil {
+		return PalletDocument{}, errors.Wrapf(
+			err, "couldn't load features provided by pallet %s", pallet.Path(),
+		)
+	}
+	doc.Features = make([]string, 0, len(features))
+	for _, feature := range features {
+		doc.Features = append(doc.Features, feature.Name)
+	}
+	return doc, nil
+}
+
+// A GitRepoReqDocument describes a requirement for a pallet or repo.
+type GitRepoReqDocument struct {
+	// Path is the path of the required pallet or repo.
+	Path string `yaml:"path"`
+	// Version is the required version of the pallet or repo.
+	Version string `yaml:"version"`
+	// VersionLock is the definition of the version lock of the requirement.
+	VersionLock forklift.VersionLockDef `yaml:"version-lock"`
+}
+
+func newGitRepoReqDocument(req forklift.GitRepoReq) GitRepoReqDocument {
+	return GitRepoReqDocument{
+		Path:        req.Path(),
+		Version:     req.VersionLock.Version,
+		VersionLock: req.VersionLock.Def,
+	}
+}
+
+// A RequiredPalletDocument describes a pallet required by another pallet.
+type RequiredPalletDocument struct {
+	// Req is the requirement for the pallet.
+	Req GitRepoReqDocument `yaml:"requirement"`
+	// Pallet describes the required pallet.
+	Pallet PalletDocument `yaml:"pallet"`
+}
+
+// Repositories
+
+// A RepoDocument describes a repo.
+type RepoDocument struct {
+	// Path is the repo path of the repo.
+	Path string `yaml:"path"`
+	// Version is the version of the repo, if it's known.
+	Version string `yaml:"version,omitempty"`
+	// Location is the path of the repo in the filesystem.
+	Location string `yaml:"location"`
+	// Def is the repo's definition.
+	Def core.RepoDef `yaml:"definition"`
+	// Pkgs is a list of the paths of the packages provided by the repo.
+	Pkgs []string `yaml:"packages,omitempty"`
+}
+
+func newRepoDocument(repo *core.FSRepo) (RepoDocument, error) {
+	pkgs, err := repo.LoadFSPkgs("**")
+	if err != nil {
+		return RepoDocument{}, errors.Wrapf(err, "couldn't load packages from repo %s", repo.Path())
+	}
+	return RepoDocument{
+		Path:     repo.Path(),
+		Version:  repo.Version,
+		Location: repo.FS.Path(),
+		Def:      repo.Def,
+		Pkgs:     newPkgPaths(pkgs),
+	}, nil
+}
+
+// A RequiredRepoDocument describes a repo required by a pallet.
+type RequiredRepoDocument struct {
+	// Req is the requirement for the repo.
+	Req GitRepoReqDocument `yaml:"requirement"`
+	// Repo describes the required repo.
+	Repo RepoDocument `yaml:"repository"`
+}
+
+// Packages
+
+// A PkgDocument describes a package.
+type PkgDocument struct {
+	// Path is the package path of the package.
+	Path string `yaml:"path"`
+	// RepoPath is the path of the repo (or the pallet) which provides the package.
+	RepoPath string `yaml:"repository"`
+	// RepoVersion is the version of the repo (or the pallet) which provides the package, if it's
+	// known.
+	RepoVersion string `yaml:"version,omitempty"`
+	// Location is the path of the package in the filesystem.
+	Location string `yaml:"location"`
+	// Def is the package's definition.
+	Def core.PkgDef `yaml:"definition"`
+}
+
+func newPkgDocument(pkg *core.FSPkg) PkgDocument {
+	return PkgDocument{
+		Path:        pkg.Path(),
+		RepoPath:    pkg.Repo.Path(),
+		RepoVersion: pkg.Repo.Version,
+		Location:    pkg.FS.Path(),
+		Def:         pkg.Def,
+	}
+}
+
+func newPkgPaths(pkgs []*core.FSPkg) []string {
+	slices.SortFunc(pkgs, func(a, b *core.FSPkg) int {
+		return core.ComparePkgs(a.Pkg, b.Pkg)
+	})
+	paths := make([]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		paths = append(paths, pkg.Path())
+	}
+	return paths
+}
+
+// Deployments
+
+// A DeplSummaryDocument briefly describes a package deployment.
+type DeplSummaryDocument struct {
+	// Name is the name of the package deployment.
+	Name string `yaml:"name"`
+	// Def is the package deployment's definition.
+	Def forklift.DeplDef `yaml:"definition"`
+}
+
+func newDeplSummaryDocuments(depls []forklift.Depl) []DeplSummaryDocument {
+	docs := make([]DeplSummaryDocument, 0, len(depls))
+	for _, depl := range depls {
+		docs = append(docs, DeplSummaryDocument{Name: depl.Name, Def: depl.Def})
+	}
+	return docs
+}
+
+// A DeplDocument describes a resolved package deployment.
+type DeplDocument struct {
+	// Name is the name of the package deployment.
+	Name string `yaml:"name"`
+	// Def is the package deployment's definition.
+	Def forklift.DeplDef `yaml:"definition"`
+	// Pkg describes the package deployed by the package deployment.
+	Pkg PkgDocument `yaml:"package"`
+	// DisabledFeatures is a list of the names of the package's features which are not enabled.
+	DisabledFeatures []string `yaml:"disabled-features,omitempty"`
+	// FileExportTargets is a list of the paths of the files exported by the package deployment.
+	FileExportTargets []string `yaml:"file-export-targets,omitempty"`
+	// ComposeApp is the name of the Docker Compose app of the package deployment, if it defines one.
+	ComposeApp string `yaml:"compose-app,omitempty"`
+}
+
+func newDeplDocument(depl *forklift.ResolvedDepl) (DeplDocument, error) {
+	doc := DeplDocument{
+		Name:             depl.Name,
+		Def:              depl.Def,
+		Pkg:              newPkgDocument(depl.Pkg),
+		DisabledFeatures: slices.Sorted(maps.Keys(depl.DisabledFeatures())),
+	}
+	targets, err := depl.GetFileExportTargets()
+	if err != nil {
+		return DeplDocument{}, errors.Wrap(err, "couldn't determine export file targets")
+	}
+	doc.FileExportTargets = targets
+	definesApp, err := depl.DefinesComposeApp()
+	if err != nil {
+		return DeplDocument{}, errors.Wrap(
+			err, "couldn't determine whether package deployment defines a Compose app",
+		)
+	}
+	if definesApp {
+		doc.ComposeApp = forklift.GetComposeAppName(depl.Name)
+	}
+	return doc, nil
+}
+
+// Imports & features
+
+// An ImportSummaryDocument briefly describes a file import group or feature.
+type ImportSummaryDocument struct {
+	// Name is the name of the import group or feature.
+	Name string `yaml:"name"`
+	// Def is the import group's definition.
+	Def forklift.ImportDef `yaml:"definition"`
+}
+
+func newImportSummaryDocuments(imps []forklift.Import) []ImportSummaryDocument {
+	docs := make([]ImportSummaryDocument, 0, len(imps))
+	for _, imp := range imps {
+		docs = append(docs, ImportSummaryDocument{Name: imp.Name, Def: imp.Def})
+	}
+	return docs
+}
+
+// An ImportDocument describes a resolved file import group or feature.
+type ImportDocument struct {
+	// Name is the name of the import group or feature.
+	Name string `yaml:"name"`
+	// Def is the import group's definition.
+	Def forklift.ImportDef `yaml:"definition"`
+	// Source is the path of the pallet which files are imported from.
+	Source string `yaml:"source"`
+	// Deprecations is a list of deprecation warnings for the import group.
+	Deprecations []string `yaml:"deprecations,omitempty"`
+	// Files is a map from the target paths of imported files to their source paths.
+	Files map[string]string `yaml:"files"`
+}
+
+func newImportDocument(
+	imp *forklift.ResolvedImport, loader forklift.FSPalletLoader,
+) (ImportDocument, error) {
+	deprecations, err := imp.CheckDeprecations(loader)
+	if err != nil {
+		return ImportDocument{}, errors.Wrapf(
+			err, "couldn't check deprecations for import %s", imp.Name,
+		)
+	}
+	files, err := imp.Evaluate(loader)
+	if err != nil {
+		return ImportDocument{}, errors.Wrapf(err, "couldn't evaluate import group %s", imp.Name)
+	}
+	doc := ImportDocument{
+		Name:         imp.Name,
+		Def:          imp.Def,
+		Source:       imp.Pallet.Path(),
+		Deprecations: make([]string, 0, len(deprecations)),
+		Files:        files,
+	}
+	for _, deprecation := range deprecations {
+		doc.Deprecations = append(doc.Deprecations, deprecation.Error())
+	}
+	return doc, nil
+}
+
+// Staging
+
+// A StagedBundleDocument describes a staged pallet bundle.
+type StagedBundleDocument struct {
+	// Index is the index of the bundle in the stage store.
+	Index int `yaml:"index"`
+	// Names is a list of the names of the bundle in the stage store.
+	Names []string `yaml:"names,omitempty"`
+	// Manifest is the bundle's manifest.
+	Manifest forklift.BundleManifest `yaml:"manifest"`
+}
+
+// NewStagedBundleDocument creates a document describing the staged pallet bundle.
+func NewStagedBundleDocument(
+	bundle *forklift.FSBundle, index int, names []string,
+) StagedBundleDocument {
+	return StagedBundleDocument{
+		Index:    index,
+		Names:    names,
+		Manifest: bundle.Manifest,
+	}
+}
+
+// A StageStoreDocument describes the state of a stage store.
+type StageStoreDocument struct {
+	// Location is the path of the stage store in the filesystem.
+	Location string `yaml:"location"`
+	// Stages is the state of the stage store, as recorded in its manifest.
+	Stages forklift.StagesSpec `yaml:"staged"`
+	// Pending is the index of the next staged pallet bundle to be applied, if it's different from
+	// the last successfully-applied staged pallet bundle.
+	Pending int `yaml:"pending,omitempty"`
+	// Current is the index of the last successfully-applied staged pallet bundle.
+	Current int `yaml:"current,omitempty"`
+	// Rollback is the index of the staged pallet bundle which was successfully applied before the
+	// current one.
+	Rollback int `yaml:"rollback,omitempty"`
+
+	// Retention is the stage store's retention policy, if it has one.
+	Retention *forklift.StageRetentionSpec `yaml:"retention,omitempty"`
+}
+
+// NewStageStoreDocument creates a document describing the state of the stage store.
+func NewStageStoreDocument(store *forklift.FSStageStore) StageStoreDocument {
+	doc := StageStoreDocument{
+		Location:  store.Path(),
+		Stages:    store.Manifest.Stages,
+		Retention: store.Manifest.Retention,
+	}
+	doc.Pending, _ = store.GetPending()
+	doc.Current, _ = store.GetCurrent()
+	doc.Rollback, _ = store.GetRollback()
+	return doc
+}
+
+// Images
+
+// An ImageDocument describes a Docker container image in the local image cache.
+type ImageDocument struct {
+	// ID is the image's ID.
+	ID string `yaml:"id"`
+	// Repository is the container image repository which provides the image, if it's known.
+	Repository string `yaml:"repository,omitempty"`
+	// Tag is the image's tag in its repository, if it has one.
+	Tag string `yaml:"tag,omitempty"`
+	// RepoTags is a list of the image's repository tags.
+	RepoTags []string `yaml:"repo-tags,omitempty"`
+	// RepoDigests is a list of the image's repository digests.
+	RepoDigests []string `yaml:"repo-digests,omitempty"`
+	// Created is when the image was created, in RFC 3339 format.
+	Created string `yaml:"created,omitempty"`
+	// Size is the size of the image in bytes.
+	Size int64 `yaml:"size"`
+}
+
+// NewImageDocument creates a document describing the Docker container image.
+func NewImageDocument(image docker.Image) ImageDocument {
+	return ImageDocument{
+		ID:          image.ID,
+		Repository:  image.Repository,
+		Tag:         image.Tag,
+		RepoTags:    image.Inspect.RepoTags,
+		RepoDigests: image.Inspect.RepoDigests,
+		Created:     image.Inspect.Created,
+		Size:        image.Inspect.Size,
+	}
+}
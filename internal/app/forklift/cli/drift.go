@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/pkg/errors"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	"github.com/forklift-run/forklift/internal/clients/docker"
+)
+
+// CheckDrift compares the Compose apps of the enabled deployments (as loaded by deplsLoader) with
+// the Docker host, and returns the ways in which the Docker host has drifted from those
+// deployments, sorted by app name. unownedApps is the policy for handling Compose apps not created
+// by Forklift, as in [PlanOptions].
+func CheckDrift(
+	indent int, deplsLoader ResolvedDeplsLoader, pkgLoader forklift.FSPkgLoader, unownedApps string,
+) ([]docker.Drift, error) {
+	if err := CheckUnownedAppsPolicy(unownedApps); err != nil {
+		return nil, err
+	}
+	depls, err := deplsLoader.LoadDepls("**/*")
+	if err != nil {
+		return nil, err
+	}
+	depls = forklift.FilterDeplsForEnabled(depls)
+	resolved, err := forklift.ResolveDepls(deplsLoader, pkgLoader, depls)
+	if err != nil {
+		return nil, err
+	}
+	dc, err := docker.NewClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't make Docker API client")
+	}
+
+	apps, err := dc.ListApps(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list active Docker Compose apps")
+	}
+	if apps, err = filterUnownedApps(indent, dc, resolved, apps, unownedApps); err != nil {
+		return nil, err
+	}
+	appsByName := make(map[string]api.Stack)
+	for _, app := range apps {
+		appsByName[app.Name] = app
+	}
+	deplsByName := make(map[string]*forklift.ResolvedDepl)
+	for _, depl := range resolved {
+		deplsByName[depl.Name] = depl
+	}
+	composeAppDefinerSet, err := identifyComposeAppDefiners(deplsByName)
+	if err != nil {
+		return nil, err
+	}
+
+	drifts := make([]docker.Drift, 0)
+	desiredApps := make(map[string]struct{})
+	for _, deplName := range slices.Sorted(composeAppDefinerSet.All()) {
+		depl := deplsByName[deplName]
+		appDef, err := depl.LoadComposeAppDefinition(true)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err, "couldn't load Compose app definition of deployment %s", depl.Name,
+			)
+		}
+		desiredApps[appDef.Name] = struct{}{}
+		if _, ok := appsByName[appDef.Name]; !ok {
+			drifts = append(drifts, docker.Drift{
+				App: appDef.Name, Kind: docker.DriftMissingApp,
+				Detail: fmt.Sprintf("app of deployment %s doesn't exist", depl.Name),
+			})
+			continue
+		}
+		appDrifts, err := dc.CheckAppDrift(context.Background(), appDef)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't check drift of deployment %s", depl.Name)
+		}
+		drifts = append(drifts, appDrifts...)
+	}
+	for _, app := range apps {
+		if _, ok := desiredApps[app.Name]; ok {
+			continue
+		}
+		drifts = append(drifts, docker.Drift{
+			App: app.Name, Kind: docker.DriftExtraApp, Detail: "app doesn't belong to any deployment",
+		})
+	}
+	slices.SortStableFunc(drifts, func(i, j docker.Drift) int {
+		return strings.Compare(i.App, j.App)
+	})
+	return drifts, nil
+}
+
+// PrintDrifts prints the drifts in the specified output format.
+func PrintDrifts(out io.Writer, drifts []docker.Drift, format string) error {
+	switch format {
+	default:
+		return errors.Errorf("unknown output format '%s'", format)
+	case OutputJSON, OutputYAML:
+		return FprintDocument(out, format, drifts)
+	case "", OutputText:
+		if len(drifts) == 0 {
+			fmt.Fprintln(out, "No drift detected")
+			return nil
+		}
+		fmt.Fprintln(out, "Detected drift:")
+		for _, drift := range drifts {
+			BulletedFprintln(1, out, drift)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,429 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	"github.com/forklift-run/forklift/internal/clients/docker"
+)
+
+// EventsJSON is the events format which writes each event as a JSON object on its own line.
+const EventsJSON = "json"
+
+const (
+	// Events about reconciliation changes
+
+	eventChangeStarted  = "change-started"
+	eventChangeFailed   = "change-failed"
+	eventChangeFinished = "change-finished"
+	eventDockerOutput   = "docker-output"
+
+	// Events about image pulls and downloads
+
+	eventPullStarted      = "pull-started"
+	eventPullProgress     = "pull-progress"
+	eventPullFinished     = "pull-finished"
+	eventDownloadStarted  = "download-started"
+	eventDownloadProgress = "download-progress"
+	eventDownloadFinished = "download-finished"
+
+	// Events about the stage store
+
+	eventStageStoreUpdated = "stage-store-updated"
+)
+
+// Types of downloaded resources
+
+const (
+	httpFileResource    = "http-file"
+	ociImageResource    = "oci-image"
+	dockerImageResource = "docker-image"
+	gitRepoResource     = "git-repo"
+)
+
+// Outcomes of downloads
+
+const (
+	downloadSucceeded = "succeeded"
+	downloadCached    = "cached"
+	downloadFailed    = "failed"
+)
+
+// An Event describes a step of a long-running operation, so that other programs can follow the
+// operation without parsing Forklift's human-readable output.
+type Event struct {
+	// Time is when the event happened.
+	Time time.Time `json:"time"`
+	// Type is the type of the event (e.g. change-started or pull-progress).
+	Type string `json:"type"`
+	// Change identifies the reconciliation change which the event is about, if any.
+	Change string `json:"change,omitempty"`
+	// Deployment is the name of the package deployment which the event is about, if any.
+	Deployment string `json:"deployment,omitempty"`
+	// Attempt is the number of the attempt at a change which the event is about, if any.
+	Attempt int `json:"attempt,omitempty"`
+	// Resource is the type of resource being downloaded (e.g. http-file or oci-image), if any.
+	Resource string `json:"resource,omitempty"`
+	// Source is the URL, image name, or Git repo path & version being downloaded, if any.
+	Source string `json:"source,omitempty"`
+	// Layer is the ID of the image layer which the event is about, if any.
+	Layer string `json:"layer,omitempty"`
+	// Status is a description of the progress of the operation, if any.
+	Status string `json:"status,omitempty"`
+	// Progress describes the amount of data which has been processed, if known.
+	Progress *EventProgress `json:"progress,omitempty"`
+	// Outcome is the outcome of a finished operation (e.g. succeeded, failed, or skipped).
+	Outcome string `json:"outcome,omitempty"`
+	// Attempts is the number of attempts made for a finished change.
+	Attempts int `json:"attempts,omitempty"`
+	// Duration is how many seconds a finished operation took.
+	Duration float64 `json:"duration,omitempty"`
+	// Error is the error message from a failed operation, if any.
+	Error string `json:"error,omitempty"`
+	// Message is a line of output from Docker, if the event is about such output.
+	Message string `json:"message,omitempty"`
+	// Stages describes the state of the stage store, if the event is about the stage store.
+	Stages *EventStages `json:"stages,omitempty"`
+}
+
+// EventProgress describes the amount of data processed by an operation.
+type EventProgress struct {
+	// Current is the number of bytes which have been processed.
+	Current int64 `json:"current"`
+	// Total is the total number of bytes to be processed, if known.
+	Total int64 `json:"total,omitempty"`
+}
+
+// EventStages describes the state of the stage store.
+type EventStages struct {
+	// Next is the index of the next staged pallet bundle to be applied, if any.
+	Next int `json:"next,omitempty"`
+	// NextFailed is whether the last attempt to apply the next staged pallet bundle failed.
+	NextFailed bool `json:"next-failed,omitempty"`
+	// Current is the index of the last successfully-applied staged pallet bundle, if any.
+	Current int `json:"current,omitempty"`
+	// Pending is the index of the next staged pallet bundle, if it's different from the current one.
+	Pending int `json:"pending,omitempty"`
+	// Rollback is the index of the staged pallet bundle which was successfully applied before the
+	// current one, if any.
+	Rollback int `json:"rollback,omitempty"`
+}
+
+// eventStream is where events are written, if events were enabled with [OpenEventStream].
+var eventStream struct {
+	sync.Mutex
+	out     io.Writer
+	closer  io.Closer
+	encoder *json.Encoder
+}
+
+// inheritedEventFiles are the already-open files which events were written to. They must not be
+// garbage-collected, because that would close their file descriptors.
+var inheritedEventFiles []*os.File
+
+// OpenEventStream starts writing events in the specified format (which may be empty, to disable
+// events) to the specified target, which is either "-" for stdout, "fd:N" for an already-open file
+// descriptor N, or the path of a file to append events to. [CloseEventStream] should be called
+// once no more events will be written.
+func OpenEventStream(format, target string) error {
+	switch format {
+	default:
+		return errors.Errorf("unknown events format '%s' (must be json or empty)", format)
+	case "":
+		return nil
+	case EventsJSON:
+	}
+
+	var out io.Writer
+	var closer io.Closer
+	switch {
+	case target == "" || target == "-":
+		out = os.Stdout
+	case strings.HasPrefix(target, "fd:"):
+		fd, err := strconv.ParseUint(strings.TrimPrefix(target, "fd:"), 10, 0)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't parse file descriptor number from %s", target)
+		}
+		file := os.NewFile(uintptr(fd), target)
+		if file == nil {
+			return errors.Errorf("invalid file descriptor %s", target)
+		}
+		// We didn't open the file descriptor, so we shouldn't close it; and we keep a reference to
+		// the file so that it won't be closed by the garbage collector:
+		inheritedEventFiles = append(inheritedEventFiles, file)
+		out = file
+	default:
+		const perm = 0o644 // owner rw, group r, public r
+		file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, perm)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't open %s to write events", target)
+		}
+		out, closer = file, file
+	}
+
+	eventStream.Lock()
+	defer eventStream.Unlock()
+	eventStream.out = out
+	eventStream.closer = closer
+	eventStream.encoder = json.NewEncoder(out)
+	return nil
+}
+
+// CloseEventStream stops writing events, closing the target opened by [OpenEventStream] (unless
+// it was stdout or an already-open file descriptor).
+func CloseEventStream() error {
+	eventStream.Lock()
+	defer eventStream.Unlock()
+	closer := eventStream.closer
+	eventStream.out = nil
+	eventStream.closer = nil
+	eventStream.encoder = nil
+	if closer == nil {
+		return nil
+	}
+	return errors.Wrap(closer.Close(), "couldn't close event stream")
+}
+
+func eventsEnabled() bool {
+	eventStream.Lock()
+	defer eventStream.Unlock()
+	return eventStream.encoder != nil
+}
+
+// eventsOnStdout returns whether events are enabled and are being written to stdout.
+func eventsOnStdout() bool {
+	eventStream.Lock()
+	defer eventStream.Unlock()
+	return eventStream.encoder != nil && eventStream.out == os.Stdout
+}
+
+// emitEvent writes the event to the event stream, if events are enabled.
+func emitEvent(event Event) {
+	eventStream.Lock()
+	defer eventStream.Unlock()
+	if eventStream.encoder == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if err := eventStream.encoder.Encode(event); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: couldn't write %s event: %s\n", event.Type, err.Error())
+	}
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// Changes
+
+func newChangeEvent(eventType string, change *ReconciliationChange) Event {
+	event := Event{
+		Type:   eventType,
+		Change: change.String(),
+	}
+	if change.Depl != nil {
+		event.Deployment = change.Depl.Name
+	}
+	return event
+}
+
+func emitChangeStarted(change *ReconciliationChange, attempt int) {
+	event := newChangeEvent(eventChangeStarted, change)
+	event.Attempt = attempt
+	emitEvent(event)
+}
+
+func emitChangeFailed(
+	change *ReconciliationChange, attempt int, duration time.Duration, err error,
+) {
+	event := newChangeEvent(eventChangeFailed, change)
+	event.Attempt = attempt
+	event.Duration = duration.Seconds()
+	event.Error = errorMessage(err)
+	emitEvent(event)
+}
+
+func emitChangeFinished(change *ReconciliationChange, result *changeResult) {
+	event := newChangeEvent(eventChangeFinished, change)
+	event.Outcome = result.Outcome
+	event.Attempts = result.Attempts
+	event.Duration = result.Duration.Seconds()
+	event.Error = errorMessage(result.Err)
+	emitEvent(event)
+}
+
+// newDockerOutputEventWriter returns a writer which emits each line written to it as an event, or
+// which discards everything written to it if events aren't enabled.
+func newDockerOutputEventWriter() io.Writer {
+	if !eventsEnabled() {
+		return io.Discard
+	}
+	return &lineEventWriter{eventType: eventDockerOutput}
+}
+
+// lineEventWriter emits each complete line written to it as an event.
+type lineEventWriter struct {
+	mu        sync.Mutex
+	eventType string
+	buffer    []byte
+}
+
+func (w *lineEventWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buffer = append(w.buffer, p...)
+	for {
+		line, rest, ok := bytes.Cut(w.buffer, []byte("\n"))
+		if !ok {
+			return len(p), nil
+		}
+		w.buffer = rest
+		if message := strings.TrimSpace(string(line)); message != "" {
+			emitEvent(Event{Type: w.eventType, Message: message})
+		}
+	}
+}
+
+// Downloads
+
+// pullProgress records when the last progress event was emitted for each layer of each image pull,
+// for throttling image pull progress events.
+var pullProgress struct {
+	sync.Mutex
+	lastEvents map[[2]string]pullProgressEvent
+}
+
+// pullProgressEvent describes the last progress event emitted for a layer of an image pull.
+type pullProgressEvent struct {
+	status string
+	time   time.Time
+}
+
+// emitPullProgress emits an event about the progress of an image pull. Events which only report
+// progress (rather than a change of status) are emitted at most once per
+// [downloadProgressInterval] for each layer of each image.
+func emitPullProgress(progress docker.PullProgress) {
+	if !shouldEmitPullProgress(progress) {
+		return
+	}
+	event := Event{
+		Type:   eventPullProgress,
+		Source: progress.Image,
+		Layer:  progress.Layer,
+		Status: progress.Status,
+	}
+	if progress.Current > 0 || progress.Total > 0 {
+		event.Progress = &EventProgress{Current: progress.Current, Total: progress.Total}
+	}
+	emitEvent(event)
+}
+
+// shouldEmitPullProgress returns whether an event should be emitted for the image pull progress.
+func shouldEmitPullProgress(progress docker.PullProgress) bool {
+	pullProgress.Lock()
+	defer pullProgress.Unlock()
+	if pullProgress.lastEvents == nil {
+		pullProgress.lastEvents = make(map[[2]string]pullProgressEvent)
+	}
+	key := [2]string{progress.Image, progress.Layer}
+	last, ok := pullProgress.lastEvents[key]
+	now := time.Now()
+	if ok && last.status == progress.Status && now.Sub(last.time) < downloadProgressInterval {
+		return false
+	}
+	pullProgress.lastEvents[key] = pullProgressEvent{status: progress.Status, time: now}
+	return true
+}
+
+// startDownloadEvents emits an event for the start of a download (of the specified resource type
+// from the specified source) or image pull, and it returns a function which should be called with
+// the outcome of the download to emit an event for the end of the download.
+func startDownloadEvents(
+	startType, finishType, resource, source string,
+) (finish func(outcome string, err error)) {
+	emitEvent(Event{Type: startType, Resource: resource, Source: source})
+	start := time.Now()
+	return func(outcome string, err error) {
+		if err != nil {
+			outcome = downloadFailed
+		}
+		emitEvent(Event{
+			Type:     finishType,
+			Resource: resource,
+			Source:   source,
+			Outcome:  outcome,
+			Duration: time.Since(start).Seconds(),
+			Error:    errorMessage(err),
+		})
+	}
+}
+
+// emitDownloadCached emits an event for a download which was skipped because the resource (of the
+// specified type) had already been downloaded from the specified source.
+func emitDownloadCached(resource, source string) {
+	emitEvent(Event{
+		Type:     eventDownloadFinished,
+		Resource: resource,
+		Source:   source,
+		Outcome:  downloadCached,
+	})
+}
+
+// downloadProgressInterval is the minimum amount of time between download progress events for
+// each download.
+const downloadProgressInterval = 500 * time.Millisecond
+
+// progressReader emits download progress events as data is read from it.
+type progressReader struct {
+	r         io.Reader
+	source    string
+	current   int64
+	total     int64
+	lastEvent time.Time
+}
+
+func (r *progressReader) Read(p []byte) (n int, err error) {
+	n, err = r.r.Read(p)
+	r.current += int64(n)
+	if time.Since(r.lastEvent) >= downloadProgressInterval || (err == io.EOF && n > 0) {
+		r.lastEvent = time.Now()
+		progress := &EventProgress{Current: r.current}
+		if r.total > 0 {
+			progress.Total = r.total
+		}
+		emitEvent(Event{Type: eventDownloadProgress, Source: r.source, Progress: progress})
+	}
+	return n, err
+}
+
+// Stage store
+
+// CommitStageStore commits the updated state of the stage store, emitting an event which describes
+// the new state.
+func CommitStageStore(store *forklift.FSStageStore) error {
+	if err := store.CommitState(); err != nil {
+		return err
+	}
+	stages := &EventStages{NextFailed: store.NextFailed()}
+	stages.Next, _ = store.GetNext()
+	stages.Current, _ = store.GetCurrent()
+	stages.Pending, _ = store.GetPending()
+	stages.Rollback, _ = store.GetRollback()
+	emitEvent(Event{Type: eventStageStoreUpdated, Stages: stages})
+	return nil
+}
@@ -10,11 +10,16 @@ import (
 	"github.com/forklift-run/forklift/internal/app/forklift"
 )
 
-func FprintPalletFeatures(indent int, out io.Writer, pallet *forklift.FSPallet) error {
+func FprintPalletFeatures(
+	indent int, out io.Writer, format string, pallet *forklift.FSPallet,
+) error {
 	imps, err := pallet.LoadFeatures("**/*")
 	if err != nil {
 		return err
 	}
+	if IsStructuredOutput(format) {
+		return FprintDocument(out, format, newImportSummaryDocuments(imps))
+	}
 	for _, imp := range imps {
 		IndentedFprintf(indent, out, "%s\n", imp.Name)
 	}
@@ -22,7 +27,7 @@ func FprintPalletFeatures(indent int, out io.Writer, pallet *forklift.FSPallet)
 }
 
 func FprintFeatureInfo(
-	indent int, out io.Writer,
+	indent int, out io.Writer, format string,
 	pallet *forklift.FSPallet, cache forklift.PathedPalletCache, featureName string,
 ) error {
 	imp, err := pallet.LoadFeature(featureName, cache)
@@ -42,6 +47,13 @@ func FprintFeatureInfo(
 			featureName, imp.Name,
 		)
 	}
+	if IsStructuredOutput(format) {
+		doc, err := newImportDocument(resolved, cache)
+		if err != nil {
+			return err
+		}
+		return FprintDocument(out, format, doc)
+	}
 	if err = FprintFeature(indent, out, resolved, cache); err != nil {
 		return errors.Wrapf(
 			err, "couldn't print feature %s resolved as import group %s", featureName, imp.Name,
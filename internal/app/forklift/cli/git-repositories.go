@@ -312,6 +312,17 @@ func validateGitRepoQueries(queries []string) error {
 func DownloadLockedGitRepoUsingLocalMirror(
 	indent int, mirrorsPath, cachePath, gitRepoPath string, lock forklift.VersionLock,
 ) (downloaded bool, err error) {
+	finish := startDownloadEvents(
+		eventDownloadStarted, eventDownloadFinished, gitRepoResource, gitRepoPath+"@"+lock.Version,
+	)
+	defer func() {
+		outcome := downloadSucceeded
+		if !downloaded {
+			outcome = downloadCached
+		}
+		finish(outcome, err)
+	}()
+
 	if err := forklift.EnsureExists(mirrorsPath); err != nil {
 		return false, errors.Wrap(err, "couldn't ensure existence of mirrors cache")
 	}
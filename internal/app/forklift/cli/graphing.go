@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	"github.com/forklift-run/forklift/pkg/core"
+	"github.com/forklift-run/forklift/pkg/structures"
+)
+
+const (
+	// GraphFormatDOT is the graph format of Graphviz DOT files.
+	GraphFormatDOT = "dot"
+	// GraphFormatMermaid is the graph format of Mermaid flowcharts.
+	GraphFormatMermaid = "mermaid"
+)
+
+// A depGraph is a directed graph of dependency relationships to be rendered, where each edge points
+// from a dependent node to the node it depends on.
+type depGraph struct {
+	name  string
+	nodes []string
+	edges []depGraphEdge
+	// cycleNodes is the set of nodes which are part of a dependency cycle.
+	cycleNodes structures.Set[string]
+}
+
+type depGraphEdge struct {
+	from, to string
+	// labels describes the resources which create the dependency relationship.
+	labels []string
+	// nonblocking is true if the dependent node doesn't need to wait for the node it depends on.
+	nonblocking bool
+	// inCycle is true if the edge is part of a dependency cycle.
+	inCycle bool
+}
+
+// FprintDeplGraph prints the graph of dependency relationships among the enabled package
+// deployments (as loaded by deplsLoader) in the specified graph format. Each edge is labeled with
+// the resources which create the dependency relationship; nonblocking dependency relationships and
+// dependency cycles are styled differently from other dependency relationships.
+func FprintDeplGraph(
+	indent int, out io.Writer, format string,
+	deplsLoader ResolvedDeplsLoader, pkgLoader forklift.FSPkgLoader,
+) error {
+	depls, satisfiedDeps, err := Check(indent, deplsLoader, pkgLoader)
+	if err != nil {
+		return errors.Wrap(err, "couldn't ensure validity")
+	}
+	return fprintDepGraph(out, format, newDeplGraph(depls, satisfiedDeps))
+}
+
+// FprintChangeGraph prints the graph of ordering relationships among the changes which would be
+// made to the Docker host (as planned by [Plan]) in the specified graph format.
+func FprintChangeGraph(
+	indent int, out io.Writer, format string,
+	deplsLoader ResolvedDeplsLoader, pkgLoader forklift.FSPkgLoader, opts PlanOptions,
+) error {
+	if err := checkPlanOptions(opts); err != nil {
+		return err
+	}
+	depls, satisfiedDeps, err := Check(indent, deplsLoader, pkgLoader)
+	if err != nil {
+		return errors.Wrap(err, "couldn't ensure validity")
+	}
+	// A serial plan is computed so that dependency cycles can be rendered rather than rejected:
+	opts.Parallel = false
+	changeDeps, _, err := planDepls(indent, depls, satisfiedDeps, opts)
+	if err != nil {
+		return err
+	}
+	return fprintDepGraph(out, format, newChangeGraph(changeDeps, newDeplGraph(depls, satisfiedDeps)))
+}
+
+func newDeplGraph(
+	depls []*forklift.ResolvedDepl, satisfiedDeps []forklift.SatisfiedDeplDeps,
+) depGraph {
+	type edgeKey struct{ from, to string }
+	edges := make(map[edgeKey]*depGraphEdge)
+	addEdge := func(from string, source []string, label string, nonblocking bool) {
+		to := strings.TrimPrefix(source[0], "deployment ")
+		if to == from { // i.e. the deployment requires a resource it provides
+			return
+		}
+		key := edgeKey{from: from, to: to}
+		edge, ok := edges[key]
+		if !ok {
+			edge = &depGraphEdge{from: from, to: to, nonblocking: true}
+			edges[key] = edge
+		}
+		edge.labels = append(edge.labels, label)
+		edge.nonblocking = edge.nonblocking && nonblocking
+	}
+	for _, satisfied := range satisfiedDeps {
+		from := satisfied.Depl.Name
+		for _, network := range satisfied.Networks {
+			addEdge(from, network.Provided.Source, "network "+network.Required.Res.Name, false)
+		}
+		for _, service := range satisfied.Services {
+			addEdge(
+				from, service.Provided.Source, describeServiceRes(service.Required.Res),
+				service.Required.Res.Nonblocking,
+			)
+		}
+		for _, fileset := range satisfied.Filesets {
+			addEdge(
+				from, fileset.Provided.Source, "fileset "+strings.Join(fileset.Required.Res.Paths, ", "),
+				fileset.Required.Res.Nonblocking,
+			)
+		}
+	}
+
+	graph := depGraph{name: "deployments"}
+	for _, depl := range depls {
+		graph.nodes = append(graph.nodes, depl.Name)
+	}
+	closure := forklift.ResolveDeps(satisfiedDeps, true).ComputeTransitiveClosure()
+	graph.cycleNodes = identifyCycleNodes(closure)
+	for _, edge := range edges {
+		slices.Sort(edge.labels)
+		edge.labels = slices.Compact(edge.labels)
+		edge.inCycle = !edge.nonblocking && closure.HasEdge(edge.to, edge.from)
+		graph.edges = append(graph.edges, *edge)
+	}
+	return graph.sorted()
+}
+
+func describeServiceRes(res core.ServiceRes) string {
+	description := "service"
+	if res.Protocol != "" {
+		description += " " + res.Protocol
+	}
+	if res.Port != 0 {
+		description += fmt.Sprintf(" port %d", res.Port)
+	}
+	if len(res.Paths) > 0 {
+		description += " " + strings.Join(res.Paths, ", ")
+	}
+	return description
+}
+
+// newChangeGraph builds a graph of ordering relationships among changes, where each edge between
+// changes for two deployments is labeled by the corresponding edge of the deployment graph.
+func newChangeGraph(
+	changeDeps structures.Digraph[*ReconciliationChange], deplGraph depGraph,
+) depGraph {
+	deplEdgeLabels := make(map[[2]string][]string)
+	for _, edge := range deplGraph.edges {
+		deplEdgeLabels[[2]string{edge.from, edge.to}] = edge.labels
+	}
+
+	graph := depGraph{name: "changes"}
+	closure := changeDeps.ComputeTransitiveClosure()
+	graph.cycleNodes = make(structures.Set[string])
+	for change, deps := range changeDeps {
+		graph.nodes = append(graph.nodes, change.String())
+		if closure.HasEdge(change, change) {
+			graph.cycleNodes.Add(change.String())
+		}
+		for dep := range deps {
+			edge := depGraphEdge{
+				from:    change.String(),
+				to:      dep.String(),
+				inCycle: closure.HasEdge(dep, change),
+			}
+			switch {
+			case dep.Type == createNetworkReconciliationChange:
+				edge.labels = []string{"network " + dep.Name}
+			case change.Depl != nil && dep.Depl != nil:
+				edge.labels = deplEdgeLabels[[2]string{change.Depl.Name, dep.Depl.Name}]
+			}
+			graph.edges = append(graph.edges, edge)
+		}
+	}
+	return graph.sorted()
+}
+
+func identifyCycleNodes(closure structures.TransitiveClosure[string]) structures.Set[string] {
+	cycleNodes := make(structures.Set[string])
+	for _, cycle := range closure.IdentifyCycles() {
+		cycleNodes.Add(cycle...)
+	}
+	return cycleNodes
+}
+
+func (g depGraph) sorted() depGraph {
+	slices.Sort(g.nodes)
+	slices.SortFunc(g.edges, func(a, b depGraphEdge) int {
+		return cmp.Or(cmp.Compare(a.from, b.from), cmp.Compare(a.to, b.to))
+	})
+	return g
+}
+
+// Rendering
+
+func fprintDepGraph(out io.Writer, format string, graph depGraph) error {
+	switch format {
+	default:
+		return errors.Errorf("unknown graph format '%s' (must be dot or mermaid)", format)
+	case GraphFormatDOT:
+		fprintDOTGraph(out, graph)
+	case GraphFormatMermaid:
+		fprintMermaidGraph(out, graph)
+	}
+	return nil
+}
+
+func fprintDOTGraph(out io.Writer, graph depGraph) {
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+	}
+	_, _ = fmt.Fprintf(out, "digraph %s {\n", quote(graph.name))
+	IndentedFprintln(1, out, "rankdir=LR;")
+	IndentedFprintln(1, out, "node [shape=box];")
+	for _, node := range graph.nodes {
+		IndentedFprint(1, out, quote(node))
+		if graph.cycleNodes.Has(node) {
+			_, _ = fmt.Fprint(out, " [color=red, fontcolor=red]")
+		}
+		_, _ = fmt.Fprintln(out, ";")
+	}
+	for _, edge := range graph.edges {
+		attrs := make([]string, 0)
+		if len(edge.labels) > 0 {
+			attrs = append(attrs, "label="+quote(strings.Join(edge.labels, "\n")))
+		}
+		if edge.nonblocking {
+			attrs = append(attrs, "style=dashed")
+		}
+		if edge.inCycle {
+			attrs = append(attrs, "color=red", "fontcolor=red")
+		}
+		IndentedFprintf(1, out, "%s -> %s", quote(edge.from), quote(edge.to))
+		if len(attrs) > 0 {
+			_, _ = fmt.Fprintf(out, " [%s]", strings.Join(attrs, ", "))
+		}
+		_, _ = fmt.Fprintln(out, ";")
+	}
+	_, _ = fmt.Fprintln(out, "}")
+}
+
+func fprintMermaidGraph(out io.Writer, graph depGraph) {
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+	}
+	ids := make(map[string]string)
+	_, _ = fmt.Fprintln(out, "flowchart LR")
+	for i, node := range graph.nodes {
+		ids[node] = fmt.Sprintf("n%d", i)
+		IndentedFprintf(1, out, "%s[%s]\n", ids[node], quote(node))
+	}
+	cycleEdges := make([]string, 0)
+	for i, edge := range graph.edges {
+		arrow := "-->"
+		if edge.nonblocking {
+			arrow = "-.->"
+		}
+		IndentedFprintf(1, out, "%s %s", ids[edge.from], arrow)
+		if len(edge.labels) > 0 {
+			_, _ = fmt.Fprintf(out, "|%s|", quote(strings.Join(edge.labels, "<br>")))
+		}
+		_, _ = fmt.Fprintf(out, " %s\n", ids[edge.to])
+		if edge.inCycle {
+			cycleEdges = append(cycleEdges, fmt.Sprint(i))
+		}
+	}
+	if len(graph.cycleNodes) == 0 {
+		return
+	}
+	IndentedFprintln(1, out, "classDef cycle stroke:#d00,color:#d00,stroke-width:2px")
+	cycleNodeIDs := make([]string, 0, len(graph.cycleNodes))
+	for _, node := range slices.Sorted(graph.cycleNodes.All()) {
+		cycleNodeIDs = append(cycleNodeIDs, ids[node])
+	}
+	IndentedFprintf(1, out, "class %s cycle\n", strings.Join(cycleNodeIDs, ","))
+	if len(cycleEdges) > 0 {
+		IndentedFprintf(1, out, "linkStyle %s stroke:#d00,color:#d00\n", strings.Join(cycleEdges, ","))
+	}
+}
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	"github.com/forklift-run/forklift/pkg/core"
+	"github.com/forklift-run/forklift/pkg/structures"
+)
+
+func TestNewDeplGraph(t *testing.T) {
+	proxy := &forklift.ResolvedDepl{Depl: forklift.Depl{Name: "proxy"}}
+	web := &forklift.ResolvedDepl{Depl: forklift.Depl{Name: "web"}}
+	docs := &forklift.ResolvedDepl{Depl: forklift.Depl{Name: "docs"}}
+	satisfiedDeps := []forklift.SatisfiedDeplDeps{
+		{
+			Depl: web,
+			Networks: []core.SatisfiedResDep[core.NetworkRes]{{
+				Required: core.AttachedRes[core.NetworkRes]{Res: core.NetworkRes{Name: "proxy-net"}},
+				Provided: core.AttachedRes[core.NetworkRes]{Source: []string{"deployment proxy"}},
+			}},
+		},
+		{
+			Depl: proxy,
+			Services: []core.SatisfiedResDep[core.ServiceRes]{{
+				Required: core.AttachedRes[core.ServiceRes]{Res: core.ServiceRes{
+					Port: 8080, Protocol: "http", Paths: []string{"/web"},
+				}},
+				Provided: core.AttachedRes[core.ServiceRes]{Source: []string{"deployment web"}},
+			}},
+		},
+		{
+			Depl: docs,
+			Services: []core.SatisfiedResDep[core.ServiceRes]{{
+				Required: core.AttachedRes[core.ServiceRes]{Res: core.ServiceRes{
+					Protocol: "http", Nonblocking: true,
+				}},
+				Provided: core.AttachedRes[core.ServiceRes]{Source: []string{"deployment web"}},
+			}},
+		},
+	}
+
+	graph := newDeplGraph([]*forklift.ResolvedDepl{web, proxy, docs}, satisfiedDeps)
+	if expected := []string{"docs", "proxy", "web"}; !slices.Equal(graph.nodes, expected) {
+		t.Errorf("expected nodes %v, got %v", expected, graph.nodes)
+	}
+	if expected := []string{"proxy", "web"}; !slices.Equal(
+		slices.Sorted(graph.cycleNodes.All()), expected,
+	) {
+		t.Errorf("expected cycle nodes %v, got %v", expected, slices.Sorted(graph.cycleNodes.All()))
+	}
+	expected := []depGraphEdge{
+		{from: "docs", to: "web", labels: []string{"service http"}, nonblocking: true},
+		{from: "proxy", to: "web", labels: []string{"service http port 8080 /web"}, inCycle: true},
+		{from: "web", to: "proxy", labels: []string{"network proxy-net"}, inCycle: true},
+	}
+	if !slices.EqualFunc(graph.edges, expected, func(a, b depGraphEdge) bool {
+		return a.from == b.from && a.to == b.to && slices.Equal(a.labels, b.labels) &&
+			a.nonblocking == b.nonblocking && a.inCycle == b.inCycle
+	}) {
+		t.Errorf("expected edges %+v, got %+v", expected, graph.edges)
+	}
+}
+
+func newTestDepGraph() depGraph {
+	cycleNodes := make(structures.Set[string])
+	cycleNodes.Add("proxy", "web")
+	return depGraph{
+		name:  "deployments",
+		nodes: []string{"docs", "proxy", "web"},
+		edges: []depGraphEdge{
+			{from: "docs", to: "web", labels: []string{"service http"}, nonblocking: true},
+			{
+				from: "proxy", to: "web", labels: []string{`service "web"`, "service http"},
+				inCycle: true,
+			},
+			{from: "web", to: "proxy", inCycle: true},
+		},
+		cycleNodes: cycleNodes,
+	}
+}
+
+func TestFprintDepGraph(t *testing.T) {
+	for _, test := range []struct {
+		format   string
+		expected []string
+	}{
+		{
+			format: GraphFormatDOT,
+			expected: []string{
+				`digraph "deployments" {`,
+				`  rankdir=LR;`,
+				`  node [shape=box];`,
+				`  "docs";`,
+				`  "proxy" [color=red, fontcolor=red];`,
+				`  "web" [color=red, fontcolor=red];`,
+				`  "docs" -> "web" [label="service http", style=dashed];`,
+				`  "proxy" -> "web" [label="service \"web\"\nservice http", color=red, fontcolor=red];`,
+				`  "web" -> "proxy" [color=red, fontcolor=red];`,
+				`}`,
+			},
+		},
+		{
+			format: GraphFormatMermaid,
+			expected: []string{
+				`flowchart LR`,
+				`  n0["docs"]`,
+				`  n1["proxy"]`,
+				`  n2["web"]`,
+				`  n0 -.->|"service http"| n2`,
+				`  n1 -->|"service #quot;web#quot;<br>service http"| n2`,
+				`  n2 --> n1`,
+				`  classDef cycle stroke:#d00,color:#d00,stroke-width:2px`,
+				`  class n1,n2 cycle`,
+				`  linkStyle 1,2 stroke:#d00,color:#d00`,
+			},
+		},
+	} {
+		t.Run(test.format, func(t *testing.T) {
+			out := &strings.Builder{}
+			if err := fprintDepGraph(out, test.format, newTestDepGraph()); err != nil {
+				t.Fatalf("couldn't print graph: %s", err)
+			}
+			expected := strings.Join(test.expected, "\n") + "\n"
+			if actual := out.String(); actual != expected {
+				t.Errorf("expected graph:\n%s\ngot:\n%s", expected, actual)
+			}
+		})
+	}
+
+	if err := fprintDepGraph(&strings.Builder{}, "svg", newTestDepGraph()); err == nil {
+		t.Error("expected an error for an unknown graph format")
+	}
+}
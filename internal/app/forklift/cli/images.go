@@ -6,6 +6,8 @@ import (
 	"io"
 	"maps"
 	"os"
+	"path"
+	"path/filepath"
 	"slices"
 
 	"github.com/pkg/errors"
@@ -13,6 +15,7 @@ import (
 
 	"github.com/forklift-run/forklift/internal/app/forklift"
 	"github.com/forklift-run/forklift/internal/clients/cli"
+	"github.com/forklift-run/forklift/internal/clients/crane"
 	"github.com/forklift-run/forklift/internal/clients/docker"
 	"github.com/forklift-run/forklift/pkg/structures"
 )
@@ -42,7 +45,7 @@ func DownloadImagesForStoreApply(
 			"Downloading Docker container images specified by the last successfully-applied staged "+
 				"pallet bundle, in case the next to be applied fails to be applied...",
 		)
-		if err := DownloadImages(indent+1, bundle, bundle, platform, false, parallel); err != nil {
+		if err := downloadUnbundledImages(indent+1, bundle, platform, parallel); err != nil {
 			return err
 		}
 	}
@@ -61,7 +64,7 @@ func DownloadImagesForStoreApply(
 			"Downloading Docker container images specified by the next staged pallet bundle to be "+
 				"applied...",
 		)
-		if err := DownloadImages(indent+1, bundle, bundle, platform, false, parallel); err != nil {
+		if err := downloadUnbundledImages(indent+1, bundle, platform, parallel); err != nil {
 			return err
 		}
 		fmt.Fprintln(os.Stderr)
@@ -77,13 +80,42 @@ func DownloadImages(
 	if err != nil {
 		return errors.Wrap(err, "couldn't determine images required by package deployments")
 	}
+	return downloadImages(indent, orderedImages, platform, parallel)
+}
+
+// downloadUnbundledImages downloads the container images required by the bundle's enabled package
+// deployments, except for images included in the bundle (which will instead be loaded from the
+// bundle when the bundle is applied).
+func downloadUnbundledImages(
+	indent int, bundle *forklift.FSBundle, platform string, parallel bool,
+) error {
+	required, err := ListRequiredImages(bundle, bundle, false)
+	if err != nil {
+		return errors.Wrap(err, "couldn't determine images required by package deployments")
+	}
+	images := make([]string, 0, len(required))
+	for _, image := range required {
+		hasImage, err := bundle.HasImage(image)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't check whether bundle includes image %s", image)
+		}
+		if hasImage {
+			IndentedFprintf(indent, os.Stderr, "Skipping %s, which is included in the bundle\n", image)
+			continue
+		}
+		images = append(images, image)
+	}
+	return downloadImages(indent, images, platform, parallel)
+}
+
+func downloadImages(indent int, orderedImages []string, platform string, parallel bool) error {
 	if len(orderedImages) == 0 {
 		// When there are no images to download, don't cause an error if we can't initialize the
 		// Docker API client!
 		return nil
 	}
 
-	dc, err := docker.NewClient()
+	dc, err := docker.NewClient(docker.WithPullProgressHandler(emitPullProgress))
 	if err != nil {
 		return errors.Wrap(err, "couldn't make Docker API client")
 	}
@@ -137,7 +169,9 @@ func downloadImagesParallel(indent int, images []string, platform string, dc *do
 	for _, image := range images {
 		eg.Go(func() error {
 			IndentedFprintf(indent, os.Stderr, "Downloading %s...\n", image)
+			finish := startDownloadEvents(eventPullStarted, eventPullFinished, dockerImageResource, image)
 			pulled, err := dc.PullImage(egctx, image, platform, docker.NewOutStream(io.Discard))
+			finish(downloadSucceeded, err)
 			if err != nil {
 				return errors.Wrapf(err, "couldn't download %s", image)
 			}
@@ -156,10 +190,17 @@ func downloadImagesParallel(indent int, images []string, platform string, dc *do
 func downloadImagesSerial(indent int, images []string, platform string, dc *docker.Client) error {
 	for _, image := range images {
 		IndentedFprintf(indent, os.Stderr, "Downloading %s...\n", image)
+		finish := startDownloadEvents(eventPullStarted, eventPullFinished, dockerImageResource, image)
+		progressOut := os.Stdout
+		if eventsOnStdout() {
+			// Docker's progress output would corrupt the stream of events, so we move it out of the way:
+			progressOut = os.Stderr
+		}
 		pulled, err := dc.PullImage(
 			context.Background(), image, platform,
-			docker.NewOutStream(cli.NewIndentedWriter(indent+1, os.Stdout)),
+			docker.NewOutStream(cli.NewIndentedWriter(indent+1, progressOut)),
 		)
+		finish(downloadSucceeded, err)
 		if err != nil {
 			return errors.Wrapf(err, "couldn't download %s", image)
 		}
@@ -169,3 +210,131 @@ func downloadImagesSerial(indent int, images []string, platform string, dc *dock
 	}
 	return nil
 }
+
+// Bundled images
+
+// SaveBundledImages downloads the container images used by the Compose apps of the bundle's package
+// deployments, and it saves them as tarballs in the bundle, so that the bundle can be applied
+// without internet access.
+func SaveBundledImages(
+	indent int, bundle *forklift.FSBundle, platform string, parallel bool,
+) error {
+	images := bundle.ListImages()
+	if !parallel {
+		for _, image := range images {
+			if err := saveBundledImage(context.Background(), indent, bundle, image, platform); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	eg, egctx := errgroup.WithContext(context.Background())
+	for _, image := range images {
+		eg.Go(func() error {
+			return saveBundledImage(egctx, indent, bundle, image, platform)
+		})
+	}
+	return eg.Wait()
+}
+
+func saveBundledImage(
+	ctx context.Context, indent int, bundle *forklift.FSBundle, image, platform string,
+) (err error) {
+	outputPath, err := bundle.GetImagePath(image)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't determine path of image %s in bundle", image)
+	}
+	if err = forklift.EnsureExists(filepath.FromSlash(path.Dir(outputPath))); err != nil {
+		return err
+	}
+
+	IndentedFprintf(indent, os.Stderr, "Saving %s into the bundle...\n", image)
+	finish := startDownloadEvents(
+		eventDownloadStarted, eventDownloadFinished, dockerImageResource, image,
+	)
+	defer func() {
+		finish(downloadSucceeded, err)
+	}()
+	tmpPath := outputPath + ".fkldownload"
+	file, err := os.Create(filepath.FromSlash(tmpPath))
+	if err != nil {
+		return errors.Wrapf(err, "couldn't create temporary file at %s", tmpPath)
+	}
+	if err = crane.SaveImage(ctx, image, file, platform); err != nil {
+		_ = file.Close()
+		return errors.Wrapf(err, "couldn't download and save image as a tarball: %s", image)
+	}
+	if err = file.Close(); err != nil {
+		return errors.Wrapf(err, "couldn't close temporary file %s", tmpPath)
+	}
+	if err = os.Rename(filepath.FromSlash(tmpPath), filepath.FromSlash(outputPath)); err != nil {
+		return errors.Wrapf(err, "couldn't commit saved image from %s to %s", tmpPath, outputPath)
+	}
+	return nil
+}
+
+// LoadBundledImages loads container images saved in the bundle into Docker, if they're missing from
+// Docker's local image store.
+func LoadBundledImages(indent int, bundle *forklift.FSBundle) error {
+	var bundled []string
+	for _, image := range bundle.ListImages() {
+		hasImage, err := bundle.HasImage(image)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't check whether bundle includes image %s", image)
+		}
+		if hasImage {
+			bundled = append(bundled, image)
+		}
+	}
+	if len(bundled) == 0 {
+		// When the bundle doesn't include any images, don't cause an error if we can't initialize the
+		// Docker API client!
+		return nil
+	}
+
+	dc, err := docker.NewClient()
+	if err != nil {
+		return errors.Wrap(err, "couldn't make Docker API client")
+	}
+	for _, image := range bundled {
+		savedName, err := crane.GetSavedImageName(image)
+		if err != nil {
+			return err
+		}
+		loaded, err := dc.HasImage(context.Background(), savedName)
+		if err != nil {
+			return err
+		}
+		if loaded {
+			continue
+		}
+		IndentedFprintf(indent, os.Stderr, "Loading %s from the bundle into Docker...\n", image)
+		if err = loadBundledImage(indent+1, dc, bundle, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadBundledImage(
+	indent int, dc *docker.Client, bundle *forklift.FSBundle, image string,
+) error {
+	imagePath, err := bundle.GetImagePath(image)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't determine path of image %s in bundle", image)
+	}
+	file, err := os.Open(filepath.FromSlash(imagePath))
+	if err != nil {
+		return errors.Wrapf(err, "couldn't open %s", imagePath)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	if err = dc.LoadImages(
+		context.Background(), file, cli.NewIndentedWriter(indent, os.Stderr),
+	); err != nil {
+		return errors.Wrapf(err, "couldn't load image %s from %s", image, imagePath)
+	}
+	return nil
+}
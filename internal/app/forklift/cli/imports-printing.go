@@ -11,11 +11,16 @@ import (
 	"github.com/forklift-run/forklift/internal/app/forklift"
 )
 
-func FprintPalletImports(indent int, out io.Writer, pallet *forklift.FSPallet) error {
+func FprintPalletImports(
+	indent int, out io.Writer, format string, pallet *forklift.FSPallet,
+) error {
 	imps, err := pallet.LoadImports("**/*")
 	if err != nil {
 		return err
 	}
+	if IsStructuredOutput(format) {
+		return FprintDocument(out, format, newImportSummaryDocuments(imps))
+	}
 	for _, imp := range imps {
 		IndentedFprintf(indent, out, "%s\n", imp.Name)
 	}
@@ -23,7 +28,7 @@ func FprintPalletImports(indent int, out io.Writer, pallet *forklift.FSPallet) e
 }
 
 func FprintImportInfo(
-	indent int, out io.Writer,
+	indent int, out io.Writer, format string,
 	pallet *forklift.FSPallet, cache forklift.PathedPalletCache, importName string,
 ) error {
 	imp, err := pallet.LoadImport(importName)
@@ -42,6 +47,13 @@ func FprintImportInfo(
 			err, "couldn't print merge pallet referenced by resolved import group %s", imp.Name,
 		)
 	}
+	if IsStructuredOutput(format) {
+		doc, err := newImportDocument(resolved, cache)
+		if err != nil {
+			return err
+		}
+		return FprintDocument(out, format, doc)
+	}
 	if err = FprintResolvedImport(indent, out, resolved, cache); err != nil {
 		return errors.Wrapf(err, "couldn't print resolved import group %s", imp.Name)
 	}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+)
+
+// WorkspaceLocks specifies which parts of the workspace should be locked against concurrent
+// modification by other processes.
+type WorkspaceLocks struct {
+	// CurrentPallet is whether to lock the workspace's current pallet.
+	CurrentPallet bool
+	// StageStore is whether to lock the stage store.
+	StageStore bool
+	// Caches is whether to lock the workspace's caches.
+	Caches bool
+}
+
+// heldLocks is the set of locks held by the current process, keyed by lock file path.
+var heldLocks struct {
+	sync.Mutex
+	locks map[string]*forklift.FileLock
+	order []string
+}
+
+// LockWorkspace acquires the specified locks on the workspace at the specified path and on the
+// stage store at the specified path (or the workspace's stage store, if no path is specified),
+// waiting up to timeout for each lock to be released by any other process which holds it. Locks
+// are always acquired in the same order (current pallet, then stage store, then caches) to avoid
+// deadlocks, and they're held until [ReleaseLocks] is called. Locks already held by the current
+// process are not acquired again.
+func LockWorkspace(
+	workspacePath, stageStorePath string, locks WorkspaceLocks, timeout time.Duration,
+) error {
+	if !locks.CurrentPallet && !locks.StageStore && !locks.Caches {
+		return nil
+	}
+	// If the workspace doesn't exist yet, then there's nothing in it to lock (and the command will
+	// either fail or make a new workspace):
+	var workspace *forklift.FSWorkspace
+	if forklift.DirExists(workspacePath) {
+		var err error
+		if workspace, err = forklift.LoadWorkspace(workspacePath); err != nil {
+			return errors.Wrap(err, "couldn't load workspace to lock it")
+		}
+	}
+
+	lockPaths := make([]string, 0, 3)
+	if locks.CurrentPallet && workspace != nil {
+		lockPaths = append(lockPaths, workspace.GetCurrentPalletLockPath())
+	}
+	if locks.StageStore {
+		switch {
+		case stageStorePath != "":
+			lockPaths = append(lockPaths, path.Join(stageStorePath, forklift.StageStoreLockFile))
+		case workspace != nil:
+			lockPaths = append(
+				lockPaths, path.Join(workspace.GetStageStorePath(), forklift.StageStoreLockFile),
+			)
+		}
+	}
+	if locks.Caches && workspace != nil {
+		lockPaths = append(lockPaths, workspace.GetCacheLockPath())
+	}
+	for _, lockPath := range lockPaths {
+		if err := acquireLock(lockPath, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func acquireLock(lockPath string, timeout time.Duration) error {
+	heldLocks.Lock()
+	defer heldLocks.Unlock()
+	if _, ok := heldLocks.locks[lockPath]; ok {
+		return nil
+	}
+
+	lock, err := forklift.LockFile(lockPath, timeout, func(err *forklift.LockedError) {
+		IndentedFprintf(
+			0, os.Stderr, "Waiting up to %s for the lock to be released: %s\n", timeout, err.Error(),
+		)
+	})
+	if err != nil {
+		return err
+	}
+	if heldLocks.locks == nil {
+		heldLocks.locks = make(map[string]*forklift.FileLock)
+	}
+	heldLocks.locks[lockPath] = lock
+	heldLocks.order = append(heldLocks.order, lockPath)
+	return nil
+}
+
+// ReleaseLocks releases all locks acquired by [LockWorkspace], in the reverse of the order in
+// which they were acquired.
+func ReleaseLocks() error {
+	heldLocks.Lock()
+	defer heldLocks.Unlock()
+	var firstErr error
+	for i := len(heldLocks.order) - 1; i >= 0; i-- {
+		lockPath := heldLocks.order[i]
+		if err := heldLocks.locks[lockPath].Unlock(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(heldLocks.locks, lockPath)
+	}
+	heldLocks.order = nil
+	return firstErr
+}
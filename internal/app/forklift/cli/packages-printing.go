@@ -12,7 +12,13 @@ import (
 	"github.com/forklift-run/forklift/pkg/core"
 )
 
-func FprintPkg(indent int, out io.Writer, cache forklift.PathedRepoCache, pkg *core.FSPkg) {
+func FprintPkg(
+	indent int, out io.Writer, format string, cache forklift.PathedRepoCache, pkg *core.FSPkg,
+) error {
+	if IsStructuredOutput(format) {
+		return FprintDocument(out, format, newPkgDocument(pkg))
+	}
+
 	IndentedFprintf(indent, out, "Package: %s\n", pkg.Path())
 	indent++
 
@@ -28,6 +34,7 @@ func FprintPkg(indent int, out io.Writer, cache forklift.PathedRepoCache, pkg *c
 	FprintDeplSpec(indent, out, pkg.Def.Deployment)
 	_, _ = fmt.Fprintln(out)
 	FprintFeatureSpecs(indent, out, pkg.Def.Features)
+	return nil
 }
 
 func fprintPkgRepo(indent int, out io.Writer, cache forklift.PathedRepoCache, pkg *core.FSPkg) {
@@ -217,7 +224,7 @@ func FprintFeatureSpec(indent int, out io.Writer, name string, spec core.PkgFeat
 // Pallet packages
 
 func FprintPalletPkgs(
-	indent int, out io.Writer, pallet *forklift.FSPallet, loader forklift.FSPkgLoader,
+	indent int, out io.Writer, format string, pallet *forklift.FSPallet, loader forklift.FSPkgLoader,
 ) error {
 	reqs, err := pallet.LoadFSRepoReqs("**")
 	if err != nil {
@@ -249,6 +256,13 @@ func FprintPalletPkgs(
 	slices.SortFunc(pkgs, func(a, b *core.FSPkg) int {
 		return core.ComparePkgs(a.Pkg, b.Pkg)
 	})
+	if IsStructuredOutput(format) {
+		docs := make([]PkgDocument, 0, len(pkgs))
+		for _, pkg := range pkgs {
+			docs = append(docs, newPkgDocument(pkg))
+		}
+		return FprintDocument(out, format, docs)
+	}
 	for _, pkg := range pkgs {
 		IndentedFprintf(indent, out, "%s\n", pkg.Path())
 	}
@@ -279,7 +293,7 @@ func FprintPkgLocation(
 }
 
 func FprintPkgInfo(
-	indent int, out io.Writer,
+	indent int, out io.Writer, format string,
 	pallet *forklift.FSPallet, cache forklift.PathedRepoCache, pkgPath string,
 ) error {
 	pkg, _, err := forklift.LoadRequiredFSPkg(pallet, cache, pkgPath)
@@ -288,6 +302,5 @@ func FprintPkgInfo(
 			err, "couldn't look up information about package %s in pallet %s", pkgPath, pallet.FS.Path(),
 		)
 	}
-	FprintPkg(indent, out, cache, pkg)
-	return nil
+	return FprintPkg(indent, out, format, cache, pkg)
 }
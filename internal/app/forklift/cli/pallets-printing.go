@@ -17,8 +17,17 @@ import (
 )
 
 func FprintCachedPallet(
-	indent int, out io.Writer, cache core.Pather, pallet *forklift.FSPallet, printHeader bool,
+	indent int, out io.Writer, format string,
+	cache core.Pather, pallet *forklift.FSPallet, printHeader bool,
 ) error {
+	if IsStructuredOutput(format) {
+		doc, err := newPalletDocument(pallet, true)
+		if err != nil {
+			return err
+		}
+		return FprintDocument(out, format, doc)
+	}
+
 	if printHeader {
 		IndentedFprintf(indent, out, "Cached pallet: %s\n", pallet.Path())
 		indent++
@@ -108,7 +117,16 @@ func fprintPalletFeatures(indent int, out io.Writer, pallet *forklift.FSPallet)
 	return nil
 }
 
-func FprintPalletInfo(indent int, out io.Writer, pallet *forklift.FSPallet) error {
+func FprintPalletInfo(indent int, out io.Writer, format string, pallet *forklift.FSPallet) error {
+	if IsStructuredOutput(format) {
+		// Note: we don't include the package deployments, for the same reason as explained below.
+		doc, err := newPalletDocument(pallet, false)
+		if err != nil {
+			return err
+		}
+		return FprintDocument(out, format, doc)
+	}
+
 	IndentedFprintf(indent, out, "Pallet: %s\n", pallet.Path())
 	indent++
 
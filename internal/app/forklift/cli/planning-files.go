@@ -0,0 +1,320 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	"github.com/forklift-run/forklift/internal/clients/docker"
+	"github.com/forklift-run/forklift/pkg/structures"
+)
+
+// A SavedPlan is a plan for changes to make to the Docker host, saved so that it can be reviewed
+// and then applied later, but only if neither the Docker host nor the desired state has changed
+// since the plan was computed.
+type SavedPlan struct {
+	// Bundle is the index of the staged pallet bundle which the plan was computed for. It's 0 if the
+	// plan was computed for a pallet rather than for a staged pallet bundle.
+	Bundle int `json:"bundle,omitempty"`
+	// Options is the set of options which the plan was computed with.
+	Options SavedPlanOptions `json:"options"`
+	// HostFingerprint is a digest of the state of the Compose apps on the Docker host which the plan
+	// was computed against.
+	HostFingerprint string `json:"host-fingerprint"`
+	// Changes is the list of changes in the plan, sorted by ID.
+	Changes []SavedChange `json:"changes"`
+	// Serialization is the list of IDs of the changes in the order in which they'll be applied, if
+	// the plan is to be applied serially. It's empty if the plan is to be applied concurrently.
+	Serialization []string `json:"serialization,omitempty"`
+}
+
+// SavedPlanOptions is the set of options which a saved plan was computed with.
+type SavedPlanOptions struct {
+	// Parallel is the Parallel option of PlanOptions.
+	Parallel bool `json:"parallel"`
+	// PrecreateNetworks is the PrecreateNetworks option of PlanOptions.
+	PrecreateNetworks bool `json:"precreate-networks"`
+	// OrphanedVolumes is the OrphanedVolumes option of PlanOptions.
+	OrphanedVolumes string `json:"orphaned-volumes,omitempty"`
+	// UnownedApps is the UnownedApps option of PlanOptions.
+	UnownedApps string `json:"unowned-apps,omitempty"`
+	// Only is the Only option of PlanOptions.
+	Only []string `json:"only,omitempty"`
+	// Exclude is the Exclude option of PlanOptions.
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// PlanOptions returns the PlanOptions which the saved plan was computed with.
+func (o SavedPlanOptions) PlanOptions() PlanOptions {
+	return PlanOptions{
+		Parallel:          o.Parallel,
+		PrecreateNetworks: o.PrecreateNetworks,
+		OrphanedVolumes:   o.OrphanedVolumes,
+		UnownedApps:       o.UnownedApps,
+		Only:              o.Only,
+		Exclude:           o.Exclude,
+	}
+}
+
+// A SavedChange describes a change in a saved plan.
+type SavedChange struct {
+	// ID identifies the change within the plan.
+	ID string `json:"id"`
+	// Type is the type of change (e.g. add, remove, update, no-op, or create-network).
+	Type string `json:"type"`
+	// Name is the name of the Compose app to be changed, or of the Docker network to be created.
+	Name string `json:"name"`
+	// Deployment is the name of the package deployment responsible for the change, if any.
+	Deployment string `json:"deployment,omitempty"`
+	// Description is a human-readable description of the change.
+	Description string `json:"description"`
+	// Fingerprint is the fingerprint of the desired Compose app definition, if any.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// After is the list of IDs of changes which must be applied before this change.
+	After []string `json:"after,omitempty"`
+	// OrphanedNetworks is the OrphanedNetworks field of the change.
+	OrphanedNetworks []string `json:"orphaned-networks,omitempty"`
+	// OrphanedVolumes is the OrphanedVolumes field of the change.
+	OrphanedVolumes []string `json:"orphaned-volumes,omitempty"`
+	// RemoveOrphanedVolumes is the RemoveOrphanedVolumes field of the change.
+	RemoveOrphanedVolumes bool `json:"remove-orphaned-volumes,omitempty"`
+}
+
+// PlanToSave builds a plan for changes to make to the Docker host (as with [Plan]) which can be
+// saved with [WriteSavedPlan]. bundleIndex is the index of the staged pallet bundle to plan for,
+// or 0 if deplsLoader is a pallet.
+func PlanToSave(
+	indent int, deplsLoader ResolvedDeplsLoader, pkgLoader forklift.FSPkgLoader, opts PlanOptions,
+	bundleIndex int,
+) (SavedPlan, error) {
+	if opts.Refresh {
+		return SavedPlan{}, errors.New("plans which refresh all Compose apps can't be saved")
+	}
+	hostFingerprint, err := fingerprintHost(deplsLoader, pkgLoader, opts.UnownedApps)
+	if err != nil {
+		return SavedPlan{}, err
+	}
+	changeDeps, serialization, err := Plan(indent, deplsLoader, pkgLoader, opts)
+	if err != nil {
+		return SavedPlan{}, err
+	}
+	return newSavedPlan(bundleIndex, opts, hostFingerprint, changeDeps, serialization), nil
+}
+
+func newSavedPlan(
+	bundleIndex int, opts PlanOptions, hostFingerprint string,
+	changeDeps structures.Digraph[*ReconciliationChange], serialization []*ReconciliationChange,
+) SavedPlan {
+	plan := SavedPlan{
+		Bundle: bundleIndex,
+		Options: SavedPlanOptions{
+			Parallel:          opts.Parallel,
+			PrecreateNetworks: opts.PrecreateNetworks,
+			OrphanedVolumes:   opts.OrphanedVolumes,
+			UnownedApps:       opts.UnownedApps,
+			Only:              opts.Only,
+			Exclude:           opts.Exclude,
+		},
+		HostFingerprint: hostFingerprint,
+		Changes:         make([]SavedChange, 0, len(changeDeps)),
+	}
+	for change, deps := range changeDeps {
+		saved := SavedChange{
+			ID:                    change.String(),
+			Type:                  change.Type,
+			Name:                  change.Name,
+			Description:           change.PlanString(),
+			Fingerprint:           change.Fingerprint,
+			OrphanedNetworks:      change.OrphanedNetworks,
+			OrphanedVolumes:       change.OrphanedVolumes,
+			RemoveOrphanedVolumes: change.RemoveOrphanedVolumes,
+		}
+		if change.Depl != nil {
+			saved.Deployment = change.Depl.Name
+		}
+		for dep := range deps {
+			saved.After = append(saved.After, dep.String())
+		}
+		slices.Sort(saved.After)
+		plan.Changes = append(plan.Changes, saved)
+	}
+	slices.SortFunc(plan.Changes, func(a, b SavedChange) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	for _, change := range serialization {
+		plan.Serialization = append(plan.Serialization, change.String())
+	}
+	return plan
+}
+
+// fingerprintHost returns a digest of the names, statuses, and fingerprints of the Compose apps
+// on the Docker host which Forklift may change (according to the policy for handling Compose apps
+// not created by Forklift).
+func fingerprintHost(
+	deplsLoader ResolvedDeplsLoader, pkgLoader forklift.FSPkgLoader, unownedApps string,
+) (string, error) {
+	depls, err := deplsLoader.LoadDepls("**/*")
+	if err != nil {
+		return "", err
+	}
+	resolved, err := forklift.ResolveDepls(
+		deplsLoader, pkgLoader, forklift.FilterDeplsForEnabled(depls),
+	)
+	if err != nil {
+		return "", err
+	}
+	dc, err := docker.NewClient()
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't make Docker API client")
+	}
+	apps, err := dc.ListApps(context.Background())
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't list active Docker Compose apps")
+	}
+	if apps, err = filterUnownedApps(0, dc, resolved, apps, unownedApps); err != nil {
+		return "", err
+	}
+	fingerprints, err := getAppFingerprints(context.Background(), dc, apps)
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't determine fingerprints of active Compose apps")
+	}
+
+	lines := make([]string, 0, len(apps))
+	for _, app := range apps {
+		lines = append(
+			lines, fmt.Sprintf("%s\t%s\t%s\n", app.Name, app.Status, fingerprints[app.Name]),
+		)
+	}
+	slices.Sort(lines)
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(strings.Join(lines, "")))), nil
+}
+
+// checkSavedPlan returns an error if the plan (computed with the specified options) for changes to
+// make to the Docker host doesn't exactly match the saved plan, or if the Docker host has changed
+// since the saved plan was computed.
+func checkSavedPlan(
+	saved SavedPlan, deplsLoader ResolvedDeplsLoader, pkgLoader forklift.FSPkgLoader,
+	opts PlanOptions, changeDeps structures.Digraph[*ReconciliationChange],
+	serialization []*ReconciliationChange,
+) error {
+	hostFingerprint, err := fingerprintHost(deplsLoader, pkgLoader, opts.UnownedApps)
+	if err != nil {
+		return err
+	}
+	if hostFingerprint != saved.HostFingerprint {
+		return errors.New(
+			"the Compose apps on the Docker host have changed since the saved plan was computed",
+		)
+	}
+	current := newSavedPlan(saved.Bundle, opts, hostFingerprint, changeDeps, serialization)
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return errors.Wrap(err, "couldn't serialize the current plan")
+	}
+	savedJSON, err := json.Marshal(saved)
+	if err != nil {
+		return errors.Wrap(err, "couldn't serialize the saved plan")
+	}
+	if string(currentJSON) == string(savedJSON) {
+		return nil
+	}
+
+	savedIDs := make(structures.Set[string])
+	for _, change := range saved.Changes {
+		savedIDs.Add(change.ID)
+	}
+	currentIDs := make(structures.Set[string])
+	for _, change := range current.Changes {
+		currentIDs.Add(change.ID)
+	}
+	if added := currentIDs.Difference(savedIDs); len(added) > 0 {
+		return errors.Errorf(
+			"the desired state has changed since the saved plan was computed; it now also requires: %+v",
+			slices.Sorted(added.All()),
+		)
+	}
+	if removed := savedIDs.Difference(currentIDs); len(removed) > 0 {
+		return errors.Errorf(
+			"the desired state has changed since the saved plan was computed; it no longer requires: "+
+				"%+v",
+			slices.Sorted(removed.All()),
+		)
+	}
+	return errors.New(
+		"the desired state or plan options have changed since the saved plan was computed",
+	)
+}
+
+// A StaleSavedPlanError is returned when a saved plan is refused because it no longer matches the
+// plan computed for the Docker host, so that nothing was changed on the Docker host.
+type StaleSavedPlanError struct {
+	// Err describes how the saved plan doesn't match the computed plan.
+	Err error
+}
+
+func (e *StaleSavedPlanError) Error() string {
+	return fmt.Sprintf("refusing to apply the saved plan: %s", e.Err.Error())
+}
+
+func (e *StaleSavedPlanError) Unwrap() error {
+	return e.Err
+}
+
+// isStaleSavedPlan checks whether the error is (or wraps) a [*StaleSavedPlanError].
+func isStaleSavedPlan(err error) bool {
+	var stale *StaleSavedPlanError
+	return errors.As(err, &stale)
+}
+
+// CheckSavedPlanBundle returns an error if the saved plan was computed for a staged pallet bundle
+// other than the one with the specified index, or if it was computed for a pallet rather than for a
+// staged pallet bundle.
+func CheckSavedPlanBundle(plan SavedPlan, bundleIndex int) error {
+	if plan.Bundle == 0 {
+		return errors.New(
+			"the saved plan was computed for a pallet, not for a staged pallet bundle; you should " +
+				"compute the plan with `forklift stage plan` instead",
+		)
+	}
+	if plan.Bundle == bundleIndex {
+		return nil
+	}
+	return errors.Errorf(
+		"the saved plan was computed for staged pallet bundle %d, not for staged pallet bundle %d",
+		plan.Bundle, bundleIndex,
+	)
+}
+
+// WriteSavedPlan saves the plan as a JSON file at the specified path.
+func WriteSavedPlan(plan SavedPlan, outputPath string) error {
+	marshaled, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "couldn't serialize plan as JSON")
+	}
+	const perm = 0o644 // owner rw, group r, public r
+	if err := os.WriteFile(outputPath, append(marshaled, '\n'), perm); err != nil {
+		return errors.Wrapf(err, "couldn't save plan to %s", outputPath)
+	}
+	return nil
+}
+
+// LoadSavedPlan loads a plan which was saved as a JSON file at the specified path.
+func LoadSavedPlan(planPath string) (SavedPlan, error) {
+	marshaled, err := os.ReadFile(planPath)
+	if err != nil {
+		return SavedPlan{}, errors.Wrapf(err, "couldn't read saved plan from %s", planPath)
+	}
+	var plan SavedPlan
+	if err = json.Unmarshal(marshaled, &plan); err != nil {
+		return SavedPlan{}, errors.Wrapf(err, "couldn't parse saved plan from %s", planPath)
+	}
+	return plan, nil
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/docker/compose/v2/pkg/api"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	"github.com/forklift-run/forklift/pkg/structures"
+)
+
+func TestNewSavedPlan(t *testing.T) {
+	web := &forklift.ResolvedDepl{Depl: forklift.Depl{Name: "apps/web"}}
+	db := &forklift.ResolvedDepl{Depl: forklift.Depl{Name: "apps/db"}}
+	addWeb := newAddReconciliationChange("apps_web", web, "sha256:web")
+	addDB := newAddReconciliationChange("apps_db", db, "sha256:db")
+	removeOld := newRemoveReconciliationChange("old_app", api.Stack{Name: "old_app"})
+	changeDeps := make(structures.Digraph[*ReconciliationChange])
+	changeDeps.AddNode(removeOld)
+	changeDeps.AddEdge(addWeb, addDB)
+	changeDeps.AddEdge(addWeb, removeOld)
+	changeDeps.AddNode(addDB)
+	serialization := []*ReconciliationChange{removeOld, addDB, addWeb}
+
+	plan := newSavedPlan(2, PlanOptions{}, "sha256:host", changeDeps, serialization)
+	if plan.Bundle != 2 || plan.HostFingerprint != "sha256:host" {
+		t.Errorf("expected plan for bundle 2 on host sha256:host, got %+v", plan)
+	}
+	ids := make([]string, 0, len(plan.Changes))
+	for _, change := range plan.Changes {
+		ids = append(ids, change.ID)
+	}
+	if expected := []string{"(add apps/db)", "(add apps/web)", "(remove old_app)"}; !slices.Equal(
+		ids, expected,
+	) {
+		t.Errorf("expected changes sorted as %v, got %v", expected, ids)
+	}
+	if expected := []string{"(add apps/db)", "(remove old_app)"}; !slices.Equal(
+		plan.Changes[1].After, expected,
+	) {
+		t.Errorf("expected change %s to be after %v, got %v", ids[1], expected, plan.Changes[1].After)
+	}
+	if plan.Changes[1].Deployment != "apps/web" || plan.Changes[1].Fingerprint != "sha256:web" {
+		t.Errorf("expected change %s for deployment apps/web, got %+v", ids[1], plan.Changes[1])
+	}
+	if expected := []string{"(remove old_app)", "(add apps/db)", "(add apps/web)"}; !slices.Equal(
+		plan.Serialization, expected,
+	) {
+		t.Errorf("expected serialization %v, got %v", expected, plan.Serialization)
+	}
+
+	// Plans must be reproducible, since saved plans are compared with freshly-computed plans:
+	if again := newSavedPlan(
+		2, PlanOptions{}, "sha256:host", changeDeps, serialization,
+	); !reflect.DeepEqual(again, plan) {
+		t.Errorf("expected identical plans, got %+v and %+v", plan, again)
+	}
+}
+
+func TestSavedPlanRoundTrip(t *testing.T) {
+	plan := SavedPlan{
+		Bundle:          3,
+		Options:         SavedPlanOptions{Parallel: true, PrecreateNetworks: true},
+		HostFingerprint: "sha256:host",
+		Changes: []SavedChange{{
+			ID: "(add apps/web)", Type: addReconciliationChange, Name: "apps_web",
+			Deployment: "apps/web", Description: "Add apps/web as apps_web",
+		}},
+	}
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	if err := WriteSavedPlan(plan, planPath); err != nil {
+		t.Fatalf("couldn't save plan: %s", err)
+	}
+	loaded, err := LoadSavedPlan(planPath)
+	if err != nil {
+		t.Fatalf("couldn't load saved plan: %s", err)
+	}
+	if !reflect.DeepEqual(loaded, plan) {
+		t.Errorf("expected loaded plan %+v, got %+v", plan, loaded)
+	}
+	if _, err := LoadSavedPlan(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing saved plan")
+	}
+}
+
+func TestCheckSavedPlanBundle(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		bundle  int
+		invalid bool
+	}{
+		{name: "same bundle", bundle: 2},
+		{name: "other bundle", bundle: 3, invalid: true},
+		{name: "pallet", invalid: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckSavedPlanBundle(SavedPlan{Bundle: test.bundle}, 2)
+			if test.invalid && err == nil {
+				t.Error("expected an error for a plan computed for something else")
+			}
+			if !test.invalid && err != nil {
+				t.Errorf("couldn't check saved plan: %s", err)
+			}
+		})
+	}
+}
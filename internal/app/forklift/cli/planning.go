@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"os"
 	"slices"
+	"strings"
 
+	dct "github.com/compose-spec/compose-go/v2/types"
 	"github.com/docker/compose/v2/pkg/api"
 	"github.com/pkg/errors"
 
@@ -20,17 +22,37 @@ const (
 	addReconciliationChange    = "add"
 	removeReconciliationChange = "remove"
 	updateReconciliationChange = "update"
+	noOpReconciliationChange   = "no-op"
+	// createNetworkReconciliationChange is the type of change for pre-creating a Docker network
+	// provided by a deployment, before that deployment (or any deployment requiring the network) is
+	// added or updated.
+	createNetworkReconciliationChange = "create-network"
 )
 
 type ReconciliationChange struct {
-	Name string
+	Name string // for a network to be created, this is the name of the network
 	Type string
 	Depl *forklift.ResolvedDepl // this is nil for an app to be removed
 	App  api.Stack              // this is empty for an app which does not yet exist
+	// Fingerprint is the fingerprint of the desired Compose app definition. It is empty for an app
+	// to be removed.
+	Fingerprint string
+	// ServiceDiffs describes how the services of the Compose app will change in an update.
+	ServiceDiffs []docker.ServiceDiff
+	// OrphanedNetworks is a list of Docker networks which were created for the Compose app but are
+	// no longer declared by its desired definition, and which will be removed after an update.
+	OrphanedNetworks []string
+	// OrphanedVolumes is a list of Docker volumes which were created for the Compose app but are no
+	// longer declared by its desired definition. This is only populated if orphaned volumes are not
+	// silently kept.
+	OrphanedVolumes []string
+	// RemoveOrphanedVolumes indicates whether OrphanedVolumes will be removed after an update (rather
+	// than only being reported).
+	RemoveOrphanedVolumes bool
 }
 
 func (c *ReconciliationChange) String() string {
-	if c.Depl == nil {
+	if c.Depl == nil || c.Type == createNetworkReconciliationChange {
 		return fmt.Sprintf("(%s %s)", c.Type, c.Name)
 	}
 	return fmt.Sprintf("(%s %s)", c.Type, c.Depl.Name)
@@ -40,27 +62,60 @@ func (c *ReconciliationChange) PlanString() string {
 	if c.Depl == nil {
 		return fmt.Sprintf("%s Compose app %s (from unknown deployment)", c.Type, c.Name)
 	}
+	if c.Type == createNetworkReconciliationChange {
+		return fmt.Sprintf("create Docker network %s for deployment %s", c.Name, c.Depl.Name)
+	}
+	if c.Type == noOpReconciliationChange {
+		return fmt.Sprintf(
+			"leave deployment %s as Compose app %s (unchanged, so it will be skipped)",
+			c.Depl.Name, c.Name,
+		)
+	}
 	return fmt.Sprintf("%s deployment %s as Compose app %s", c.Type, c.Depl.Name, c.Name)
 }
 
 func newAddReconciliationChange(
-	deplName string, depl *forklift.ResolvedDepl,
+	deplName string, depl *forklift.ResolvedDepl, fingerprint string,
 ) *ReconciliationChange {
 	return &ReconciliationChange{
-		Name: forklift.GetComposeAppName(deplName),
-		Type: addReconciliationChange,
-		Depl: depl,
+		Name:        forklift.GetComposeAppName(deplName),
+		Type:        addReconciliationChange,
+		Depl:        depl,
+		Fingerprint: fingerprint,
 	}
 }
 
 func newUpdateReconciliationChange(
-	deplName string, depl *forklift.ResolvedDepl, app api.Stack,
+	deplName string, depl *forklift.ResolvedDepl, app api.Stack, fingerprint string,
 ) *ReconciliationChange {
 	return &ReconciliationChange{
-		Name: forklift.GetComposeAppName(deplName),
-		Type: updateReconciliationChange,
-		Depl: depl,
-		App:  app,
+		Name:        forklift.GetComposeAppName(deplName),
+		Type:        updateReconciliationChange,
+		Depl:        depl,
+		App:         app,
+		Fingerprint: fingerprint,
+	}
+}
+
+func newNoOpReconciliationChange(
+	deplName string, depl *forklift.ResolvedDepl, app api.Stack, fingerprint string,
+) *ReconciliationChange {
+	return &ReconciliationChange{
+		Name:        forklift.GetComposeAppName(deplName),
+		Type:        noOpReconciliationChange,
+		Depl:        depl,
+		App:         app,
+		Fingerprint: fingerprint,
+	}
+}
+
+func newCreateNetworkReconciliationChange(
+	networkName string, provider *forklift.ResolvedDepl,
+) *ReconciliationChange {
+	return &ReconciliationChange{
+		Name: networkName,
+		Type: createNetworkReconciliationChange,
+		Depl: provider,
 	}
 }
 
@@ -72,42 +127,175 @@ func newRemoveReconciliationChange(appName string, app api.Stack) *Reconciliatio
 	}
 }
 
+const (
+	// OrphanedVolumesKeep is the policy of silently keeping orphaned Docker volumes.
+	OrphanedVolumesKeep = "keep"
+	// OrphanedVolumesRemove is the policy of removing orphaned Docker volumes.
+	OrphanedVolumesRemove = "remove"
+	// OrphanedVolumesReport is the policy of keeping orphaned Docker volumes, but reporting them.
+	OrphanedVolumesReport = "report"
+)
+
+const (
+	// UnownedAppsAdopt is the policy of taking over any Compose app which wasn't created by Forklift
+	// but has the same name as the Compose app of some deployment, and of leaving all other Compose
+	// apps not created by Forklift untouched.
+	UnownedAppsAdopt = "adopt"
+	// UnownedAppsIgnore is the policy of leaving all Compose apps not created by Forklift untouched.
+	UnownedAppsIgnore = "ignore"
+	// UnownedAppsMigrate is the policy of treating Compose apps without any label recording which
+	// tool created them (e.g. Compose apps created by versions of Forklift which didn't label their
+	// Compose apps) as if they were created by Forklift, so that they are removed if they don't
+	// match any deployment; other Compose apps not created by Forklift are handled as with
+	// UnownedAppsAdopt.
+	UnownedAppsMigrate = "migrate"
+	// UnownedAppsRemove is the policy of treating all Compose apps not created by Forklift as if
+	// they were created by Forklift, so that they are removed if they don't match any deployment.
+	UnownedAppsRemove = "remove"
+)
+
+// PlanOptions controls how a plan for changes to make to the Docker host is built.
+type PlanOptions struct {
+	// Parallel allows the plan to be executed concurrently (rather than serially).
+	Parallel bool
+	// PrecreateNetworks causes every Docker network which is provided by one deployment and required
+	// by other deployments to be created by a separate change which runs before the deployments are
+	// added or updated, so that deployments don't need to wait for each other merely because they
+	// share networks. This prevents dependency cycles caused only by shared networks.
+	PrecreateNetworks bool
+	// OrphanedVolumes is the policy (either OrphanedVolumesKeep, OrphanedVolumesRemove, or
+	// OrphanedVolumesReport) for handling Docker volumes which were created for a Compose app but
+	// are no longer declared by the app after it's updated. Orphaned Docker networks are always
+	// removed. An empty policy is treated as OrphanedVolumesKeep.
+	OrphanedVolumes string
+	// UnownedApps is the policy (either UnownedAppsAdopt, UnownedAppsIgnore, UnownedAppsMigrate, or
+	// UnownedAppsRemove) for handling Compose apps on the Docker host which weren't created by
+	// Forklift. An empty policy is treated as UnownedAppsAdopt.
+	UnownedApps string
+	// Refresh causes the Compose apps of all deployments in the plan to be updated even if their
+	// definitions haven't changed since they were last deployed, so that any of their containers
+	// which are missing or stopped will be recreated or restarted.
+	Refresh bool
+	// Only is a list of the names of deployments to restrict the plan to. Any deployment required by
+	// one of these deployments (whether directly or indirectly) is also included in the plan. If the
+	// list is empty, all deployments are included in the plan.
+	Only []string
+	// Exclude is a list of the names of deployments to exclude from the plan, even if they're
+	// required by a deployment listed in Only. Compose apps of excluded deployments are left
+	// untouched.
+	Exclude []string
+}
+
+// IsPartial returns whether the options restrict the plan to only some deployments.
+func (o PlanOptions) IsPartial() bool {
+	return len(o.Only) > 0 || len(o.Exclude) > 0
+}
+
 // Plan builds a plan for changes to make to the Docker host in order to reconcile it with the
 // desired state as expressed by the pallet or bundle. The plan is expressed as a dependency graph
 // which can be used to build a partial ordering of the changes (where each change is a node in the
 // graph) for concurrent execution, and - if serial execution is required either because the
-// parallel arg is set to true or because a dependency cycle was detected - a total ordering of the
+// Parallel option is not set or because a dependency cycle was detected - a total ordering of the
 // changes for serial (rather than concurrent) execution.
 func Plan(
-	indent int, deplsLoader ResolvedDeplsLoader, pkgLoader forklift.FSPkgLoader, parallel bool,
+	indent int, deplsLoader ResolvedDeplsLoader, pkgLoader forklift.FSPkgLoader, opts PlanOptions,
 ) (
 	changeDeps structures.Digraph[*ReconciliationChange], serialization []*ReconciliationChange,
 	err error,
 ) {
-	dc, err := docker.NewClient()
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "couldn't make Docker API client")
+	if err = checkPlanOptions(opts); err != nil {
+		return nil, nil, err
 	}
-
 	depls, satisfiedDeps, err := Check(indent, deplsLoader, pkgLoader)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "couldn't ensure validity")
 	}
+	return planDepls(indent, depls, satisfiedDeps, opts)
+}
+
+// checkPlanOptions returns an error if any policy in the options is unknown.
+func checkPlanOptions(opts PlanOptions) error {
+	switch opts.OrphanedVolumes {
+	default:
+		return errors.Errorf("unknown orphaned volumes policy '%s'", opts.OrphanedVolumes)
+	case "", OrphanedVolumesKeep, OrphanedVolumesRemove, OrphanedVolumesReport:
+	}
+	return CheckUnownedAppsPolicy(opts.UnownedApps)
+}
+
+// planDepls builds a plan (as described for [Plan]) for the package deployments, which must have
+// already been checked (e.g. by [Check]) with the provided dependency relationships among them.
+func planDepls(
+	indent int, depls []*forklift.ResolvedDepl, satisfiedDeps []forklift.SatisfiedDeplDeps,
+	opts PlanOptions,
+) (
+	changeDeps structures.Digraph[*ReconciliationChange], serialization []*ReconciliationChange,
+	err error,
+) {
+	dc, err := docker.NewClient()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "couldn't make Docker API client")
+	}
+
 	// Always skip nonblocking dependency relationships - even for serial execution, they don't need
 	// to be considered for a total ordering. And we don't want nonblocking dependency relationships
 	// to count towards dependency cycles. And it's simpler to just have the same behavior (and the
 	// same resulting dependency graph) regardless of serial vs. concurrent execution.
 	deps := forklift.ResolveDeps(satisfiedDeps, true)
+	var networkDeps map[string]map[string]string
+	if opts.PrecreateNetworks {
+		deps = forklift.ResolveDeps(withoutNetworkDeps(satisfiedDeps), true)
+		networkDeps = resolveNetworkDeps(satisfiedDeps)
+	}
 
 	IndentedFprintln(indent, os.Stderr, "Determining and ordering package deployment changes...")
 	apps, err := dc.ListApps(context.Background())
 	if err != nil {
 		return nil, nil, errors.Wrapf(err, "couldn't list active Docker Compose apps")
 	}
-	changeDeps, cycles, serialization, err := planChanges(depls, deps, apps, !parallel)
+	if apps, err = filterUnownedApps(indent, dc, depls, apps, opts.UnownedApps); err != nil {
+		return nil, nil, err
+	}
+	appFingerprints, err := getAppFingerprints(context.Background(), dc, apps)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "couldn't determine fingerprints of active Compose apps")
+	}
+	if opts.Refresh {
+		clear(appFingerprints)
+	}
+	appNames := make([]string, 0, len(apps))
+	for _, app := range apps {
+		appNames = append(appNames, app.Name)
+	}
+	appDeps, err := dc.ResolveAppDeps(context.Background(), appNames)
+	if err != nil {
+		return nil, nil, errors.Wrap(
+			err, "couldn't determine Docker resource dependencies among active Compose apps",
+		)
+	}
+	var selectedApps structures.Set[string]
+	if opts.IsPartial() {
+		if selectedApps, err = selectApps(
+			depls, forklift.ResolveDeps(satisfiedDeps, true), apps, opts.Only, opts.Exclude,
+		); err != nil {
+			return nil, nil, errors.Wrap(err, "couldn't select the deployments to plan changes for")
+		}
+		IndentedFprintf(
+			indent, os.Stderr, "Restricting changes to Compose apps: %+v\n",
+			slices.Sorted(selectedApps.All()),
+		)
+	}
+	changeDeps, cycles, serialization, err := planChanges(
+		depls, deps, networkDeps, apps, appFingerprints, appDeps, selectedApps, !opts.Parallel,
+	)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "couldn't compute a plan for changes")
 	}
+	if err = inspectAppUpdates(
+		context.Background(), dc, changeDeps, opts.OrphanedVolumes,
+	); err != nil {
+		return nil, nil, errors.Wrap(err, "couldn't inspect the Compose apps to be updated")
+	}
 
 	IndentedFprintln(indent, os.Stderr, "Ordering relationships:")
 	printDigraph(indent+1, changeDeps, "after")
@@ -116,13 +304,15 @@ func Plan(
 		for _, cycle := range cycles {
 			IndentedFprintf(indent+1, os.Stderr, "cycle between: %s\n", cycle)
 		}
-		if parallel {
+		if opts.Parallel {
 			return nil, nil, errors.Errorf(
 				"concurrent plan would deadlock due to ordering cycles (try a serial plan instead): %+v",
 				cycles,
 			)
 		}
 	}
+	printAppDiffs(indent, changeDeps)
+	printOrphanedResources(indent, changeDeps)
 	if serialization == nil {
 		return changeDeps, nil, nil
 	}
@@ -135,6 +325,319 @@ func Plan(
 	return changeDeps, serialization, nil
 }
 
+// CheckUnownedAppsPolicy returns an error if the policy for handling Compose apps not created by
+// Forklift is unknown.
+func CheckUnownedAppsPolicy(policy string) error {
+	switch policy {
+	default:
+		return errors.Errorf("unknown policy '%s' for apps not created by Forklift", policy)
+	case "", UnownedAppsAdopt, UnownedAppsIgnore, UnownedAppsMigrate, UnownedAppsRemove:
+		return nil
+	}
+}
+
+// ListOwnedApps returns the names of the Compose apps on the Docker host which were created by
+// Forklift, according to the specified policy for handling Compose apps not created by Forklift.
+// Compose apps are normally identified by their labels; but under UnownedAppsMigrate, Compose apps
+// without any ownership label are also included (so that Compose apps created by versions of
+// Forklift which didn't label their Compose apps can be removed); those apps will be labeled when
+// they are next updated by Forklift.
+func ListOwnedApps(
+	ctx context.Context, dc *docker.Client, policy string,
+) (structures.Set[string], error) {
+	owned, err := dc.ListOwnedApps(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't determine which Compose apps were created by Forklift")
+	}
+	if policy != UnownedAppsMigrate {
+		return owned, nil
+	}
+	unlabeled, err := dc.ListUnlabeledApps(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't determine which Compose apps are unlabeled")
+	}
+	owned.Add(slices.Collect(unlabeled.All())...)
+	return owned, nil
+}
+
+// filterUnownedApps returns the Compose apps which Forklift may change on the Docker host,
+// according to the specified policy for handling Compose apps not created by Forklift.
+func filterUnownedApps(
+	indent int, dc *docker.Client, depls []*forklift.ResolvedDepl, apps []api.Stack, policy string,
+) ([]api.Stack, error) {
+	if policy == UnownedAppsRemove {
+		return apps, nil
+	}
+	owned, err := ListOwnedApps(context.Background(), dc, policy)
+	if err != nil {
+		return nil, err
+	}
+	deplsByName := make(map[string]*forklift.ResolvedDepl)
+	for _, depl := range depls {
+		deplsByName[depl.Name] = depl
+	}
+	composeAppDefinerSet, err := identifyComposeAppDefiners(deplsByName)
+	if err != nil {
+		return nil, err
+	}
+	appDeplNames := make(map[string]string)
+	for name := range composeAppDefinerSet {
+		appDeplNames[forklift.GetComposeAppName(name)] = name
+	}
+
+	filtered := make([]api.Stack, 0, len(apps))
+	ignored := make([]string, 0)
+	for _, app := range apps {
+		if owned.Has(app.Name) {
+			filtered = append(filtered, app)
+			continue
+		}
+		deplName, hasDepl := appDeplNames[app.Name]
+		switch {
+		case hasDepl && policy == UnownedAppsIgnore:
+			return nil, errors.Errorf(
+				"Compose app %s wasn't created by Forklift, but deployment %s would replace it",
+				app.Name, deplName,
+			)
+		case hasDepl:
+			filtered = append(filtered, app)
+		default:
+			ignored = append(ignored, app.Name)
+		}
+	}
+	if len(ignored) > 0 {
+		slices.Sort(ignored)
+		IndentedFprintf(
+			indent, os.Stderr, "Ignoring Compose apps not created by Forklift: %+v\n", ignored,
+		)
+	}
+	return filtered, nil
+}
+
+// selectApps returns the set of the names of the Compose apps of the deployments listed in only
+// (or of all deployments and active Compose apps, if only is empty) together with the deployments
+// which they require (as determined from deplDirectDeps), but without the deployments listed in
+// exclude.
+func selectApps(
+	depls []*forklift.ResolvedDepl, deplDirectDeps structures.Digraph[string], apps []api.Stack,
+	only, exclude []string,
+) (structures.Set[string], error) {
+	known := make(structures.Set[string]) // app names
+	for _, depl := range depls {
+		known.Add(forklift.GetComposeAppName(depl.Name))
+	}
+	for _, app := range apps {
+		known.Add(app.Name)
+	}
+	for _, deplName := range slices.Concat(only, exclude) {
+		if !known.Has(forklift.GetComposeAppName(deplName)) {
+			return nil, errors.Errorf(
+				"deployment %s neither exists nor has an active Compose app", deplName,
+			)
+		}
+	}
+
+	selected := make(structures.Set[string])
+	if len(only) == 0 {
+		selected.Add(slices.Collect(known.All())...)
+	}
+	deplIndirectDeps := deplDirectDeps.ComputeTransitiveClosure()
+	for _, deplName := range only {
+		selected.Add(forklift.GetComposeAppName(deplName))
+		for dep := range deplIndirectDeps[deplName] {
+			selected.Add(forklift.GetComposeAppName(dep))
+		}
+	}
+	excluded := make(structures.Set[string])
+	for _, deplName := range exclude {
+		excluded.Add(forklift.GetComposeAppName(deplName))
+	}
+	return selected.Difference(excluded), nil
+}
+
+// inspectAppUpdates records how the services of each Compose app to be updated will change, as
+// well as the Docker networks (and, depending on the orphaned volumes policy, the Docker volumes)
+// which will no longer be declared by the Compose app.
+func inspectAppUpdates(
+	ctx context.Context, dc *docker.Client, changes structures.Digraph[*ReconciliationChange],
+	orphanedVolumesPolicy string,
+) error {
+	for change := range changes {
+		if change.Type != updateReconciliationChange {
+			continue
+		}
+		appDef, _, err := loadFingerprintedComposeApp(change.Depl)
+		if err != nil {
+			return err
+		}
+		if change.ServiceDiffs, err = dc.DiffApp(ctx, appDef); err != nil {
+			return err
+		}
+		if change.OrphanedNetworks, err = dc.ListOrphanedAppNetworks(ctx, appDef); err != nil {
+			return err
+		}
+		if orphanedVolumesPolicy == "" || orphanedVolumesPolicy == OrphanedVolumesKeep {
+			continue
+		}
+		if change.OrphanedVolumes, err = dc.ListOrphanedAppVolumes(ctx, appDef); err != nil {
+			return err
+		}
+		change.RemoveOrphanedVolumes = orphanedVolumesPolicy == OrphanedVolumesRemove
+	}
+	return nil
+}
+
+// printAppDiffs prints how the services of the Compose apps to be updated will change.
+func printAppDiffs(indent int, changes structures.Digraph[*ReconciliationChange]) {
+	sortedChanges := make([]*ReconciliationChange, 0, len(changes))
+	for change := range changes {
+		if len(change.ServiceDiffs) > 0 {
+			sortedChanges = append(sortedChanges, change)
+		}
+	}
+	if len(sortedChanges) == 0 {
+		return
+	}
+	slices.SortFunc(sortedChanges, func(i, j *ReconciliationChange) int {
+		return cmp.Compare(i.Name, j.Name)
+	})
+
+	IndentedFprintln(indent, os.Stderr, "Changes to services of Compose apps to be updated:")
+	for _, change := range sortedChanges {
+		IndentedFprintf(indent+1, os.Stderr, "Compose app %s:\n", change.Name)
+		for _, diff := range change.ServiceDiffs {
+			switch diff.Status {
+			case docker.ServiceAdded:
+				IndentedFprintf(indent+2, os.Stderr, "+ service %s\n", diff.Service)
+				continue
+			case docker.ServiceRemoved:
+				IndentedFprintf(indent+2, os.Stderr, "- service %s\n", diff.Service)
+				continue
+			}
+			IndentedFprintf(indent+2, os.Stderr, "~ service %s:\n", diff.Service)
+			for _, fieldChange := range diff.Changes {
+				IndentedFprintln(indent+3, os.Stderr, formatFieldChange(fieldChange))
+			}
+		}
+	}
+}
+
+func formatFieldChange(change docker.FieldChange) string {
+	field := change.Field
+	if change.Key != "" {
+		field += " " + change.Key
+	}
+	switch {
+	case change.Added:
+		if change.Desired == change.Key {
+			return "+ " + field
+		}
+		return fmt.Sprintf("+ %s: %s", field, formatFieldValue(change.Desired))
+	case change.Removed:
+		if change.Current == change.Key {
+			return "- " + field
+		}
+		return fmt.Sprintf("- %s: %s", field, formatFieldValue(change.Current))
+	default:
+		return fmt.Sprintf(
+			"~ %s: %s -> %s", field, formatFieldValue(change.Current), formatFieldValue(change.Desired),
+		)
+	}
+}
+
+// formatFieldValue quotes an empty value, so that it can be distinguished in printed changes.
+func formatFieldValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+	return value
+}
+
+// printOrphanedResources prints the Docker networks and volumes which will no longer be declared
+// by the Compose apps to be updated.
+func printOrphanedResources(indent int, changes structures.Digraph[*ReconciliationChange]) {
+	sortedChanges := make([]*ReconciliationChange, 0, len(changes))
+	for change := range changes {
+		if len(change.OrphanedNetworks) == 0 && len(change.OrphanedVolumes) == 0 {
+			continue
+		}
+		sortedChanges = append(sortedChanges, change)
+	}
+	if len(sortedChanges) == 0 {
+		return
+	}
+	slices.SortFunc(sortedChanges, func(i, j *ReconciliationChange) int {
+		return cmp.Compare(i.Name, j.Name)
+	})
+
+	IndentedFprintln(indent, os.Stderr, "Cleanup of resources no longer declared by Compose apps:")
+	for _, change := range sortedChanges {
+		IndentedFprintf(indent+1, os.Stderr, "Compose app %s:\n", change.Name)
+		for _, network := range change.OrphanedNetworks {
+			IndentedFprintf(indent+2, os.Stderr, "remove Docker network %s\n", network)
+		}
+		for _, volume := range change.OrphanedVolumes {
+			if change.RemoveOrphanedVolumes {
+				IndentedFprintf(indent+2, os.Stderr, "remove Docker volume %s\n", volume)
+				continue
+			}
+			IndentedFprintf(indent+2, os.Stderr, "keep orphaned Docker volume %s\n", volume)
+		}
+	}
+}
+
+// withoutNetworkDeps returns a copy of the provided dependency reports, but without any Docker
+// network dependencies.
+func withoutNetworkDeps(satisfiedDeps []forklift.SatisfiedDeplDeps) []forklift.SatisfiedDeplDeps {
+	filtered := make([]forklift.SatisfiedDeplDeps, 0, len(satisfiedDeps))
+	for _, satisfied := range satisfiedDeps {
+		satisfied.Networks = nil
+		filtered = append(filtered, satisfied)
+	}
+	return filtered
+}
+
+// resolveNetworkDeps returns a map between the names of deployments and the Docker networks which
+// they require from other deployments. Each network is represented as a map between the name of
+// the network and the name of the deployment which provides it.
+func resolveNetworkDeps(
+	satisfiedDeps []forklift.SatisfiedDeplDeps,
+) (networkDeps map[string]map[string]string) {
+	networkDeps = make(map[string]map[string]string)
+	for _, satisfied := range satisfiedDeps {
+		for _, network := range satisfied.Networks {
+			provider := strings.TrimPrefix(network.Provided.Source[0], "deployment ")
+			if provider == satisfied.Depl.Name { // i.e. the deployment requires a network it provides
+				continue
+			}
+			if _, ok := networkDeps[satisfied.Depl.Name]; !ok {
+				networkDeps[satisfied.Depl.Name] = make(map[string]string)
+			}
+			networkDeps[satisfied.Depl.Name][network.Provided.Res.Name] = provider
+		}
+	}
+	return networkDeps
+}
+
+// getAppFingerprints returns a map between the names of the provided Docker Compose apps and the
+// fingerprints stamped on those apps. Apps without a consistent fingerprint are omitted.
+func getAppFingerprints(
+	ctx context.Context, dc *docker.Client, apps []api.Stack,
+) (map[string]string, error) {
+	fingerprints := make(map[string]string)
+	for _, app := range apps {
+		fingerprint, err := dc.GetAppFingerprint(ctx, app.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't determine fingerprint of Compose app %s", app.Name)
+		}
+		if fingerprint == "" {
+			continue
+		}
+		fingerprints[app.Name] = fingerprint
+	}
+	return fingerprints, nil
+}
+
 func printDigraph[Node comparable, Digraph structures.MapDigraph[Node]](
 	indent int, digraph Digraph, edgeType string,
 ) {
@@ -170,27 +673,41 @@ func printNodeOutboundEdges[Node comparable, Digraph structures.MapDigraph[Node]
 
 // planChanges builds a dependency graph of changes to make to the Docker host (as a plan for
 // concurrent execution), for a given list of resolved package deployments, a precomputed graph of
-// direct dependency relationships between them, and a list of currently active Compose apps.
+// direct dependency relationships between them, a list of currently active Compose apps, the
+// fingerprints of those Compose apps (keyed by app name), and a graph of direct Docker resource
+// dependency relationships among those Compose apps (keyed by app name).
+// If deplNetworkDeps (as returned by resolveNetworkDeps) is non-nil, the Docker networks in it
+// will be created by separate changes, and deplDirectDeps should exclude network dependencies.
+// If selectedApps is non-nil, only changes to the Compose apps named in it will be planned, and all
+// other Compose apps will be left untouched.
 // This function also identifies any cycles in the returned dependency graph.
 // If the serialize arg is set to true, this function will also compute a non-nil sequential order
 // for executing the changes serially (rather than concurrently); otherwise, a nil sequential order
 // will be returned.
 func planChanges(
-	depls []*forklift.ResolvedDepl, deplDirectDeps structures.Digraph[string], apps []api.Stack,
-	serialize bool,
+	depls []*forklift.ResolvedDepl, deplDirectDeps structures.Digraph[string],
+	deplNetworkDeps map[string]map[string]string, apps []api.Stack,
+	appFingerprints map[string]string, appDirectDeps structures.Digraph[string],
+	selectedApps structures.Set[string], serialize bool,
 ) (
 	changeDirectDeps structures.Digraph[*ReconciliationChange], cycles [][]*ReconciliationChange,
 	serialization []*ReconciliationChange, err error,
 ) {
-	// TODO: make a reconciliation plan where relevant resources (i.e. Docker networks) are created
-	// simultaneously/independently so that circular dependencies for those resources won't prevent
-	// successful application.
-	changes, err := identifyReconciliationChanges(depls, apps)
+	changes, err := identifyReconciliationChanges(depls, apps, appFingerprints, deplNetworkDeps)
 	if err != nil {
 		return nil, nil, nil, errors.Wrap(err, "couldn't identify the changes to make")
 	}
+	if selectedApps != nil {
+		changes = slices.DeleteFunc(changes, func(change *ReconciliationChange) bool {
+			appName := change.Name
+			if change.Type == createNetworkReconciliationChange {
+				appName = forklift.GetComposeAppName(change.Depl.Name)
+			}
+			return !selectedApps.Has(appName)
+		})
+	}
 
-	changeDirectDeps = computeChangeDeps(changes, deplDirectDeps)
+	changeDirectDeps = computeChangeDeps(changes, deplDirectDeps, deplNetworkDeps, appDirectDeps)
 	changeIndirectDeps := changeDirectDeps.ComputeTransitiveClosure()
 	cycles = changeIndirectDeps.IdentifyCycles()
 	if !serialize {
@@ -207,8 +724,15 @@ func planChanges(
 
 // identifyReconciliationChanges builds an arbitrarily-ordered list of changes to carry out to
 // reconcile the desired list of deployments with the actual list of active Docker Compose apps.
+// Active apps whose fingerprints (in the provided map keyed by app name) match the fingerprints of
+// their desired definitions are identified as no-op changes. Every Docker network in
+// deplNetworkDeps (as returned by resolveNetworkDeps) which is defined by the Compose app of its
+// provider is identified as a network to be created. Every active app which doesn't match a
+// deployment is identified as an app to be removed, so apps which Forklift must leave untouched
+// should already have been excluded from the list of active apps (e.g. by filterUnownedApps).
 func identifyReconciliationChanges(
-	depls []*forklift.ResolvedDepl, apps []api.Stack,
+	depls []*forklift.ResolvedDepl, apps []api.Stack, appFingerprints map[string]string,
+	deplNetworkDeps map[string]map[string]string,
 ) ([]*ReconciliationChange, error) {
 	deplsByName := make(map[string]*forklift.ResolvedDepl)
 	for _, depl := range depls {
@@ -223,20 +747,40 @@ func identifyReconciliationChanges(
 		return nil, err
 	}
 
+	networkProviders := make(map[string]string) // network name -> depl name
+	for _, networks := range deplNetworkDeps {
+		for network, provider := range networks {
+			networkProviders[network] = provider
+		}
+	}
+
 	appDeplNames := make(map[string]string)
 	changes := make([]*ReconciliationChange, 0, len(depls)+len(apps))
 	for name, depl := range deplsByName {
-		appDeplNames[forklift.GetComposeAppName(name)] = name
-		app, ok := appsByName[forklift.GetComposeAppName(name)]
-		if !ok {
-			if composeAppDefinerSet.Has(name) {
-				changes = append(changes, newAddReconciliationChange(name, depl))
+		appName := forklift.GetComposeAppName(name)
+		appDeplNames[appName] = name
+		if !composeAppDefinerSet.Has(name) {
+			continue
+		}
+		appDef, fingerprint, err := loadFingerprintedComposeApp(depl)
+		if err != nil {
+			return nil, err
+		}
+		for network, provider := range networkProviders {
+			if provider == name && docker.FindAppNetwork(appDef, network) != "" {
+				changes = append(changes, newCreateNetworkReconciliationChange(network, depl))
 			}
+		}
+		app, ok := appsByName[appName]
+		if !ok {
+			changes = append(changes, newAddReconciliationChange(name, depl, fingerprint))
 			continue
 		}
-		if composeAppDefinerSet.Has(name) {
-			changes = append(changes, newUpdateReconciliationChange(name, depl, app))
+		if appFingerprints[appName] == fingerprint {
+			changes = append(changes, newNoOpReconciliationChange(name, depl, app, fingerprint))
+			continue
 		}
+		changes = append(changes, newUpdateReconciliationChange(name, depl, app, fingerprint))
 	}
 	for name, app := range appsByName {
 		if deplName, ok := appDeplNames[name]; ok {
@@ -249,6 +793,26 @@ func identifyReconciliationChanges(
 	return changes, nil
 }
 
+// loadFingerprintedComposeApp loads the Compose app definition of the deployment, with each service
+// stamped with a fingerprint of its definition. It also returns a fingerprint of the whole app.
+func loadFingerprintedComposeApp(
+	depl *forklift.ResolvedDepl,
+) (appDef *dct.Project, fingerprint string, err error) {
+	if appDef, err = depl.LoadComposeAppDefinition(true); err != nil {
+		return nil, "", errors.Wrapf(
+			err, "couldn't load Compose app definition of deployment %s", depl.Name,
+		)
+	}
+	fingerprint, serviceFingerprints, err := docker.ComputeAppFingerprint(appDef)
+	if err != nil {
+		return nil, "", errors.Wrapf(
+			err, "couldn't compute fingerprint of Compose app definition of deployment %s", depl.Name,
+		)
+	}
+	docker.StampAppFingerprint(appDef, serviceFingerprints)
+	return appDef, fingerprint, nil
+}
+
 // identifyComposeAppDefiners builds a set of the names of deployments which define Compose apps.
 func identifyComposeAppDefiners(
 	depls map[string]*forklift.ResolvedDepl,
@@ -269,43 +833,106 @@ func identifyComposeAppDefiners(
 }
 
 // computeChangeDeps produces a dependency graph of changes to make on the Docker host based on the
-// desired list of deployments, a graph of direct dependencies among those deployments, and a list
-// of Docker Compose apps describing the current complete state of the Docker host. The returned
-// dependency graph is a map between each reconciliation change and the respective set of any other
-// reconciliation changes which must be completed first.
+// desired list of deployments, a graph of direct dependencies among those deployments, a map of
+// the Docker networks required by those deployments (as returned by resolveNetworkDeps, or nil if
+// networks aren't created by separate changes), and a graph of direct Docker resource dependencies
+// among the Docker Compose apps currently on the Docker host. The returned dependency graph is a
+// map between each reconciliation change and the respective set of any other reconciliation
+// changes which must be completed first.
 func computeChangeDeps(
-	changes []*ReconciliationChange, directDeps structures.Digraph[string],
+	changes []*ReconciliationChange, deplDirectDeps structures.Digraph[string],
+	deplNetworkDeps map[string]map[string]string, appDirectDeps structures.Digraph[string],
 ) structures.Digraph[*ReconciliationChange] {
 	removalChanges := make(map[string]*ReconciliationChange)    // keyed by app name
+	networkChanges := make(map[string]*ReconciliationChange)    // keyed by network name
 	nonremovalChanges := make(map[string]*ReconciliationChange) // keyed by depl name
 	graph := make(structures.Digraph[*ReconciliationChange])
 	for _, change := range changes {
 		graph.AddNode(change)
-		if change.Type == removeReconciliationChange {
+		switch change.Type {
+		case removeReconciliationChange:
 			removalChanges[change.Name] = change
 			continue
+		case createNetworkReconciliationChange:
+			networkChanges[change.Name] = change
+			continue
 		}
 		nonremovalChanges[change.Depl.Name] = change
 	}
-	// FIXME: ideally we would order the removal changes based on dependency relationships between
-	// the Compose apps, e.g. with networks. With removals we don't have deployments which would
-	// tell us about Docker resource dependency relationships, so we'd need to determine this from
-	// Docker. If app r depends on a resource provided by app s, then app r must be removed first -
-	// so the removal of app s depends upon the removal of app r.
 	// Remove old resources first, in case additions/updates would add overlapping resources.
-	for _, change := range nonremovalChanges {
-		for _, removalChange := range removalChanges {
+	for _, removalChange := range removalChanges {
+		for _, change := range nonremovalChanges {
+			graph.AddEdge(change, removalChange)
+		}
+		for _, change := range networkChanges {
 			graph.AddEdge(change, removalChange)
 		}
 	}
+	// Create networks before their providers are added/updated. Deployments which require those
+	// networks then only need to wait for the networks to be created, rather than for their
+	// providers.
+	for _, networkChange := range networkChanges {
+		if provider, ok := nonremovalChanges[networkChange.Depl.Name]; ok {
+			graph.AddEdge(provider, networkChange)
+		}
+	}
 	for _, dependent := range nonremovalChanges {
-		for deplName := range directDeps[dependent.Depl.Name] {
+		for network, deplName := range deplNetworkDeps[dependent.Depl.Name] {
+			if networkChange, ok := networkChanges[network]; ok {
+				graph.AddEdge(dependent, networkChange)
+				continue
+			}
+			// The network can't be created separately, so we must wait for its provider instead
 			if dependency, ok := nonremovalChanges[deplName]; ok {
 				graph.AddEdge(dependent, dependency)
 			}
 		}
 	}
+	for dependent, dependencies := range computeRemovalDeps(removalChanges, appDirectDeps) {
+		for dependency := range dependencies {
+			graph.AddEdge(dependent, dependency)
+		}
+	}
+	for _, dependent := range nonremovalChanges {
+		for deplName := range deplDirectDeps[dependent.Depl.Name] {
+			if dependency, ok := nonremovalChanges[deplName]; ok {
+				graph.AddEdge(dependent, dependency)
+			}
+		}
+	}
+
+	return graph
+}
+
+// computeRemovalDeps produces a dependency graph among the provided removal changes (keyed by app
+// name), based on a graph of direct Docker resource dependencies among Docker Compose apps. If app
+// r depends on a resource provided by app s, then app r must be removed first - so the removal of
+// app s depends upon the removal of app r. Dependency relationships among apps in a dependency
+// cycle are ignored, so that Docker resource dependency cycles won't prevent concurrent removal of
+// the apps.
+func computeRemovalDeps(
+	removalChanges map[string]*ReconciliationChange, appDirectDeps structures.Digraph[string],
+) structures.Digraph[*ReconciliationChange] {
+	removalAppDeps := make(structures.Digraph[string])
+	for appName := range removalChanges {
+		removalAppDeps.AddNode(appName)
+		for dependency := range appDirectDeps[appName] {
+			if _, ok := removalChanges[dependency]; ok {
+				removalAppDeps.AddEdge(appName, dependency)
+			}
+		}
+	}
+	removalAppIndirectDeps := removalAppDeps.ComputeTransitiveClosure()
 
+	graph := make(structures.Digraph[*ReconciliationChange])
+	for dependent, dependencies := range removalAppDeps {
+		for dependency := range dependencies {
+			if removalAppIndirectDeps.HasEdge(dependency, dependent) { // i.e. they're in a cycle
+				continue
+			}
+			graph.AddEdge(removalChanges[dependency], removalChanges[dependent])
+		}
+	}
 	return graph
 }
 
@@ -329,7 +956,9 @@ func compareChangesTotal(
 
 	// Compare by names as a last resort
 	if r.Depl != nil && s.Depl != nil {
-		return compareDeplNames(r.Depl.Name, s.Depl.Name)
+		if result := compareDeplNames(r.Depl.Name, s.Depl.Name); result != core.CompareEQ {
+			return result
+		}
 	}
 	return compareDeplNames(r.Name, s.Name)
 }
@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/docker/compose/v2/pkg/api"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	"github.com/forklift-run/forklift/pkg/core"
+	"github.com/forklift-run/forklift/pkg/structures"
+)
+
+func TestSelectApps(t *testing.T) {
+	depls := []*forklift.ResolvedDepl{
+		{Depl: forklift.Depl{Name: "infra/caddy"}},
+		{Depl: forklift.Depl{Name: "apps/web"}},
+		{Depl: forklift.Depl{Name: "apps/db"}},
+		{Depl: forklift.Depl{Name: "apps/docs"}},
+	}
+	deps := make(structures.Digraph[string])
+	deps.AddEdge("apps/web", "apps/db")
+	deps.AddEdge("apps/db", "infra/caddy")
+	apps := []api.Stack{{Name: "apps_web"}, {Name: "old_app"}}
+
+	for _, test := range []struct {
+		name     string
+		only     []string
+		exclude  []string
+		expected []string
+	}{
+		{
+			name:     "everything",
+			expected: []string{"apps_db", "apps_docs", "apps_web", "infra_caddy", "old_app"},
+		},
+		{
+			name:     "only with transitive dependencies",
+			only:     []string{"apps/web"},
+			expected: []string{"apps_db", "apps_web", "infra_caddy"},
+		},
+		{
+			name:     "only without dependencies",
+			only:     []string{"apps/docs"},
+			expected: []string{"apps_docs"},
+		},
+		{
+			name:     "only an active Compose app without a deployment",
+			only:     []string{"old_app"},
+			expected: []string{"old_app"},
+		},
+		{
+			name:     "exclude",
+			exclude:  []string{"apps/docs", "old_app"},
+			expected: []string{"apps_db", "apps_web", "infra_caddy"},
+		},
+		{
+			name:     "exclude a dependency of only",
+			only:     []string{"apps/web"},
+			exclude:  []string{"apps/db"},
+			expected: []string{"apps_web", "infra_caddy"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			selected, err := selectApps(depls, deps, apps, test.only, test.exclude)
+			if err != nil {
+				t.Fatalf("couldn't select apps: %s", err)
+			}
+			if actual := slices.Sorted(selected.All()); !slices.Equal(actual, test.expected) {
+				t.Errorf("expected selected apps %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSelectAppsUnknown(t *testing.T) {
+	depls := []*forklift.ResolvedDepl{{Depl: forklift.Depl{Name: "apps/web"}}}
+	deps := make(structures.Digraph[string])
+	for _, test := range []struct {
+		name    string
+		only    []string
+		exclude []string
+	}{
+		{name: "only", only: []string{"apps/missing"}},
+		{name: "exclude", exclude: []string{"apps/missing"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := selectApps(depls, deps, nil, test.only, test.exclude); err == nil {
+				t.Error("expected an error for an unknown deployment")
+			}
+		})
+	}
+}
+
+// describeEdges returns a sorted list of descriptions of the edges of the graph of changes.
+func describeEdges(graph structures.Digraph[*ReconciliationChange]) []string {
+	edges := make([]string, 0, len(graph))
+	for from, tos := range graph {
+		for to := range tos {
+			edges = append(edges, fmt.Sprintf("%s -> %s", from, to))
+		}
+	}
+	slices.Sort(edges)
+	return edges
+}
+
+func TestComputeRemovalDeps(t *testing.T) {
+	removals := make(map[string]*ReconciliationChange)
+	for _, name := range []string{"web", "db", "volumes", "left", "right", "client"} {
+		removals[name] = newRemoveReconciliationChange(name, api.Stack{Name: name})
+	}
+	appDeps := make(structures.Digraph[string])
+	appDeps.AddEdge("web", "db")
+	appDeps.AddEdge("db", "volumes")
+	appDeps.AddEdge("left", "right")
+	appDeps.AddEdge("right", "left")
+	appDeps.AddEdge("client", "server") // server isn't being removed
+
+	expected := []string{
+		"(remove db) -> (remove web)",
+		"(remove volumes) -> (remove db)",
+	}
+	actual := describeEdges(computeRemovalDeps(removals, appDeps))
+	if !slices.Equal(actual, expected) {
+		t.Errorf("expected removal ordering %v, got %v", expected, actual)
+	}
+}
+
+func TestComputeChangeDepsPrecreatedNetworks(t *testing.T) {
+	// The proxy provides a network used by the web app, and the proxy requires a service provided by
+	// the web app:
+	proxy := &forklift.ResolvedDepl{Depl: forklift.Depl{Name: "proxy"}}
+	web := &forklift.ResolvedDepl{Depl: forklift.Depl{Name: "web"}}
+	satisfiedDeps := []forklift.SatisfiedDeplDeps{
+		{
+			Depl: web,
+			Networks: []core.SatisfiedResDep[core.NetworkRes]{{
+				Required: core.AttachedRes[core.NetworkRes]{Res: core.NetworkRes{Name: "proxy-net"}},
+				Provided: core.AttachedRes[core.NetworkRes]{
+					Res: core.NetworkRes{Name: "proxy-net"}, Source: []string{"deployment proxy"},
+				},
+			}},
+		},
+		{
+			Depl: proxy,
+			Services: []core.SatisfiedResDep[core.ServiceRes]{{
+				Provided: core.AttachedRes[core.ServiceRes]{Source: []string{"deployment web"}},
+			}},
+		},
+	}
+
+	for _, test := range []struct {
+		name      string
+		precreate bool
+		expected  []string
+		cycles    int
+	}{
+		{
+			name: "without precreated networks",
+			expected: []string{
+				"(add proxy) -> (add web)",
+				"(add web) -> (add proxy)",
+			},
+			cycles: 1,
+		},
+		{
+			name:      "with precreated networks",
+			precreate: true,
+			expected: []string{
+				"(add proxy) -> (add web)",
+				"(add proxy) -> (create-network proxy-net)",
+				"(add web) -> (create-network proxy-net)",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			changes := []*ReconciliationChange{
+				newAddReconciliationChange("proxy", proxy, ""),
+				newAddReconciliationChange("web", web, ""),
+			}
+			deps := forklift.ResolveDeps(satisfiedDeps, true)
+			var networkDeps map[string]map[string]string
+			if test.precreate {
+				deps = forklift.ResolveDeps(withoutNetworkDeps(satisfiedDeps), true)
+				networkDeps = resolveNetworkDeps(satisfiedDeps)
+				changes = append(changes, newCreateNetworkReconciliationChange("proxy-net", proxy))
+			}
+
+			graph := computeChangeDeps(changes, deps, networkDeps, nil)
+			if actual := describeEdges(graph); !slices.Equal(actual, test.expected) {
+				t.Errorf("expected ordering relationships %v, got %v", test.expected, actual)
+			}
+			if cycles := graph.ComputeTransitiveClosure().IdentifyCycles(); len(cycles) != test.cycles {
+				t.Errorf("expected %d ordering cycles, got %v", test.cycles, cycles)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	"github.com/forklift-run/forklift/internal/clients/docker"
+	"github.com/forklift-run/forklift/pkg/structures"
+)
+
+// ReconcileBundle updates the Docker host to match the bundle, without recording anything in the
+// stage store. Any Compose app which has missing or stopped containers will be updated, even if
+// its definition hasn't changed. No new changes will be started after ctx is canceled, and changes
+// in progress will be aborted if changeCtx is canceled.
+func ReconcileBundle(
+	ctx, changeCtx context.Context, indent int, bundle *forklift.FSBundle, opts ApplyOptions,
+) error {
+	opts.Refresh = true
+	_, _, err := applyBundle(ctx, changeCtx, indent, bundle, opts)
+	return err
+}
+
+// WatchOptions controls how the Docker host is continuously reconciled with a bundle.
+type WatchOptions struct {
+	// SettleDelay is how long to wait after a container stops before checking whether Docker has
+	// restarted it (or will restart it) according to its restart policy.
+	SettleDelay time.Duration
+	// MinInterval is the minimum amount of time between the starts of consecutive reconciliations.
+	MinInterval time.Duration
+	// MaxBackoff is the maximum amount of time to wait before retrying a failed reconciliation. The
+	// delay starts at MinInterval and doubles after each consecutive failure.
+	MaxBackoff time.Duration
+}
+
+// A BundleLoader loads the bundle which the Docker host should be reconciled with.
+type BundleLoader func() (*forklift.FSBundle, error)
+
+// WatchAndReconcile watches Docker for containers of Compose apps created by Forklift which stop
+// permanently or are removed, and then reconciles just the affected deployments (and any
+// deployments which they require) with the bundle, until ctx is canceled. The bundle is loaded
+// again for each reconciliation, so that the Docker host is always reconciled with the latest
+// bundle (e.g. after another staged pallet bundle is applied). Reconciliations in progress will be
+// aborted if changeCtx is canceled.
+func WatchAndReconcile(
+	ctx, changeCtx context.Context, indent int, loadBundle BundleLoader, opts ApplyOptions,
+	watchOpts WatchOptions,
+) error {
+	dc, err := docker.NewClient()
+	if err != nil {
+		return errors.Wrap(err, "couldn't make Docker API client")
+	}
+
+	events, watchErrs := dc.WatchOwnedAppEvents(ctx)
+	IndentedFprintln(indent, os.Stderr, "Watching for containers which stop or are removed...")
+	pending := make(map[string][]string)   // app name -> container IDs
+	forced := make(structures.Set[string]) // apps to reconcile even if no containers are down
+	var (
+		timer          <-chan time.Time
+		lastStart      time.Time
+		lastEnd        time.Time
+		failures       int
+		reconcileDelay = watchOpts.MinInterval
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watchErrs:
+			return err
+		case event, ok := <-events:
+			if !ok {
+				// The events channel is closed after any error is sent on the error channel:
+				select {
+				case err := <-watchErrs:
+					return err
+				default:
+				}
+				if ctx.Err() != nil {
+					return nil
+				}
+				return errors.New("the stream of Docker events ended unexpectedly")
+			}
+			if !lastEnd.IsZero() && event.Time.After(lastStart) && !event.Time.After(lastEnd) {
+				// The event was caused by the previous reconciliation
+				continue
+			}
+			IndentedFprintf(
+				indent, os.Stderr, "Container %.12s of Compose app %s (service %s) had event: %s\n",
+				event.ContainerID, event.App, event.Service, event.Action,
+			)
+			pending[event.App] = append(pending[event.App], event.ContainerID)
+			if timer == nil {
+				timer = time.After(max(
+					watchOpts.SettleDelay, time.Until(lastStart.Add(reconcileDelay)),
+				))
+			}
+		case <-timer:
+			timer = nil
+			down, err := identifyDownApps(ctx, dc, pending)
+			if err != nil {
+				// We can't tell which apps are down, so we'll just reconcile all of them on the retry:
+				for appName := range pending {
+					forced.Add(appName)
+				}
+				clear(pending)
+				if ctx.Err() != nil {
+					return nil
+				}
+				failures++
+				reconcileDelay = backoffDelay(watchOpts.MinInterval, watchOpts.MaxBackoff, failures)
+				IndentedFprintf(
+					indent, os.Stderr,
+					"Error: couldn't check which Compose apps are down (will retry in %s): %s\n",
+					reconcileDelay, err.Error(),
+				)
+				timer = time.After(reconcileDelay)
+				continue
+			}
+			for _, appName := range down {
+				forced.Add(appName)
+			}
+			clear(pending)
+			if len(forced) == 0 {
+				continue
+			}
+
+			fmt.Fprintln(os.Stderr)
+			lastStart = time.Now()
+			affected, err := reconcileApps(ctx, changeCtx, indent, loadBundle, opts, forced)
+			lastEnd = time.Now()
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err == nil {
+				clear(forced)
+				failures = 0
+				reconcileDelay = watchOpts.MinInterval
+				if len(affected) > 0 {
+					IndentedFprintln(indent, os.Stderr, "Reconciled deployments!")
+				}
+				continue
+			}
+
+			failures++
+			reconcileDelay = backoffDelay(watchOpts.MinInterval, watchOpts.MaxBackoff, failures)
+			IndentedFprintf(
+				indent, os.Stderr, "Error: couldn't reconcile deployments (will retry in %s): %s\n",
+				reconcileDelay, err.Error(),
+			)
+			timer = time.After(reconcileDelay)
+		}
+	}
+}
+
+// reconcileApps loads the bundle and reconciles the deployments (and any deployments which they
+// require) of the bundle which correspond to the specified Compose apps, returning the names of
+// those deployments. Compose apps which don't correspond to any enabled deployment of the bundle
+// are ignored.
+func reconcileApps(
+	ctx, changeCtx context.Context, indent int, loadBundle BundleLoader, opts ApplyOptions,
+	apps structures.Set[string],
+) (affected []string, err error) {
+	bundle, err := loadBundle()
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't load the bundle to reconcile with")
+	}
+	depls, err := bundle.LoadDepls("**/*")
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't load package deployments of the bundle")
+	}
+	for _, depl := range forklift.FilterDeplsForEnabled(depls) {
+		if apps.Has(forklift.GetComposeAppName(depl.Name)) {
+			affected = append(affected, depl.Name)
+		}
+	}
+	if len(affected) == 0 {
+		return nil, nil
+	}
+	slices.Sort(affected)
+
+	IndentedFprintf(indent, os.Stderr, "Reconciling deployments: %+v\n", affected)
+	reconcileOpts := opts
+	reconcileOpts.Only = affected
+	return affected, ReconcileBundle(ctx, changeCtx, indent+1, bundle, reconcileOpts)
+}
+
+// identifyDownApps returns the names of Compose apps (from the provided map of app names to
+// container IDs) with any container which is down.
+func identifyDownApps(
+	ctx context.Context, dc *docker.Client, containers map[string][]string,
+) ([]string, error) {
+	down := make([]string, 0, len(containers))
+	for appName, ids := range containers {
+		for _, id := range ids {
+			isDown, err := dc.IsContainerDown(ctx, id)
+			if err != nil {
+				return nil, errors.Wrapf(
+					err, "couldn't check status of container %.12s of Compose app %s", id, appName,
+				)
+			}
+			if isDown {
+				down = append(down, appName)
+				break
+			}
+		}
+	}
+	return down, nil
+}
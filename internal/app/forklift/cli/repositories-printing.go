@@ -12,8 +12,16 @@ import (
 )
 
 func FprintCachedRepo(
-	indent int, out io.Writer, cache core.Pather, repo *core.FSRepo, printHeader bool,
+	indent int, out io.Writer, format string, cache core.Pather, repo *core.FSRepo, printHeader bool,
 ) error {
+	if IsStructuredOutput(format) {
+		doc, err := newRepoDocument(repo)
+		if err != nil {
+			return err
+		}
+		return FprintDocument(out, format, doc)
+	}
+
 	if printHeader {
 		IndentedFprintf(indent, out, "Cached repo: %s\n", repo.Path())
 		indent++
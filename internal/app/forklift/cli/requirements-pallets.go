@@ -71,7 +71,9 @@ func GetRequiredPallet(
 
 // Printing
 
-func FprintRequiredPallets(indent int, out io.Writer, pallet *forklift.FSPallet) error {
+func FprintRequiredPallets(
+	indent int, out io.Writer, format string, pallet *forklift.FSPallet,
+) error {
 	loadedPallets, err := pallet.LoadFSPalletReqs("**")
 	if err != nil {
 		return errors.Wrapf(err, "couldn't identify pallets")
@@ -79,6 +81,13 @@ func FprintRequiredPallets(indent int, out io.Writer, pallet *forklift.FSPallet)
 	slices.SortFunc(loadedPallets, func(a, b *forklift.FSPalletReq) int {
 		return forklift.CompareGitRepoReqs(a.PalletReq.GitRepoReq, b.PalletReq.GitRepoReq)
 	})
+	if IsStructuredOutput(format) {
+		docs := make([]GitRepoReqDocument, 0, len(loadedPallets))
+		for _, req := range loadedPallets {
+			docs = append(docs, newGitRepoReqDocument(req.PalletReq.GitRepoReq))
+		}
+		return FprintDocument(out, format, docs)
+	}
 	for _, pallet := range loadedPallets {
 		IndentedFprintf(indent, out, "%s\n", pallet.Path())
 	}
@@ -86,7 +95,7 @@ func FprintRequiredPallets(indent int, out io.Writer, pallet *forklift.FSPallet)
 }
 
 func FprintRequiredPalletInfo(
-	indent int, out io.Writer,
+	indent int, out io.Writer, format string,
 	pallet *forklift.FSPallet, cache forklift.PathedPalletCache, requiredPalletPath string,
 ) error {
 	req, err := pallet.LoadFSPalletReq(requiredPalletPath)
@@ -96,9 +105,6 @@ func FprintRequiredPalletInfo(
 			requiredPalletPath, pallet.FS.Path(),
 		)
 	}
-	fprintPalletReq(indent, out, req.PalletReq)
-	indent++
-
 	version := req.VersionLock.Version
 	cachedPallet, err := cache.LoadFSPallet(requiredPalletPath, version)
 	if err != nil {
@@ -115,7 +121,16 @@ func FprintRequiredPalletInfo(
 			cachedPallet.Path(),
 		)
 	}
-	return FprintCachedPallet(indent, out, cache, mergedPallet, false)
+	if IsStructuredOutput(format) {
+		doc := RequiredPalletDocument{Req: newGitRepoReqDocument(req.PalletReq.GitRepoReq)}
+		if doc.Pallet, err = newPalletDocument(mergedPallet, true); err != nil {
+			return err
+		}
+		return FprintDocument(out, format, doc)
+	}
+	fprintPalletReq(indent, out, req.PalletReq)
+	indent++
+	return FprintCachedPallet(indent, out, format, cache, mergedPallet, false)
 }
 
 func fprintPalletReq(indent int, out io.Writer, req forklift.PalletReq) {
@@ -83,7 +83,9 @@ func makeRepoOverrideCacheFromPallet(
 
 // Printing
 
-func FprintRequiredRepos(indent int, out io.Writer, pallet *forklift.FSPallet) error {
+func FprintRequiredRepos(
+	indent int, out io.Writer, format string, pallet *forklift.FSPallet,
+) error {
 	loadedRepos, err := pallet.LoadFSRepoReqs("**")
 	if err != nil {
 		return errors.Wrapf(err, "couldn't identify repos")
@@ -91,6 +93,13 @@ func FprintRequiredRepos(indent int, out io.Writer, pallet *forklift.FSPallet) e
 	slices.SortFunc(loadedRepos, func(a, b *forklift.FSRepoReq) int {
 		return forklift.CompareGitRepoReqs(a.RepoReq.GitRepoReq, b.RepoReq.GitRepoReq)
 	})
+	if IsStructuredOutput(format) {
+		docs := make([]GitRepoReqDocument, 0, len(loadedRepos))
+		for _, req := range loadedRepos {
+			docs = append(docs, newGitRepoReqDocument(req.RepoReq.GitRepoReq))
+		}
+		return FprintDocument(out, format, docs)
+	}
 	for _, repo := range loadedRepos {
 		IndentedFprintf(indent, out, "%s\n", repo.Path())
 	}
@@ -121,7 +130,7 @@ func FprintRequiredRepoLocation(
 }
 
 func FprintRequiredRepoInfo(
-	indent int, out io.Writer,
+	indent int, out io.Writer, format string,
 	pallet *forklift.FSPallet, cache forklift.PathedRepoCache, requiredRepoPath string,
 ) error {
 	req, err := pallet.LoadFSRepoReq(requiredRepoPath)
@@ -131,9 +140,6 @@ func FprintRequiredRepoInfo(
 			requiredRepoPath, pallet.FS.Path(),
 		)
 	}
-	fprintRepoReq(indent, out, req.RepoReq)
-	indent++
-
 	version := req.VersionLock.Version
 	cachedRepo, err := cache.LoadFSRepo(requiredRepoPath, version)
 	if err != nil {
@@ -142,7 +148,16 @@ func FprintRequiredRepoInfo(
 			requiredRepoPath, version,
 		)
 	}
-	return FprintCachedRepo(indent, out, cache, cachedRepo, false)
+	if IsStructuredOutput(format) {
+		doc := RequiredRepoDocument{Req: newGitRepoReqDocument(req.RepoReq.GitRepoReq)}
+		if doc.Repo, err = newRepoDocument(cachedRepo); err != nil {
+			return err
+		}
+		return FprintDocument(out, format, doc)
+	}
+	fprintRepoReq(indent, out, req.RepoReq)
+	indent++
+	return FprintCachedRepo(indent, out, format, cache, cachedRepo, false)
 }
 
 func fprintRepoReq(indent int, out io.Writer, req forklift.RepoReq) {
@@ -0,0 +1,887 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	"github.com/forklift-run/forklift/internal/clients/docker"
+	"github.com/forklift-run/forklift/pkg/core"
+	"github.com/forklift-run/forklift/pkg/structures"
+)
+
+const (
+	// SBOMFormatCycloneDX is the SBOM format of CycloneDX JSON documents.
+	SBOMFormatCycloneDX = "cyclonedx"
+	// SBOMFormatSPDX is the SBOM format of SPDX JSON documents.
+	SBOMFormatSPDX = "spdx"
+)
+
+const (
+	bomPallet = "pallet"
+	bomRepo   = "repository"
+	bomPkg    = "package"
+	bomImage  = "image"
+	bomFile   = "file"
+)
+
+// A bom is a software bill of materials for a pallet, to be rendered in a standard SBOM format.
+type bom struct {
+	// toolVersion is the version of Forklift which generated the bill of materials.
+	toolVersion string
+	// root is the component for the pallet described by the bill of materials.
+	root bomComponent
+	// components is the list of all other components in the bill of materials, sorted by ref.
+	components []bomComponent
+	// dependencies maps the ref of each component to the refs of the components which it depends on.
+	dependencies map[string]structures.Set[string]
+}
+
+// A bomComponent is a component in a software bill of materials.
+type bomComponent struct {
+	// ref identifies the component within the bill of materials.
+	ref string
+	// kind is the type of the component (e.g. pallet, repository, package, image, or file).
+	kind        string
+	name        string
+	version     string
+	commit      string
+	description string
+	// license is the license of the component, if known. It should be an SPDX license expression,
+	// but it might be free-form text (see [isSPDXLicenseExpression]).
+	license     string
+	maintainers []core.PkgMaintainer
+	// sources is a list of URLs providing the source code of the component.
+	sources []string
+	// url is the URL from which the component can be downloaded, if any.
+	url string
+	// digest is the content digest (e.g. sha256:...) of the component, if known.
+	digest string
+	// properties are Forklift-specific properties of the component.
+	properties map[string]string
+}
+
+// FprintBundleSBOM prints a software bill of materials for the pallet bundle in the specified SBOM
+// format, resolving the digests of container images from Docker's local image store where
+// possible.
+func FprintBundleSBOM(
+	indent int, out io.Writer, format string, bundle *forklift.FSBundle, toolVersion string,
+) error {
+	if err := checkSBOMFormat(format); err != nil {
+		return err
+	}
+	pkgSpecs := make(map[string]core.PkgSpec)
+	for deplName, depl := range bundle.Manifest.Deploys {
+		if _, ok := pkgSpecs[depl.Package]; ok {
+			continue
+		}
+		pkg, err := bundle.LoadFSPkg(depl.Package, "")
+		if err != nil {
+			return errors.Wrapf(
+				err, "couldn't load package %s for deployment %s from bundle", depl.Package, deplName,
+			)
+		}
+		pkgSpecs[depl.Package] = pkg.Def.Package
+	}
+	b := newBOM(bundle.Manifest, pkgSpecs, toolVersion)
+	resolveImageDigests(indent, &b)
+	return fprintBOM(out, format, b)
+}
+
+// FprintPalletSBOM prints a software bill of materials for the pallet in the specified SBOM
+// format, describing the pallet as it would be described if it were bundled.
+func FprintPalletSBOM(
+	indent int, out io.Writer, format string, merged *forklift.FSPallet,
+	palletCache forklift.PathedPalletCache, repoCache forklift.PathedRepoCache, toolVersion string,
+) error {
+	if err := checkSBOMFormat(format); err != nil {
+		return err
+	}
+	manifest, err := newBundleManifest(merged, palletCache, repoCache, toolVersion)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't describe pallet %s", merged.Path())
+	}
+	depls, _, err := Check(indent, merged, repoCache)
+	if err != nil {
+		return errors.Wrap(err, "couldn't ensure pallet validity")
+	}
+	pkgSpecs := make(map[string]core.PkgSpec)
+	for _, depl := range depls {
+		manifest.Deploys[depl.Name] = depl.Def
+		pkgSpecs[depl.Def.Package] = depl.Pkg.Def.Package
+		if manifest.Downloads[depl.Name], err = listDeplDownloads(depl); err != nil {
+			return err
+		}
+	}
+	b := newBOM(manifest, pkgSpecs, toolVersion)
+	resolveImageDigests(indent, &b)
+	return fprintBOM(out, format, b)
+}
+
+func checkSBOMFormat(format string) error {
+	if format != SBOMFormatCycloneDX && format != SBOMFormatSPDX {
+		return errors.Errorf("unknown SBOM format '%s' (must be cyclonedx or spdx)", format)
+	}
+	return nil
+}
+
+// listDeplDownloads lists the resources which would be downloaded for the deployment if it were
+// bundled, including the container images used by its Compose app.
+func listDeplDownloads(
+	depl *forklift.ResolvedDepl,
+) (downloads forklift.BundleDeplDownloads, err error) {
+	if downloads.HTTPFile, err = depl.GetHTTPFileDownloadURLs(); err != nil {
+		return downloads, errors.Wrapf(
+			err, "couldn't determine HTTP file downloads for deployment %s", depl.Name,
+		)
+	}
+	images := make(structures.Set[string])
+	ociImages, err := depl.GetOCIImageDownloadNames()
+	if err != nil {
+		return downloads, errors.Wrapf(
+			err, "couldn't determine OCI image downloads for deployment %s", depl.Name,
+		)
+	}
+	images.Add(ociImages...)
+	definesComposeApp, err := depl.DefinesComposeApp()
+	if err != nil {
+		return downloads, errors.Wrapf(
+			err, "couldn't check deployment %s for a Compose app", depl.Name,
+		)
+	}
+	if definesComposeApp {
+		appDef, err := depl.LoadComposeAppDefinition(false)
+		if err != nil {
+			return downloads, errors.Wrapf(
+				err, "couldn't load Compose app definition of deployment %s", depl.Name,
+			)
+		}
+		for _, service := range appDef.Services {
+			images.Add(service.Image)
+		}
+	}
+	downloads.OCIImage = slices.Sorted(images.All())
+	return downloads, nil
+}
+
+// Bill of materials
+
+// newBOM builds a bill of materials from the bundle manifest and the specs of the packages
+// deployed by the bundle (keyed by package path).
+func newBOM(
+	manifest forklift.BundleManifest, pkgSpecs map[string]core.PkgSpec, toolVersion string,
+) bom {
+	b := bom{
+		toolVersion: toolVersion,
+		root: bomComponent{
+			kind:        bomPallet,
+			name:        manifest.Pallet.Path,
+			version:     manifest.Pallet.Version,
+			commit:      manifest.Pallet.Commit,
+			description: manifest.Pallet.Description,
+			url:         "https://" + manifest.Pallet.Path,
+			properties: map[string]string{
+				"forklift:clean": fmt.Sprint(manifest.Pallet.Clean),
+			},
+		},
+		dependencies: make(map[string]structures.Set[string]),
+	}
+	b.root.ref = newBOMRef(b.root.kind, b.root.name, b.root.version)
+	components := make(map[string]bomComponent)
+	addComponent := func(dependent string, component bomComponent) {
+		if component.ref == "" {
+			component.ref = newBOMRef(component.kind, component.name, component.version)
+		}
+		prev, ok := components[component.ref]
+		if _, hasDepls := prev.properties["forklift:deployments"]; ok && hasDepls {
+			// Merge the deployments which use the component:
+			component.properties = maps.Clone(component.properties)
+			deplNames := slices.Concat(
+				strings.Split(prev.properties["forklift:deployments"], ", "),
+				strings.Split(component.properties["forklift:deployments"], ", "),
+			)
+			slices.Sort(deplNames)
+			component.properties["forklift:deployments"] = strings.Join(slices.Compact(deplNames), ", ")
+		}
+		components[component.ref] = component
+		if b.dependencies[dependent] == nil {
+			b.dependencies[dependent] = make(structures.Set[string])
+		}
+		b.dependencies[dependent].Add(component.ref)
+	}
+
+	addPalletInclusions(b.root.ref, manifest.Includes.Pallets, addComponent)
+	repoRefs := make(map[string]string) // repo path -> component ref
+	for _, repoPath := range slices.Sorted(maps.Keys(manifest.Includes.Repos)) {
+		component := newBOMInclusionComponent(
+			bomRepo, repoPath, manifest.Includes.Repos[repoPath].Req.VersionLock,
+			manifest.Includes.Repos[repoPath].Override,
+		)
+		addComponent(b.root.ref, component)
+		repoRefs[repoPath] = newBOMRef(component.kind, component.name, component.version)
+	}
+
+	for _, deplName := range slices.Sorted(maps.Keys(manifest.Deploys)) {
+		pkgPath := manifest.Deploys[deplName].Package
+		spec := pkgSpecs[pkgPath]
+		if path.IsAbs(pkgPath) { // special case: package is provided by the pallet itself
+			pkgPath = path.Join(manifest.Pallet.Path, pkgPath)
+		}
+		pkg := bomComponent{
+			kind:        bomPkg,
+			name:        pkgPath,
+			description: spec.Description,
+			license:     spec.License,
+			maintainers: spec.Maintainers,
+			sources:     spec.Sources,
+			properties:  map[string]string{"forklift:deployments": deplName},
+		}
+		provider := b.root.ref
+		pkg.version = b.root.version
+		if repoPath := findProvidingRepo(pkgPath, manifest.Includes.Repos); repoPath != "" {
+			provider = repoRefs[repoPath]
+			pkg.version = components[provider].version
+		}
+		pkg.ref = newBOMRef(pkg.kind, pkg.name, pkg.version)
+		addComponent(provider, pkg)
+
+		deplProperties := map[string]string{"forklift:deployments": deplName}
+		downloads := manifest.Downloads[deplName]
+		images := slices.Concat(downloads.OCIImage, manifest.Exports[deplName].ComposeApp.Images)
+		for _, image := range images {
+			addComponent(pkg.ref, bomComponent{
+				ref:        newBOMRef(bomImage, image, ""),
+				kind:       bomImage,
+				name:       image,
+				properties: deplProperties,
+			})
+		}
+		for _, fileURL := range downloads.HTTPFile {
+			addComponent(pkg.ref, bomComponent{
+				ref:        newBOMRef(bomFile, fileURL, ""),
+				kind:       bomFile,
+				name:       fileURL,
+				url:        fileURL,
+				properties: deplProperties,
+			})
+		}
+	}
+
+	b.components = slices.SortedFunc(maps.Values(components), func(a, b bomComponent) int {
+		return strings.Compare(a.ref, b.ref)
+	})
+	return b
+}
+
+func addPalletInclusions(
+	dependent string, inclusions map[string]forklift.BundlePalletInclusion,
+	addComponent func(dependent string, component bomComponent),
+) {
+	for _, palletPath := range slices.Sorted(maps.Keys(inclusions)) {
+		inclusion := inclusions[palletPath]
+		component := newBOMInclusionComponent(
+			bomPallet, palletPath, inclusion.Req.VersionLock, inclusion.Override,
+		)
+		component.ref = newBOMRef(component.kind, component.name, component.version)
+		addComponent(dependent, component)
+		addPalletInclusions(component.ref, inclusion.Includes, addComponent)
+	}
+}
+
+func newBOMInclusionComponent(
+	kind, reqPath string, lock forklift.VersionLock, override forklift.BundleInclusionOverride,
+) bomComponent {
+	component := bomComponent{
+		kind:   kind,
+		name:   reqPath,
+		commit: lock.Def.Commit,
+		url:    "https://" + reqPath,
+	}
+	component.version, _ = lock.Def.Version()
+	if override == (forklift.BundleInclusionOverride{}) {
+		return component
+	}
+	component.version = override.Version
+	component.commit = ""
+	component.properties = map[string]string{
+		"forklift:override-path": override.Path,
+		"forklift:clean":         fmt.Sprint(override.Clean),
+	}
+	return component
+}
+
+// findProvidingRepo returns the path of the included repo which provides the package with the
+// specified path, or an empty string if the package isn't provided by any included repo.
+func findProvidingRepo(
+	pkgPath string, repos map[string]forklift.BundleRepoInclusion,
+) (repoPath string) {
+	for candidate := range repos {
+		if (pkgPath == candidate || strings.HasPrefix(pkgPath, candidate+"/")) &&
+			len(candidate) > len(repoPath) {
+			repoPath = candidate
+		}
+	}
+	return repoPath
+}
+
+func newBOMRef(kind, name, version string) string {
+	if version == "" {
+		return kind + ":" + name
+	}
+	return kind + ":" + name + "@" + version
+}
+
+// resolveImageDigests fills in the digests of the container images in the bill of materials,
+// wherever the digests can be determined from the image names or from Docker's local image store.
+func resolveImageDigests(indent int, b *bom) {
+	dc, err := docker.NewClient()
+	if err != nil {
+		IndentedFprintf(
+			indent, os.Stderr, "Warning: couldn't make Docker API client to resolve image digests: %s\n",
+			err,
+		)
+	}
+	for i, component := range b.components {
+		if component.kind != bomImage {
+			continue
+		}
+		if named, err := reference.ParseNormalizedNamed(component.name); err == nil {
+			if canonical, ok := named.(reference.Canonical); ok {
+				b.components[i].digest = canonical.Digest().String()
+				continue
+			}
+		}
+		if dc == nil {
+			continue
+		}
+		digest, err := dc.ResolveImageDigest(context.Background(), component.name)
+		if err != nil {
+			IndentedFprintf(
+				indent, os.Stderr,
+				"Warning: couldn't resolve image digests from Docker's local image store: %s\n", err,
+			)
+			dc = nil
+			continue
+		}
+		b.components[i].digest = digest
+	}
+}
+
+// Rendering
+
+func fprintBOM(out io.Writer, format string, b bom) error {
+	switch format {
+	default:
+		return checkSBOMFormat(format)
+	case SBOMFormatCycloneDX:
+		return fprintJSON(out, newCycloneDXDocument(b))
+	case SBOMFormatSPDX:
+		return fprintJSON(out, newSPDXDocument(b))
+	}
+}
+
+func fprintJSON(out io.Writer, doc any) error {
+	marshaled, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "couldn't serialize %T as json document", doc)
+	}
+	_, _ = fmt.Fprintln(out, string(marshaled))
+	return nil
+}
+
+// splitImageName splits a container image name into its repository, tag, and the name to use in
+// a package URL.
+func splitImageName(imageName string) (repository, tag, purlName string) {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return imageName, "", ""
+	}
+	if tagged, ok := named.(reference.Tagged); ok {
+		tag = tagged.Tag()
+	}
+	return reference.FamiliarName(named), tag, path.Base(reference.Path(named))
+}
+
+// newImagePURL returns an OCI package URL for the container image, or an empty string if the
+// image's digest is unknown.
+func newImagePURL(component bomComponent) string {
+	named, err := reference.ParseNormalizedNamed(component.name)
+	if err != nil || component.digest == "" {
+		return ""
+	}
+	_, tag, purlName := splitImageName(component.name)
+	query := url.Values{}
+	query.Set("repository_url", named.Name())
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	return fmt.Sprintf(
+		"pkg:oci/%s@%s?%s", strings.ToLower(purlName), url.QueryEscape(component.digest),
+		query.Encode(),
+	)
+}
+
+// newVCSLocation returns the location of the Git repository which provides the component, if it's
+// a pallet or repo.
+func newVCSLocation(component bomComponent) string {
+	if component.kind != bomPallet && component.kind != bomRepo {
+		return ""
+	}
+	location := "git+" + component.url
+	if component.commit != "" {
+		location += "@" + component.commit
+	}
+	return location
+}
+
+func splitDigest(digest string) (algorithm, hash string) {
+	algorithm, hash, _ = strings.Cut(digest, ":")
+	return strings.ToUpper(algorithm), hash
+}
+
+// CycloneDX
+
+type cdxDocument struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	BOMRef             string                 `json:"bom-ref,omitempty"`
+	Type               string                 `json:"type"`
+	Name               string                 `json:"name"`
+	Version            string                 `json:"version,omitempty"`
+	Description        string                 `json:"description,omitempty"`
+	Authors            []cdxContact           `json:"authors,omitempty"`
+	Licenses           []cdxLicense           `json:"licenses,omitempty"`
+	Hashes             []cdxHash              `json:"hashes,omitempty"`
+	PURL               string                 `json:"purl,omitempty"`
+	ExternalReferences []cdxExternalReference `json:"externalReferences,omitempty"`
+	Pedigree           *cdxPedigree           `json:"pedigree,omitempty"`
+	Properties         []cdxProperty          `json:"properties,omitempty"`
+}
+
+type cdxContact struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// A cdxLicense has either an SPDX license expression or a license (for any license which can't be
+// expressed as an SPDX license expression).
+type cdxLicense struct {
+	Expression string          `json:"expression,omitempty"`
+	License    *cdxLicenseInfo `json:"license,omitempty"`
+}
+
+type cdxLicenseInfo struct {
+	Name string `json:"name"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cdxPedigree struct {
+	Commits []cdxCommit `json:"commits"`
+}
+
+type cdxCommit struct {
+	UID string `json:"uid"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+func newCycloneDXDocument(b bom) cdxDocument {
+	doc := cdxDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.6",
+		SerialNumber: "urn:uuid:" + uuid.NewString(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{
+				{Type: "application", Name: "forklift", Version: b.toolVersion},
+			}},
+			Component: newCycloneDXComponent(b.root),
+		},
+		Components:   make([]cdxComponent, 0, len(b.components)),
+		Dependencies: make([]cdxDependency, 0, len(b.components)+1),
+	}
+	for _, component := range b.components {
+		doc.Components = append(doc.Components, newCycloneDXComponent(component))
+	}
+	for _, component := range slices.Concat([]bomComponent{b.root}, b.components) {
+		doc.Dependencies = append(doc.Dependencies, cdxDependency{
+			Ref:       component.ref,
+			DependsOn: slices.Sorted(b.dependencies[component.ref].All()),
+		})
+	}
+	return doc
+}
+
+func newCycloneDXComponent(component bomComponent) cdxComponent {
+	converted := cdxComponent{
+		BOMRef:      component.ref,
+		Type:        "application",
+		Name:        component.name,
+		Version:     component.version,
+		Description: component.description,
+	}
+	switch component.kind {
+	case bomPallet, bomRepo:
+		converted.ExternalReferences = append(converted.ExternalReferences, cdxExternalReference{
+			Type: "vcs", URL: component.url,
+		})
+	case bomPkg:
+		converted.Type = "library"
+	case bomImage:
+		converted.Type = "container"
+		converted.Name, converted.Version, _ = splitImageName(component.name)
+		if converted.Version == "" {
+			converted.Version = component.digest
+		}
+		converted.PURL = newImagePURL(component)
+	case bomFile:
+		converted.Type = "file"
+		converted.ExternalReferences = append(converted.ExternalReferences, cdxExternalReference{
+			Type: "distribution", URL: component.url,
+		})
+	}
+	if component.commit != "" {
+		converted.Pedigree = &cdxPedigree{Commits: []cdxCommit{{UID: component.commit}}}
+	}
+	switch {
+	case component.license == "":
+	case isSPDXLicenseExpression(component.license):
+		converted.Licenses = []cdxLicense{{Expression: component.license}}
+	default:
+		converted.Licenses = []cdxLicense{{License: &cdxLicenseInfo{Name: component.license}}}
+	}
+	for _, maintainer := range component.maintainers {
+		converted.Authors = append(converted.Authors, cdxContact(maintainer))
+	}
+	for _, source := range component.sources {
+		converted.ExternalReferences = append(converted.ExternalReferences, cdxExternalReference{
+			Type: "vcs", URL: source,
+		})
+	}
+	if component.digest != "" {
+		algorithm, hash := splitDigest(component.digest)
+		converted.Hashes = []cdxHash{{
+			Alg: strings.Replace(algorithm, "SHA", "SHA-", 1), Content: hash,
+		}}
+	}
+	for _, name := range slices.Sorted(maps.Keys(component.properties)) {
+		converted.Properties = append(converted.Properties, cdxProperty{
+			Name: name, Value: component.properties[name],
+		})
+	}
+	return converted
+}
+
+// SPDX
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+	// HasExtractedLicensingInfos describes the licenses which can't be expressed with SPDX license
+	// identifiers, and which are instead referred to by LicenseRef identifiers.
+	HasExtractedLicensingInfos []spdxExtractedLicense `json:"hasExtractedLicensingInfos,omitempty"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                string            `json:"SPDXID"`
+	Name                  string            `json:"name"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	Supplier              string            `json:"supplier,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	Checksums             []spdxChecksum    `json:"checksums,omitempty"`
+	SourceInfo            string            `json:"sourceInfo,omitempty"`
+	LicenseConcluded      string            `json:"licenseConcluded"`
+	LicenseDeclared       string            `json:"licenseDeclared"`
+	CopyrightText         string            `json:"copyrightText"`
+	Description           string            `json:"description,omitempty"`
+	Comment               string            `json:"comment,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxExtractedLicense struct {
+	LicenseID     string `json:"licenseId"`
+	ExtractedText string `json:"extractedText"`
+	Name          string `json:"name"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+const spdxNoAssertion = "NOASSERTION"
+
+var spdxIDInvalidChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+func newSPDXDocument(b bom) spdxDocument {
+	name := newBOMRef(bomPallet, b.root.name, b.root.version)
+	doc := spdxDocument{
+		SPDXVersion: "SPDX-2.3",
+		DataLicense: "CC0-1.0",
+		SPDXID:      "SPDXRef-DOCUMENT",
+		Name:        name,
+		DocumentNamespace: "https://spdx.org/spdxdocs/" +
+			spdxIDInvalidChars.ReplaceAllString(name, "-") + "-" + uuid.NewString(),
+		CreationInfo: spdxCreationInfo{
+			Created:  time.Now().UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: forklift-" + b.toolVersion},
+		},
+		Packages: make([]spdxPackage, 0, len(b.components)+1),
+	}
+
+	ids := make(map[string]string)         // component ref -> SPDX ID
+	licenseRefs := make(map[string]string) // free-form license text -> LicenseRef ID
+	usedIDs := make(structures.Set[string])
+	for _, component := range slices.Concat([]bomComponent{b.root}, b.components) {
+		id := "SPDXRef-" + spdxIDInvalidChars.ReplaceAllString(component.ref, "-")
+		for i := 2; usedIDs.Has(id); i++ {
+			id = fmt.Sprintf("SPDXRef-%s-%d", spdxIDInvalidChars.ReplaceAllString(component.ref, "-"), i)
+		}
+		usedIDs.Add(id)
+		ids[component.ref] = id
+		pkg := newSPDXPackage(id, component)
+		if component.license != "" && !isSPDXLicenseExpression(component.license) {
+			ref, ok := licenseRefs[component.license]
+			if !ok {
+				ref = fmt.Sprintf("LicenseRef-%d", len(licenseRefs)+1)
+				licenseRefs[component.license] = ref
+				doc.HasExtractedLicensingInfos = append(
+					doc.HasExtractedLicensingInfos, spdxExtractedLicense{
+						LicenseID: ref, ExtractedText: component.license, Name: component.license,
+					},
+				)
+			}
+			pkg.LicenseDeclared = ref
+		}
+		doc.Packages = append(doc.Packages, pkg)
+	}
+
+	doc.Relationships = append(doc.Relationships, spdxRelationship{
+		SPDXElementID: doc.SPDXID, RelationshipType: "DESCRIBES", RelatedSPDXElement: ids[b.root.ref],
+	})
+	for _, component := range slices.Concat([]bomComponent{b.root}, b.components) {
+		for _, dep := range slices.Sorted(b.dependencies[component.ref].All()) {
+			doc.Relationships = append(doc.Relationships, spdxRelationship{
+				SPDXElementID:      ids[component.ref],
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSPDXElement: ids[dep],
+			})
+		}
+	}
+	return doc
+}
+
+func newSPDXPackage(id string, component bomComponent) spdxPackage {
+	converted := spdxPackage{
+		SPDXID:           id,
+		Name:             component.name,
+		VersionInfo:      component.version,
+		DownloadLocation: spdxNoAssertion,
+		LicenseConcluded: spdxNoAssertion,
+		LicenseDeclared:  spdxNoAssertion,
+		CopyrightText:    spdxNoAssertion,
+		Description:      component.description,
+	}
+	switch component.kind {
+	case bomPallet, bomRepo:
+		converted.DownloadLocation = newVCSLocation(component)
+		converted.PrimaryPackagePurpose = "SOURCE"
+	case bomPkg:
+		converted.PrimaryPackagePurpose = "APPLICATION"
+	case bomImage:
+		converted.PrimaryPackagePurpose = "CONTAINER"
+		converted.Name, converted.VersionInfo, _ = splitImageName(component.name)
+		if converted.VersionInfo == "" {
+			converted.VersionInfo = component.digest
+		}
+		if purl := newImagePURL(component); purl != "" {
+			converted.ExternalRefs = []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: purl,
+			}}
+		}
+	case bomFile:
+		converted.PrimaryPackagePurpose = "FILE"
+		converted.DownloadLocation = component.url
+	}
+	if isSPDXLicenseExpression(component.license) {
+		// Free-form license text is instead declared with a LicenseRef by [newSPDXDocument]:
+		converted.LicenseDeclared = component.license
+	}
+	if len(component.maintainers) > 0 {
+		converted.Supplier = "Person: " + describeMaintainer(component.maintainers[0])
+	}
+	if len(component.sources) > 0 {
+		converted.SourceInfo = "source code is provided at " + strings.Join(component.sources, ", ")
+	}
+	if component.digest != "" {
+		algorithm, hash := splitDigest(component.digest)
+		converted.Checksums = []spdxChecksum{{Algorithm: algorithm, ChecksumValue: hash}}
+	}
+
+	comments := make([]string, 0, len(component.properties)+1)
+	if len(component.maintainers) > 1 {
+		maintainers := make([]string, 0, len(component.maintainers))
+		for _, maintainer := range component.maintainers {
+			maintainers = append(maintainers, describeMaintainer(maintainer))
+		}
+		comments = append(comments, "maintainers: "+strings.Join(maintainers, ", "))
+	}
+	for _, name := range slices.Sorted(maps.Keys(component.properties)) {
+		comments = append(comments, name+": "+component.properties[name])
+	}
+	converted.Comment = strings.Join(comments, "\n")
+	return converted
+}
+
+func describeMaintainer(maintainer core.PkgMaintainer) string {
+	if maintainer.Email == "" {
+		return maintainer.Name
+	}
+	return fmt.Sprintf("%s (%s)", maintainer.Name, maintainer.Email)
+}
+
+// SPDX license expressions
+
+var (
+	spdxLicenseIDRegexp = regexp.MustCompile(
+		`^((DocumentRef-[A-Za-z0-9.-]+:)?LicenseRef-[A-Za-z0-9.-]+|[A-Za-z0-9.-]+\+?)$`,
+	)
+	spdxExceptionIDRegexp = regexp.MustCompile(`^[A-Za-z0-9.-]+$`)
+)
+
+// isSPDXLicenseExpression checks whether the license is syntactically valid as an SPDX license
+// expression (e.g. "MIT", "Apache-2.0 OR GPL-2.0-or-later", or "(MIT AND BSD-3-Clause)"). It
+// doesn't check whether the license identifiers are on the SPDX License List, but it rejects
+// free-form text such as "MIT License" or "Apache 2.0".
+func isSPDXLicenseExpression(license string) bool {
+	tokens := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(license))
+	rest, ok := parseSPDXOrExpression(tokens)
+	return ok && len(rest) == 0
+}
+
+func parseSPDXOrExpression(tokens []string) (rest []string, ok bool) {
+	if tokens, ok = parseSPDXAndExpression(tokens); !ok {
+		return nil, false
+	}
+	for len(tokens) > 0 && tokens[0] == "OR" {
+		if tokens, ok = parseSPDXAndExpression(tokens[1:]); !ok {
+			return nil, false
+		}
+	}
+	return tokens, true
+}
+
+func parseSPDXAndExpression(tokens []string) (rest []string, ok bool) {
+	if tokens, ok = parseSPDXSimpleExpression(tokens); !ok {
+		return nil, false
+	}
+	for len(tokens) > 0 && tokens[0] == "AND" {
+		if tokens, ok = parseSPDXSimpleExpression(tokens[1:]); !ok {
+			return nil, false
+		}
+	}
+	return tokens, true
+}
+
+func parseSPDXSimpleExpression(tokens []string) (rest []string, ok bool) {
+	if len(tokens) == 0 {
+		return nil, false
+	}
+	if tokens[0] == "(" {
+		if tokens, ok = parseSPDXOrExpression(tokens[1:]); !ok || len(tokens) == 0 ||
+			tokens[0] != ")" {
+			return nil, false
+		}
+		return tokens[1:], true
+	}
+	if isSPDXOperator(tokens[0]) || !spdxLicenseIDRegexp.MatchString(tokens[0]) {
+		return nil, false
+	}
+	tokens = tokens[1:]
+	if len(tokens) == 0 || tokens[0] != "WITH" {
+		return tokens, true
+	}
+	if len(tokens) < 2 || isSPDXOperator(tokens[1]) || !spdxExceptionIDRegexp.MatchString(tokens[1]) {
+		return nil, false
+	}
+	return tokens[2:], true
+}
+
+func isSPDXOperator(token string) bool {
+	return token == "AND" || token == "OR" || token == "WITH"
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"testing"
+)
+
+func TestIsSPDXLicenseExpression(t *testing.T) {
+	for _, test := range []struct {
+		license  string
+		expected bool
+	}{
+		{license: "MIT", expected: true},
+		{license: "GPL-2.0+", expected: true},
+		{license: "Apache-2.0 OR GPL-2.0-or-later", expected: true},
+		{license: "(MIT AND BSD-3-Clause) OR Apache-2.0", expected: true},
+		{license: "GPL-2.0-or-later WITH Classpath-exception-2.0", expected: true},
+		{license: "LicenseRef-custom", expected: true},
+		{license: "DocumentRef-other:LicenseRef-custom", expected: true},
+		{license: ""},
+		{license: "MIT License"},
+		{license: "Apache 2.0"},
+		{license: "MIT AND"},
+		{license: "(MIT"},
+		{license: "MIT)"},
+		{license: "(MIT OR Apache-2.0) WITH Classpath-exception-2.0"},
+		{license: "AND"},
+		{license: "Public domain, see https://example.com"},
+	} {
+		t.Run(test.license, func(t *testing.T) {
+			if actual := isSPDXLicenseExpression(test.license); actual != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNewSPDXDocumentLicenses(t *testing.T) {
+	b := bom{
+		root: bomComponent{ref: "pallet", kind: bomPallet, name: "github.com/x/p", license: "MIT"},
+		components: []bomComponent{
+			{ref: "a", kind: bomPkg, name: "a", license: "Apache 2.0"},
+			{ref: "b", kind: bomPkg, name: "b", license: "Apache 2.0"},
+			{ref: "c", kind: bomPkg, name: "c"},
+		},
+	}
+	doc := newSPDXDocument(b)
+	for i, expected := range []string{"MIT", "LicenseRef-1", "LicenseRef-1", spdxNoAssertion} {
+		if actual := doc.Packages[i].LicenseDeclared; actual != expected {
+			t.Errorf(
+				"expected package %s to declare license %s, got %s",
+				doc.Packages[i].Name, expected, actual,
+			)
+		}
+	}
+	if len(doc.HasExtractedLicensingInfos) != 1 ||
+		doc.HasExtractedLicensingInfos[0].ExtractedText != "Apache 2.0" {
+		t.Errorf(
+			"expected one extracted license for 'Apache 2.0', got %+v", doc.HasExtractedLicensingInfos,
+		)
+	}
+
+	component := newCycloneDXComponent(b.components[0])
+	if len(component.Licenses) != 1 || component.Licenses[0].Expression != "" ||
+		component.Licenses[0].License == nil || component.Licenses[0].License.Name != "Apache 2.0" {
+		t.Errorf("expected a license named 'Apache 2.0', got %+v", component.Licenses)
+	}
+}
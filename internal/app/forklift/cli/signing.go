@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+)
+
+// CheckStagedBundleTrust checks, if the stage store's bundle trust config requires staged pallet
+// bundles to be signed, that the staged pallet bundle at the specified index has a valid signature
+// by a key trusted by the bundle trust config.
+func CheckStagedBundleTrust(
+	indent int, store *forklift.FSStageStore, bundle *forklift.FSBundle, index int,
+) error {
+	config := store.GetBundleTrust()
+	if !config.Enforce {
+		return nil
+	}
+	key, err := config.CheckBundle(bundle)
+	if err != nil {
+		return errors.Wrapf(
+			err, "refusing to use staged pallet bundle %d, since staged pallet bundles must be signed "+
+				"by a trusted key", index,
+		)
+	}
+	name := key.Name
+	if name == "" {
+		name = key.PublicKey
+	}
+	IndentedFprintf(
+		indent, os.Stderr, "Staged pallet bundle %d is signed by trusted key %s\n", index, name,
+	)
+	return nil
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+)
+
+// newTestStagedBundle makes a stage store in a temporary directory with an empty staged pallet
+// bundle 1, and loads them.
+func newTestStagedBundle(t *testing.T) (*forklift.FSStageStore, *forklift.FSBundle) {
+	t.Helper()
+	fsys := forklift.DirFS(filepath.ToSlash(t.TempDir()))
+	if err := forklift.EnsureFSStageStore(fsys, "stages", "v0.1.0"); err != nil {
+		t.Fatalf("couldn't make stage store: %s", err)
+	}
+	store, err := forklift.LoadFSStageStore(fsys, "stages")
+	if err != nil {
+		t.Fatalf("couldn't load stage store: %s", err)
+	}
+	index, err := store.AllocateNew()
+	if err != nil {
+		t.Fatalf("couldn't allocate staged pallet bundle: %s", err)
+	}
+	bundle := &forklift.FSBundle{FS: forklift.DirFS(store.GetBundlePath(index))}
+	if err = bundle.WriteManifestFile(); err != nil {
+		t.Fatalf("couldn't write bundle manifest: %s", err)
+	}
+	if bundle, err = store.LoadFSBundle(index); err != nil {
+		t.Fatalf("couldn't load staged pallet bundle: %s", err)
+	}
+	return store, bundle
+}
+
+func TestCheckStagedBundleTrust(t *testing.T) {
+	key, err := forklift.GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("couldn't generate signing key: %s", err)
+	}
+	publicKey := forklift.EncodePublicKey(key.Public().(ed25519.PublicKey))
+
+	store, bundle := newTestStagedBundle(t)
+	if err = CheckStagedBundleTrust(0, store, bundle, 1); err != nil {
+		t.Errorf("expected unsigned bundle to be allowed when trust isn't enforced, got: %s", err)
+	}
+
+	store.SetBundleTrust(forklift.BundleTrustConfig{Enforce: true})
+	if err = CheckStagedBundleTrust(0, store, bundle, 1); err == nil {
+		t.Error("expected unsigned bundle to be refused when trust is enforced")
+	}
+	if _, err = bundle.Sign(key); err != nil {
+		t.Fatalf("couldn't sign bundle: %s", err)
+	}
+	if err = CheckStagedBundleTrust(0, store, bundle, 1); err == nil {
+		t.Error("expected bundle signed by an untrusted key to be refused when trust is enforced")
+	}
+
+	store.SetBundleTrust(forklift.BundleTrustConfig{
+		Enforce: true,
+		Keys:    []forklift.TrustedKey{{Name: "release", PublicKey: publicKey}},
+	})
+	if err = CheckStagedBundleTrust(0, store, bundle, 1); err != nil {
+		t.Errorf("expected bundle signed by a trusted key to be allowed, got: %s", err)
+	}
+
+	if err = os.WriteFile(
+		filepath.Join(filepath.FromSlash(bundle.FS.Path()), "extra.yml"), []byte("extra"), 0o644,
+	); err != nil {
+		t.Fatalf("couldn't modify bundle: %s", err)
+	}
+	if err = CheckStagedBundleTrust(0, store, bundle, 1); err == nil {
+		t.Error("expected bundle modified after signing to be refused when trust is enforced")
+	}
+}
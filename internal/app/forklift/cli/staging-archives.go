@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+	"github.com/forklift-run/forklift/internal/clients/docker"
+)
+
+// Export
+
+// ExportStagedBundle writes an archive of the staged bundle at the specified index to the specified
+// path, together with the cached downloads required by the bundle. If includeImages is set, the
+// archive also includes the container images required by the bundle, which must already be in
+// Docker's local image store.
+func ExportStagedBundle(
+	indent int, store *forklift.FSStageStore, index int, dlCache *forklift.FSDownloadCache,
+	outputPath, toolVersion string, includeImages bool,
+) (err error) {
+	bundle, err := store.LoadFSBundle(index)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't load staged bundle %d", index)
+	}
+
+	var images []string
+	imagesTarball := ""
+	if includeImages {
+		images = bundle.ListImages()
+	}
+	if len(images) > 0 {
+		IndentedFprintf(
+			indent, os.Stderr, "Saving %d container images from Docker...\n", len(images),
+		)
+		if imagesTarball, err = saveImages(images); err != nil {
+			return err
+		}
+		defer func() {
+			if rerr := os.Remove(imagesTarball); rerr != nil && err == nil {
+				err = errors.Wrapf(rerr, "couldn't remove temporary file %s", imagesTarball)
+			}
+		}()
+	}
+
+	IndentedFprintf(
+		indent, os.Stderr, "Writing staged pallet bundle %d to %s...\n", index, outputPath,
+	)
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't create %s", outputPath)
+	}
+	if err = forklift.WriteBundleArchive(
+		file, bundle, dlCache, toolVersion, imagesTarball, images,
+	); err != nil {
+		_ = file.Close()
+		_ = os.Remove(outputPath)
+		return errors.Wrapf(err, "couldn't write archive of staged bundle %d", index)
+	}
+	return errors.Wrapf(file.Close(), "couldn't close %s", outputPath)
+}
+
+// saveImages saves the specified container images from Docker into a temporary tarball, returning
+// the path of the tarball.
+func saveImages(images []string) (tarballPath string, err error) {
+	dc, err := docker.NewClient()
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't make Docker API client")
+	}
+	tarball, err := os.CreateTemp("", "forklift-images-*.tar")
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't create temporary file for container images")
+	}
+	if err = dc.SaveImages(context.Background(), images, tarball); err != nil {
+		_ = tarball.Close()
+		_ = os.Remove(tarball.Name())
+		return "", errors.Wrap(
+			err, "couldn't save container images from Docker (maybe they haven't been downloaded yet?)",
+		)
+	}
+	if err = tarball.Close(); err != nil {
+		_ = os.Remove(tarball.Name())
+		return "", errors.Wrapf(err, "couldn't close %s", tarball.Name())
+	}
+	return tarball.Name(), nil
+}
+
+// Import
+
+// ImportStagedBundle extracts the bundle archive at the specified path, checks its contents, and
+// adds its bundle to the stage store as a new staged bundle. The archive's downloads are added to
+// the download cache, and (if loadImages is set) the archive's container images are loaded into
+// Docker. The index of the new staged bundle is returned.
+func ImportStagedBundle(
+	indent int, store *forklift.FSStageStore, dlCache *forklift.FSDownloadCache, inputPath string,
+	loadImages bool,
+) (index int, err error) {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return 0, errors.Wrapf(err, "couldn't open %s", inputPath)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	storePath := filepath.FromSlash(store.FS.Path())
+	if err = forklift.EnsureExists(storePath); err != nil {
+		return 0, errors.Wrapf(err, "couldn't ensure the existence of %s", storePath)
+	}
+	// We extract the archive into the stage store so that the bundle can be moved into place
+	// without copying it across filesystems:
+	extracted, err := os.MkdirTemp(storePath, "import-")
+	if err != nil {
+		return 0, errors.Wrapf(err, "couldn't make temporary directory in %s", storePath)
+	}
+	defer func() {
+		if rerr := os.RemoveAll(extracted); rerr != nil && err == nil {
+			err = errors.Wrapf(rerr, "couldn't remove temporary directory %s", extracted)
+		}
+	}()
+
+	IndentedFprintf(indent, os.Stderr, "Extracting and checking %s...\n", inputPath)
+	manifest, err := forklift.ReadBundleArchive(file, extracted)
+	if err != nil {
+		return 0, errors.Wrapf(err, "couldn't extract bundle archive %s", inputPath)
+	}
+	IndentedFprintf(
+		indent+1, os.Stderr, "Archive contains a bundle of pallet %s@%s\n",
+		manifest.Pallet.Path, manifest.Pallet.Version,
+	)
+
+	imported, err := forklift.ImportArchivedDownloads(extracted, dlCache)
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't add downloads from bundle archive to the cache")
+	}
+	IndentedFprintf(indent, os.Stderr, "Added %d downloads to the download cache\n", imported)
+
+	if loadImages && len(manifest.Images) > 0 {
+		IndentedFprintf(
+			indent, os.Stderr, "Loading %d container images into Docker...\n", len(manifest.Images),
+		)
+		if err = loadImageTarball(forklift.GetArchivedImagesPath(extracted)); err != nil {
+			return 0, err
+		}
+	}
+
+	if index, err = store.ImportArchivedBundle(extracted); err != nil {
+		return 0, errors.Wrap(err, "couldn't add bundle from archive to the stage store")
+	}
+	IndentedFprintf(indent, os.Stderr, "Imported the bundle as staged pallet bundle %d\n", index)
+	return index, nil
+}
+
+// loadImageTarball loads the container images from the tarball at the specified path into Docker.
+func loadImageTarball(tarballPath string) error {
+	dc, err := docker.NewClient()
+	if err != nil {
+		return errors.Wrap(err, "couldn't make Docker API client")
+	}
+	tarball, err := os.Open(tarballPath)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't open %s", tarballPath)
+	}
+	defer func() {
+		_ = tarball.Close()
+	}()
+	return errors.Wrap(
+		dc.LoadImages(context.Background(), tarball, os.Stderr),
+		"couldn't load container images into Docker",
+	)
+}
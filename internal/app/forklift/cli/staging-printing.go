@@ -12,9 +12,13 @@ import (
 // Bundles
 
 func FprintStagedBundle(
-	indent int, out io.Writer,
+	indent int, out io.Writer, format string,
 	store *forklift.FSStageStore, bundle *forklift.FSBundle, index int, names []string,
-) {
+) error {
+	if IsStructuredOutput(format) {
+		return FprintDocument(out, format, NewStagedBundleDocument(bundle, index, names))
+	}
+
 	IndentedFprintf(indent, out, "Staged pallet bundle: %d\n", index)
 	indent++
 
@@ -62,6 +66,7 @@ func FprintStagedBundle(
 		_, _ = fmt.Fprintln(out)
 		fprintBundleExports(indent+1, out, bundle.Manifest.Exports)
 	}
+	return nil
 }
 
 func fprintBundlePallet(indent int, out io.Writer, pallet forklift.BundlePallet) {
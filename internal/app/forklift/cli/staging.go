@@ -1,16 +1,22 @@
 package cli
 
 import (
+	"cmp"
 	"context"
 	"fmt"
-	"io"
+	"maps"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/pkg/errors"
-	"golang.org/x/sync/errgroup"
 
 	"github.com/forklift-run/forklift/internal/app/forklift"
 	"github.com/forklift-run/forklift/internal/clients/cli"
@@ -39,12 +45,20 @@ func SetNextStagedBundle(
 	toolVersion, bundleMinVersion string, skipImageCaching bool, platform string, parallel,
 	ignoreToolVersion bool,
 ) error {
+	bundle, err := store.LoadFSBundle(index)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't load staged pallet bundle %d", index)
+	}
+	if err = CheckStagedBundleTrust(indent, store, bundle, index); err != nil {
+		return err
+	}
+
 	store.SetNext(index)
 	IndentedFprintf(
 		indent, os.Stderr,
 		"Committing update to the stage store for stage %d as the next stage to be applied...\n", index,
 	)
-	if err := store.CommitState(); err != nil {
+	if err := CommitStageStore(store); err != nil {
 		return errors.Wrap(err, "couldn't commit updated stage store state")
 	}
 
@@ -78,7 +92,7 @@ type StagingCaches struct {
 func StagePallet(
 	indent int, merged *forklift.FSPallet, stageStore *forklift.FSStageStore, caches StagingCaches,
 	exportPath string, versions StagingVersions,
-	skipImageCaching bool, platform string, parallel, ignoreToolVersion bool,
+	skipImageCaching, bundleImages bool, platform string, parallel, ignoreToolVersion bool,
 ) (index int, err error) {
 	if _, isMerged := merged.FS.(*forklift.MergeFS); isMerged {
 		return 0, errors.Errorf("the pallet provided for staging should not be a merged pallet!")
@@ -113,6 +127,20 @@ func StagePallet(
 	); err != nil {
 		return index, errors.Wrapf(err, "couldn't bundle pallet %s as stage %d", merged.Path(), index)
 	}
+	if bundleImages {
+		bundle, err := stageStore.LoadFSBundle(index)
+		if err != nil {
+			return index, errors.Wrapf(err, "couldn't load staged pallet bundle %d", index)
+		}
+		if err = SaveBundledImages(indent+1, bundle, platform, parallel); err != nil {
+			return index, errors.Wrapf(err, "couldn't save container images into stage %d", index)
+		}
+		if err = bundle.WriteIntegrityManifest(); err != nil {
+			return index, errors.Wrapf(
+				err, "couldn't update bundle integrity manifest of stage %d", index,
+			)
+		}
+	}
 	if err = SetNextStagedBundle(
 		indent, stageStore, index, exportPath, versions.Core.Tool, versions.MinSupportedBundle,
 		skipImageCaching, platform, parallel, ignoreToolVersion,
@@ -121,6 +149,16 @@ func StagePallet(
 			err, "couldn't prepare staged pallet bundle %d to be applied next", index,
 		)
 	}
+
+	if policy := stageStore.Manifest.Retention; policy != nil {
+		fmt.Fprintln(os.Stderr)
+		if err = GCStageStore(indent, stageStore, *policy, false); err != nil {
+			// The pallet was still staged successfully, so we don't report this as an error:
+			IndentedFprintf(
+				indent, os.Stderr, "Warning: couldn't garbage-collect the stage store: %s\n", err.Error(),
+			)
+		}
+	}
 	return index, nil
 }
 
@@ -155,9 +193,13 @@ func buildBundle(
 	if err = outputBundle.WriteFileExports(dlCache); err != nil {
 		return errors.Wrap(err, "couldn't write file exports into bundle")
 	}
+	outputBundle.Manifest.Hashed = true
 	if err = outputBundle.WriteManifestFile(); err != nil {
 		return errors.Wrap(err, "couldn't write bundle manifest file into bundle")
 	}
+	if err = outputBundle.WriteIntegrityManifest(); err != nil {
+		return errors.Wrap(err, "couldn't write bundle integrity manifest into bundle")
+	}
 	return nil
 }
 
@@ -181,6 +223,7 @@ func newBundleManifest(
 		Exports:   make(map[string]forklift.BundleDeplExports),
 	}
 	desc.Pallet.Version, desc.Pallet.Clean = CheckGitRepoVersion(merged.FS.Path())
+	desc.Pallet.Commit = checkGitRepoCommit(merged.FS.Path())
 	palletReqs, err := merged.LoadFSPalletReqs("**")
 	if err != nil {
 		return desc, errors.Wrapf(
@@ -244,6 +287,20 @@ func CheckGitRepoVersion(palletPath string) (version string, clean bool) {
 	return versionString, status.IsClean()
 }
 
+// checkGitRepoCommit returns the full hash of the current commit of the Git repository at the
+// specified path, or an empty string if it can't be determined.
+func checkGitRepoCommit(repoPath string) string {
+	gitRepo, err := git.Open(filepath.FromSlash(repoPath))
+	if err != nil {
+		return ""
+	}
+	commit, err := gitRepo.GetHead()
+	if err != nil {
+		return ""
+	}
+	return commit
+}
+
 func newBundlePalletInclusion(
 	pallet *forklift.FSPallet, req *forklift.FSPalletReq, palletCache forklift.PathedPalletCache,
 	describeImports bool,
@@ -368,18 +425,146 @@ func newBundleRepoInclusion(
 	}
 }
 
+// Garbage collection
+
+// GCStageStore deletes the staged pallet bundles which aren't kept by the retention policy, also
+// removing them from the history and names of the stage store. If dryRun is true, it only prints
+// which staged pallet bundles would be deleted.
+func GCStageStore(
+	indent int, store *forklift.FSStageStore, policy forklift.StageRetentionSpec, dryRun bool,
+) error {
+	deletions, err := store.PlanGC(policy, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "couldn't determine which staged pallet bundles to delete")
+	}
+	if len(deletions) == 0 {
+		IndentedFprintln(
+			indent, os.Stderr, "There are no staged pallet bundles to delete under the retention policy!",
+		)
+		return nil
+	}
+	if dryRun {
+		IndentedFprintf(
+			indent, os.Stderr, "Would delete staged pallet bundles under the retention policy: %+v\n",
+			deletions,
+		)
+		return nil
+	}
+
+	IndentedFprintf(
+		indent, os.Stderr, "Deleting staged pallet bundles under the retention policy: %+v\n",
+		deletions,
+	)
+	// We update the stage store's manifest before deleting the bundles, so that the manifest never
+	// refers to deleted bundles:
+	for _, index := range deletions {
+		store.RemoveBundleNames(index)
+		store.RemoveBundleHistory(index)
+	}
+	if err = CommitStageStore(store); err != nil {
+		return errors.Wrap(err, "couldn't commit updated stage store state")
+	}
+	failedIndices := make([]int, 0, len(deletions))
+	for _, index := range deletions {
+		if err = os.RemoveAll(filepath.FromSlash(store.GetBundlePath(index))); err != nil {
+			failedIndices = append(failedIndices, index)
+		}
+	}
+	if len(failedIndices) > 0 {
+		return errors.Errorf("couldn't delete some staged pallet bundles: %+v", failedIndices)
+	}
+	return nil
+}
+
 // Apply
 
+// ApplyOptions controls how a pallet bundle is applied to the Docker host.
+type ApplyOptions struct {
+	PlanOptions
+	// Retries is the number of times a failed change will be retried (with exponential backoff)
+	// before it's reported as failed.
+	Retries int
+	// SavedPlan is a previously-saved plan which must exactly match the plan computed for the
+	// bundle (which should be computed with the saved plan's options), or else the bundle won't be
+	// applied. If it's nil, the computed plan is applied without any such check.
+	SavedPlan *SavedPlan
+}
+
+// ApplyNextOrCurrentBundle applies the bundle, which must be the next staged pallet bundle (or, if
+// the next staged pallet bundle failed in the past, the last successfully-applied staged pallet
+// bundle), and records the result in the stage store. If the bundle is refused without being
+// applied, the refusal is recorded as a failure and a [*RefusedBundleError] is returned.
 func ApplyNextOrCurrentBundle(
-	indent int, store *forklift.FSStageStore, bundle *forklift.FSBundle, parallel bool,
+	indent int, store *forklift.FSStageStore, bundle *forklift.FSBundle, toolVersion string,
+	opts ApplyOptions,
 ) error {
+	ctx, changeCtx, stopHandlingInterrupts := HandleInterrupts(indent)
+	defer stopHandlingInterrupts()
+
 	applyingFallback := store.NextFailed()
-	applyErr := applyBundle(0, bundle, parallel)
 	current, _ := store.GetCurrent()
 	next, _ := store.GetNext()
+	record := forklift.ApplyRecord{
+		Bundle:      next,
+		Fallback:    applyingFallback,
+		Partial:     opts.IsPartial(),
+		ToolVersion: toolVersion,
+		Started:     time.Now(),
+	}
+	if applyingFallback {
+		record.Bundle = current
+	}
+	if err := checkStagedBundle(0, store, bundle, record.Bundle); err != nil {
+		finishApplyRecord(indent, store, record, nil, err, false)
+		if !applyingFallback || current == next {
+			store.RecordNextSuccess(false)
+			if cerr := CommitStageStore(store); cerr != nil {
+				IndentedFprintf(
+					indent, os.Stderr,
+					"Error: couldn't record failure of the next staged pallet bundle: %s\n", cerr.Error(),
+				)
+			}
+		}
+		return err
+	}
+	// If the saved plan is stale, we haven't tried to apply anything, so we don't record anything:
+	plan, applyErr := planBundleApply(0, bundle, opts)
+	if isStaleSavedPlan(applyErr) {
+		return applyErr
+	}
+	var (
+		applied []string
+		failed  []forklift.FailedChange
+	)
+	if applyErr == nil {
+		applied, failed, applyErr = applyPlan(ctx, changeCtx, 0, bundle, plan, opts.Retries)
+	}
+	interrupted := applyErr != nil && ctx.Err() != nil
 	fmt.Fprintln(os.Stderr)
+	finishApplyRecord(indent, store, record, failed, applyErr, interrupted)
 	if !applyingFallback || current == next {
-		store.RecordNextSuccess(applyErr == nil)
+		switch {
+		case interrupted:
+			store.RecordNextInterrupted()
+		case opts.IsPartial():
+			store.RecordNextPartialSuccess(applyErr == nil, applied)
+		default:
+			store.RecordNextSuccess(applyErr == nil)
+		}
+	}
+	if interrupted {
+		if err := CommitStageStore(store); err != nil {
+			IndentedFprintf(
+				indent, os.Stderr,
+				"Error: couldn't record interruption of the pallet bundle: %s\n", err.Error(),
+			)
+		}
+		IndentedFprintln(
+			indent, os.Stderr,
+			"Interrupted before all changes could be applied; if you run `forklift stage apply` again, "+
+				"it will attempt to apply the same pallet bundle again.",
+		)
+		return errors.Wrap(applyErr, "apply was interrupted")
 	}
 	if applyErr != nil {
 		if applyingFallback {
@@ -390,7 +575,7 @@ func ApplyNextOrCurrentBundle(
 			)
 			return applyErr
 		}
-		if err := store.CommitState(); err != nil {
+		if err := CommitStageStore(store); err != nil {
 			IndentedFprintf(
 				indent, os.Stderr,
 				"Error: couldn't record failure of the next staged pallet bundle: %s\n", err.Error(),
@@ -404,25 +589,279 @@ func ApplyNextOrCurrentBundle(
 		)
 		return errors.Wrap(applyErr, "couldn't apply next staged bundle")
 	}
-	if err := store.CommitState(); err != nil {
+	if err := CommitStageStore(store); err != nil {
 		return errors.Wrap(err, "couldn't commit updated stage store state")
 	}
 	return nil
 }
 
-func applyBundle(indent int, bundle *forklift.FSBundle, parallel bool) error {
-	concurrentPlan, serialPlan, err := Plan(indent, bundle, bundle, parallel)
-	if err != nil {
+// A RefusedBundleError is returned when a staged pallet bundle is refused before any changes are
+// made to the Docker host, e.g. because it isn't signed by a trusted key or because it failed its
+// integrity check.
+type RefusedBundleError struct {
+	// Bundle is the index of the refused staged pallet bundle.
+	Bundle int
+	// Err describes why the staged pallet bundle was refused.
+	Err error
+}
+
+func (e *RefusedBundleError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RefusedBundleError) Unwrap() error {
+	return e.Err
+}
+
+// checkStagedBundle checks that the staged pallet bundle at the specified index may be applied: it
+// must be signed by a trusted key (if the stage store's bundle trust config requires it), and it
+// must pass its integrity check. Otherwise, a [*RefusedBundleError] is returned.
+func checkStagedBundle(
+	indent int, store *forklift.FSStageStore, bundle *forklift.FSBundle, index int,
+) error {
+	if err := CheckStagedBundleTrust(indent, store, bundle, index); err != nil {
+		return &RefusedBundleError{Bundle: index, Err: err}
+	}
+	if err := CheckStagedBundleIntegrity(indent, bundle, index); err != nil {
+		return &RefusedBundleError{Bundle: index, Err: err}
+	}
+	return nil
+}
+
+// ApplyFallbackBundle applies the staged pallet bundle at the specified index as a fallback for a
+// staged pallet bundle which couldn't be applied. Other than recording the attempt in the stage
+// store's apply journal, it doesn't change the stage store.
+func ApplyFallbackBundle(
+	indent int, store *forklift.FSStageStore, bundle *forklift.FSBundle, index int,
+	toolVersion string, opts ApplyOptions,
+) error {
+	ctx, changeCtx, stopHandlingInterrupts := HandleInterrupts(indent)
+	defer stopHandlingInterrupts()
+
+	record := forklift.ApplyRecord{
+		Bundle:      index,
+		Fallback:    true,
+		Partial:     opts.IsPartial(),
+		ToolVersion: toolVersion,
+		Started:     time.Now(),
+	}
+	if err := checkStagedBundle(indent, store, bundle, index); err != nil {
+		finishApplyRecord(indent, store, record, nil, err, false)
 		return err
 	}
+	plan, err := planBundleApply(indent, bundle, opts)
+	if isStaleSavedPlan(err) {
+		return err
+	}
+	var failed []forklift.FailedChange
+	if err == nil {
+		_, failed, err = applyPlan(ctx, changeCtx, indent, bundle, plan, opts.Retries)
+	}
+	interrupted := err != nil && ctx.Err() != nil
+	finishApplyRecord(indent, store, record, failed, err, interrupted)
+	if interrupted {
+		return errors.Wrap(err, "apply was interrupted")
+	}
+	return err
+}
+
+// RecordFailedApply records in the stage store's apply journal that the staged pallet bundle at the
+// specified index couldn't be applied at all (e.g. because it's incompatible with the Forklift
+// tool).
+func RecordFailedApply(
+	indent int, store *forklift.FSStageStore, index int, fallback bool, toolVersion string,
+	applyErr error,
+) {
+	finishApplyRecord(indent, store, forklift.ApplyRecord{
+		Bundle:      index,
+		Fallback:    fallback,
+		ToolVersion: toolVersion,
+		Started:     time.Now(),
+	}, nil, applyErr, false)
+}
+
+// finishApplyRecord completes the record of an attempt to apply a staged pallet bundle and appends
+// it to the stage store's apply journal. Because the journal is only informational, any error in
+// appending to it is reported without causing the apply to fail.
+func finishApplyRecord(
+	indent int, store *forklift.FSStageStore, record forklift.ApplyRecord,
+	failed []forklift.FailedChange, applyErr error, interrupted bool,
+) {
+	record.Finished = time.Now()
+	record.FailedChanges = failed
+	switch {
+	case interrupted:
+		record.Result = forklift.ApplyInterrupted
+	case applyErr != nil:
+		record.Result = forklift.ApplyFailed
+	default:
+		record.Result = forklift.ApplySucceeded
+	}
+	if applyErr != nil {
+		record.Error = applyErr.Error()
+	}
+	if err := store.AppendApplyRecord(record); err != nil {
+		IndentedFprintf(
+			indent, os.Stderr, "Error: couldn't record the apply in the apply journal: %s\n", err.Error(),
+		)
+	}
+}
+
+// CheckStagedBundleIntegrity checks the staged pallet bundle at the specified index against its
+// integrity manifest, printing every mismatch. Staged pallet bundles made by older versions of
+// Forklift (which didn't write integrity manifests) are not checked, but staged pallet bundles made
+// with integrity manifests fail the check if their integrity manifests are missing.
+func CheckStagedBundleIntegrity(indent int, bundle *forklift.FSBundle, index int) error {
+	if _, ok, err := bundle.LoadIntegrityManifest(); err != nil {
+		return errors.Wrapf(err, "couldn't check integrity of staged pallet bundle %d", index)
+	} else if !ok && bundle.Manifest.Hashed {
+		return errors.Errorf(
+			"staged pallet bundle %d failed its integrity check, since its integrity manifest is "+
+				"missing", index,
+		)
+	} else if !ok {
+		IndentedFprintf(
+			indent, os.Stderr,
+			"Warning: staged pallet bundle %d has no integrity manifest, so it can't be checked for "+
+				"corruption\n",
+			index,
+		)
+		return nil
+	}
+	mismatches, err := bundle.CheckIntegrity()
+	if err != nil {
+		return errors.Wrapf(err, "couldn't check integrity of staged pallet bundle %d", index)
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+	IndentedFprintf(indent, os.Stderr, "Staged pallet bundle %d is corrupted:\n", index)
+	for _, mismatch := range mismatches {
+		BulletedFprintln(indent+1, os.Stderr, mismatch)
+	}
+	return errors.Errorf("staged pallet bundle %d failed its integrity check", index)
+}
 
+// HandleInterrupts handles SIGINT and SIGTERM signals during an apply or a reconciliation. The
+// first signal cancels the returned ctx, which should prevent any new changes from being started;
+// changes already in progress are allowed to finish. A second signal also cancels the returned
+// changeCtx, which should abort any changes in progress. The returned stop function must be called
+// to stop handling signals.
+func HandleInterrupts(indent int) (ctx, changeCtx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	changeCtx, cancelChanges := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-signals:
+			}
+			fmt.Fprintln(os.Stderr)
+			if ctx.Err() == nil {
+				IndentedFprintln(
+					indent, os.Stderr,
+					"Interrupted! Waiting for changes in progress to finish (interrupt again to abort "+
+						"them)...",
+				)
+				cancel()
+				continue
+			}
+			IndentedFprintln(indent, os.Stderr, "Interrupted again! Aborting changes in progress...")
+			cancelChanges()
+			// Any further signals should have their default behavior (e.g. killing the process):
+			signal.Stop(signals)
+			return
+		}
+	}()
+	return ctx, changeCtx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+		cancelChanges()
+	}
+}
+
+// applyBundle applies the bundle and returns the sorted names of the package deployments (or, for
+// Compose apps without known deployments, the names of the Compose apps) which were successfully
+// changed, as well as records of the changes which failed. No new changes will be started after ctx
+// is canceled, and changes in progress will be aborted if changeCtx is canceled.
+func applyBundle(
+	ctx, changeCtx context.Context, indent int, bundle *forklift.FSBundle, opts ApplyOptions,
+) (applied []string, failed []forklift.FailedChange, err error) {
+	plan, err := planBundleApply(indent, bundle, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return applyPlan(ctx, changeCtx, indent, bundle, plan, opts.Retries)
+}
+
+// bundlePlan is a plan for changes to make to the Docker host to apply a bundle.
+type bundlePlan struct {
+	concurrent structures.Digraph[*ReconciliationChange]
+	serial     []*ReconciliationChange
+}
+
+// planBundleApply computes the plan for applying the bundle. If the options include a saved plan
+// which doesn't match the computed plan, a [*StaleSavedPlanError] is returned.
+func planBundleApply(
+	indent int, bundle *forklift.FSBundle, opts ApplyOptions,
+) (plan bundlePlan, err error) {
+	if plan.concurrent, plan.serial, err = Plan(indent, bundle, bundle, opts.PlanOptions); err != nil {
+		return bundlePlan{}, err
+	}
+	if opts.SavedPlan == nil {
+		return plan, nil
+	}
+	if err = checkSavedPlan(
+		*opts.SavedPlan, bundle, bundle, opts.PlanOptions, plan.concurrent, plan.serial,
+	); err != nil {
+		return bundlePlan{}, &StaleSavedPlanError{Err: err}
+	}
+	fmt.Fprintln(os.Stderr)
+	IndentedFprintln(indent, os.Stderr, "The computed plan matches the saved plan.")
+	return plan, nil
+}
+
+// applyPlan applies the plan for the bundle, with the same results as [applyBundle].
+func applyPlan(
+	ctx, changeCtx context.Context, indent int, bundle *forklift.FSBundle, plan bundlePlan,
+	retries int,
+) (applied []string, failed []forklift.FailedChange, err error) {
+	// Container images saved in the bundle must be loaded before we reconcile, since otherwise Docker
+	// would try to download them:
+	if err = LoadBundledImages(indent, bundle); err != nil {
+		return nil, nil, errors.Wrap(err, "couldn't load container images saved in the bundle")
+	}
+	concurrentPlan, serialPlan := plan.concurrent, plan.serial
+	var results map[*ReconciliationChange]*changeResult
+	order := serialPlan
 	if serialPlan != nil {
-		return applyChangesSerially(indent, serialPlan)
+		results, err = applyChangesSerially(
+			ctx, changeCtx, indent, serialPlan, concurrentPlan, retries,
+		)
+	} else {
+		results, err = applyChangesConcurrently(ctx, changeCtx, indent, concurrentPlan, retries)
+		order = slices.SortedFunc(maps.Keys(concurrentPlan), func(i, j *ReconciliationChange) int {
+			return cmp.Compare(i.PlanString(), j.PlanString())
+		})
+	}
+	if err != nil {
+		return nil, nil, err
 	}
-	return applyChangesConcurrently(indent, concurrentPlan)
+	fmt.Fprintln(os.Stderr)
+	printChangeResults(indent, order, results)
+	return listSucceededChanges(order, results), recordFailedChanges(order, results),
+		checkChangeResults(order, results)
 }
 
-func applyChangesSerially(indent int, plan []*ReconciliationChange) error {
+func applyChangesSerially(
+	ctx, changeCtx context.Context, indent int, plan []*ReconciliationChange,
+	changeDeps structures.Digraph[*ReconciliationChange], retries int,
+) (map[*ReconciliationChange]*changeResult, error) {
 	const dockerIndent = 2 // docker's indentation is flaky, so we indent extra
 	dc, err := docker.NewClient(
 		// we want to send all of Docker's log messages to stderr:
@@ -430,19 +869,241 @@ func applyChangesSerially(indent int, plan []*ReconciliationChange) error {
 		docker.WithErrorStream(cli.NewIndentedWriter(indent+dockerIndent, os.Stderr)),
 	)
 	if err != nil {
-		return errors.Wrap(err, "couldn't make Docker API client")
+		return nil, errors.Wrap(err, "couldn't make Docker API client")
 	}
 
 	fmt.Fprintln(os.Stderr)
-	IndentedFprintln(indent, os.Stderr, os.Stderr, "Applying changes serially...")
+	IndentedFprintln(indent, os.Stderr, "Applying changes serially...")
 	indent++
+	changeIndirectDeps := changeDeps.ComputeTransitiveClosure()
+	results := make(map[*ReconciliationChange]*changeResult)
 	for _, change := range plan {
+		// Because the serial plan may include dependency cycles, a change might depend on changes which
+		// haven't been attempted yet; we only skip changes which depend on unsuccessful changes.
+		deps := make([]*ReconciliationChange, 0, len(changeIndirectDeps[change]))
+		for dep := range changeIndirectDeps[change] {
+			if _, ok := results[dep]; ok {
+				deps = append(deps, dep)
+			}
+		}
+		if result := skipChange(ctx, deps, results); result != nil {
+			results[change] = result
+			emitChangeFinished(change, result)
+			continue
+		}
 		fmt.Fprintln(os.Stderr)
-		if err := applyReconciliationChange(context.Background(), indent, change, dc); err != nil {
-			return errors.Wrapf(err, "couldn't apply change '%s'", change.PlanString())
+		results[change] = applyChangeWithRetries(ctx, changeCtx, indent, change, dc, retries)
+	}
+	return results, nil
+}
+
+// Change results
+
+const (
+	changeSucceeded  = "succeeded"
+	changeFailed     = "failed"
+	changeSkipped    = "skipped"
+	changeNotStarted = "not started"
+)
+
+// changeResult describes the outcome of an attempt to apply a reconciliation change.
+type changeResult struct {
+	// Outcome is either changeSucceeded, changeFailed, changeSkipped (because some change which it
+	// depends on didn't succeed), or changeNotStarted (because the apply was interrupted).
+	Outcome string
+	// Attempts is the number of times the change was attempted.
+	Attempts int
+	// Duration is the total amount of time spent on attempts (and retry delays) for the change.
+	Duration time.Duration
+	// Err is the error from the last attempt, if the change didn't succeed.
+	Err error
+}
+
+// retryBackoff is the delay before the first retry of a failed change. The delay is doubled for
+// each subsequent retry of the same change, up to maxRetryBackoff.
+const (
+	retryBackoff    = 2 * time.Second
+	maxRetryBackoff = 5 * time.Minute
+)
+
+// backoffDelay returns the delay after the specified number of consecutive failures, where the
+// delay starts at initial and is doubled after each failure, up to maxDelay.
+func backoffDelay(initial, maxDelay time.Duration, failures int) time.Duration {
+	delay := initial
+	for range failures {
+		if delay >= maxDelay/2 {
+			return maxDelay
 		}
+		delay *= 2
 	}
-	return nil
+	return min(delay, maxDelay)
+}
+
+// skipChange returns a skipped result if any of the provided dependencies (which must all have
+// results) of a change was unsuccessful, or a not-started result if the apply was interrupted (i.e.
+// ctx was canceled); otherwise it returns nil.
+func skipChange(
+	ctx context.Context, deps []*ReconciliationChange,
+	results map[*ReconciliationChange]*changeResult,
+) *changeResult {
+	unsuccessful := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		if results[dep].Outcome != changeSucceeded {
+			unsuccessful = append(unsuccessful, dep.String())
+		}
+	}
+	if len(unsuccessful) == 0 {
+		if err := ctx.Err(); err != nil {
+			return &changeResult{
+				Outcome: changeNotStarted,
+				Err:     errors.Wrap(err, "interrupted before the change could be applied"),
+			}
+		}
+		return nil
+	}
+	slices.Sort(unsuccessful)
+	return &changeResult{
+		Outcome: changeSkipped,
+		Err:     errors.Errorf("unsuccessful dependencies: %s", strings.Join(unsuccessful, ", ")),
+	}
+}
+
+// applyChangeWithRetries attempts to apply the change, retrying it (with exponential backoff) up to
+// the specified number of times if it fails. No attempts will be started after ctx is canceled.
+func applyChangeWithRetries(
+	ctx, changeCtx context.Context, indent int, change *ReconciliationChange, dc *docker.Client,
+	retries int,
+) *changeResult {
+	start := time.Now()
+	result := &changeResult{}
+	for {
+		if err := ctx.Err(); err != nil {
+			if result.Attempts == 0 {
+				result.Outcome = changeNotStarted
+				result.Err = errors.Wrap(err, "interrupted before the change could be applied")
+			}
+			break
+		}
+		result.Attempts++
+		emitChangeStarted(change, result.Attempts)
+		attemptStart := time.Now()
+		err := applyReconciliationChange(changeCtx, indent, change, dc)
+		if err == nil {
+			result.Outcome = changeSucceeded
+			result.Err = nil
+			break
+		}
+		result.Outcome = changeFailed
+		result.Err = err
+		emitChangeFailed(change, result.Attempts, time.Since(attemptStart), err)
+		if result.Attempts > retries {
+			break
+		}
+		backoff := backoffDelay(retryBackoff, maxRetryBackoff, result.Attempts-1)
+		IndentedFprintf(
+			indent, os.Stderr, "Failed to apply change '%s' (attempt %d of %d), retrying in %s: %s\n",
+			change.PlanString(), result.Attempts, retries+1, backoff, err.Error(),
+		)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+	}
+	result.Duration = time.Since(start)
+	emitChangeFinished(change, result)
+	return result
+}
+
+// printChangeResults prints a table of the results of the changes, in the specified order.
+func printChangeResults(
+	indent int, changes []*ReconciliationChange, results map[*ReconciliationChange]*changeResult,
+) {
+	IndentedFprintln(indent, os.Stderr, "Results of changes:")
+	w := tabwriter.NewWriter(cli.NewIndentedWriter(indent+1, os.Stderr), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANGE\tOUTCOME\tATTEMPTS\tDURATION\tERROR")
+	for _, change := range changes {
+		result, ok := results[change]
+		if !ok {
+			result = &changeResult{Outcome: changeNotStarted}
+		}
+		errMessage := ""
+		if result.Err != nil {
+			errMessage = strings.ReplaceAll(result.Err.Error(), "\n", " ")
+		}
+		fmt.Fprintf(
+			w, "%s\t%s\t%d\t%s\t%s\n", change.PlanString(), result.Outcome, result.Attempts,
+			result.Duration.Round(time.Millisecond), errMessage,
+		)
+	}
+	_ = w.Flush()
+}
+
+// listSucceededChanges returns the sorted names of the package deployments (or, for Compose apps
+// without known deployments, the names of the Compose apps) which were added, updated, or removed
+// by changes which succeeded. No-op changes and network creation changes are ignored.
+func listSucceededChanges(
+	changes []*ReconciliationChange, results map[*ReconciliationChange]*changeResult,
+) []string {
+	names := make(structures.Set[string])
+	for _, change := range changes {
+		if change.Type == noOpReconciliationChange ||
+			change.Type == createNetworkReconciliationChange {
+			continue
+		}
+		if result, ok := results[change]; !ok || result.Outcome != changeSucceeded {
+			continue
+		}
+		if change.Depl == nil {
+			names.Add(change.Name)
+			continue
+		}
+		names.Add(change.Depl.Name)
+	}
+	return slices.Sorted(names.All())
+}
+
+// recordFailedChanges returns records of the changes which failed.
+func recordFailedChanges(
+	changes []*ReconciliationChange, results map[*ReconciliationChange]*changeResult,
+) []forklift.FailedChange {
+	var failed []forklift.FailedChange
+	for _, change := range changes {
+		if result, ok := results[change]; ok && result.Outcome == changeFailed {
+			failed = append(failed, forklift.FailedChange{
+				Change: change.PlanString(),
+				Error:  result.Err.Error(),
+			})
+		}
+	}
+	return failed
+}
+
+// checkChangeResults returns an error if any change was unsuccessful.
+func checkChangeResults(
+	changes []*ReconciliationChange, results map[*ReconciliationChange]*changeResult,
+) error {
+	outcomes := make(map[string]int)
+	var firstErr error
+	for _, change := range changes {
+		result, ok := results[change]
+		if !ok {
+			result = &changeResult{Outcome: changeNotStarted}
+		}
+		outcomes[result.Outcome]++
+		if result.Outcome == changeFailed && firstErr == nil {
+			firstErr = errors.Wrapf(result.Err, "couldn't apply change '%s'", change.PlanString())
+		}
+	}
+	if outcomes[changeSucceeded] == len(changes) {
+		return nil
+	}
+	if firstErr == nil {
+		firstErr = errors.New("some changes were skipped")
+	}
+	return errors.Wrapf(
+		firstErr, "%d of %d changes failed, %d were skipped, and %d were not started",
+		outcomes[changeFailed], len(changes), outcomes[changeSkipped], outcomes[changeNotStarted],
+	)
 }
 
 func applyReconciliationChange(
@@ -477,6 +1138,31 @@ func applyReconciliationChange(
 		if err := deployApp(ctx, indent, change.Depl, change.Name, dc); err != nil {
 			return errors.Wrapf(err, "couldn't add %s", change.Name)
 		}
+		pruneOrphanedResources(ctx, indent, change, dc)
+		return nil
+	case createNetworkReconciliationChange:
+		IndentedFprintf(
+			indent, os.Stderr, "Creating Docker network %s for package deployment %s...\n",
+			change.Name, change.Depl.Name,
+		)
+		appDef, _, err := loadFingerprintedComposeApp(change.Depl)
+		if err != nil {
+			return err
+		}
+		created, err := dc.EnsureAppNetwork(ctx, appDef, change.Name)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't create network %s", change.Name)
+		}
+		if !created {
+			IndentedFprintf(indent, os.Stderr, "Docker network %s already exists!\n", change.Name)
+		}
+		return nil
+	case noOpReconciliationChange:
+		IndentedFprintf(
+			indent, os.Stderr,
+			"Skipping package deployment %s as Compose app %s, which is already up-to-date...\n",
+			change.Depl.Name, change.Name,
+		)
 		return nil
 	}
 }
@@ -495,53 +1181,106 @@ func deployApp(
 		return nil
 	}
 
-	appDef, err := depl.LoadComposeAppDefinition(true)
+	appDef, _, err := loadFingerprintedComposeApp(depl)
+	if err != nil {
+		return err
+	}
+	readinessTimeout, err := depl.GetReadinessTimeout()
 	if err != nil {
-		return errors.Wrap(err, "couldn't load Compose app definition")
+		return errors.Wrapf(err, "couldn't determine readiness timeout of deployment %s", depl.Name)
 	}
-	if err = dc.DeployApp(ctx, appDef, 0); err != nil {
+	if err = dc.DeployApp(ctx, appDef, readinessTimeout); err != nil {
+		if readinessTimeout > 0 {
+			return errors.Wrapf(
+				err, "couldn't deploy Compose app '%s' and wait (for up to %s) for it to become ready",
+				name, readinessTimeout,
+			)
+		}
 		return errors.Wrapf(err, "couldn't deploy Compose app '%s'", name)
 	}
 	return nil
 }
 
-func applyChangesConcurrently(indent int, plan structures.Digraph[*ReconciliationChange]) error {
+// pruneOrphanedResources removes the Docker networks (and, if specified, the Docker volumes) which
+// are no longer declared by the Compose app updated by the change. Because such resources might
+// still be used by other Compose apps, failures to remove them are only reported as warnings.
+func pruneOrphanedResources(
+	ctx context.Context, indent int, change *ReconciliationChange, dc *docker.Client,
+) {
+	for _, network := range change.OrphanedNetworks {
+		IndentedFprintf(indent, os.Stderr, "Removing orphaned Docker network %s...\n", network)
+		if err := dc.RemoveNetworks(ctx, []string{network}); err != nil {
+			IndentedFprintf(indent+1, os.Stderr, "Warning: %s\n", err.Error())
+		}
+	}
+	for _, volume := range change.OrphanedVolumes {
+		if !change.RemoveOrphanedVolumes {
+			IndentedFprintf(
+				indent, os.Stderr, "Keeping orphaned Docker volume %s, which may be removed manually\n",
+				volume,
+			)
+			continue
+		}
+		IndentedFprintf(indent, os.Stderr, "Removing orphaned Docker volume %s...\n", volume)
+		if err := dc.RemoveVolumes(ctx, []string{volume}); err != nil {
+			IndentedFprintf(indent+1, os.Stderr, "Warning: %s\n", err.Error())
+		}
+	}
+}
+
+func applyChangesConcurrently(
+	ctx, changeCtx context.Context, indent int, plan structures.Digraph[*ReconciliationChange],
+	retries int,
+) (map[*ReconciliationChange]*changeResult, error) {
 	const dockerIndent = 2 // docker's indentation is flaky, so we indent extra
 	dc, err := docker.NewClient(
 		docker.WithConcurrencySafeOutput(),
 		docker.WithOutputStream(cli.NewIndentedWriter(indent+dockerIndent, os.Stderr)),
-		// Docker's usual stderr output looks weird with concurrency, so we discard it.
-		// TODO: direct it to a concurrency-safe logger instead?
-		docker.WithErrorStream(cli.NewIndentedWriter(indent+dockerIndent, io.Discard)),
+		// Docker's usual stderr output looks weird with concurrency, so we don't print it; instead, we
+		// emit it as events (if events are enabled) so that it isn't lost.
+		docker.WithErrorStream(newDockerOutputEventWriter()),
 	)
 	if err != nil {
-		return errors.Wrap(err, "couldn't make Docker API client")
+		return nil, errors.Wrap(err, "couldn't make Docker API client")
 	}
 	fmt.Fprintln(os.Stderr)
 	IndentedFprintln(indent, os.Stderr, "Applying changes concurrently...")
 	indent++
 
 	changeDone := make(map[*ReconciliationChange]chan struct{})
+	results := make(map[*ReconciliationChange]*changeResult)
 	for change := range plan {
 		changeDone[change] = make(chan struct{})
 	}
-	// We don't use the errgroup's context because we don't want one failing service to prevent
-	// bringup of all other services.
-	eg, _ := errgroup.WithContext(context.Background())
+	// Results are written by each change's goroutine only after all of its dependencies' goroutines
+	// have finished writing their results, so we guard the map with a mutex rather than channels.
+	var resultsMu sync.Mutex
+	// We don't use an errgroup's context because we don't want one failing change to prevent
+	// bringup of all other independent changes; only an interruption (which cancels ctx) should do
+	// that. Changes which depend on a failed change are skipped.
+	var wg sync.WaitGroup
 	for change, deps := range plan {
-		eg.Go(func() error {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 			defer close(changeDone[change])
 
 			for dep := range deps {
 				<-changeDone[dep]
 			}
-			if err := applyReconciliationChange(
-				context.Background(), indent, change, dc,
-			); err != nil {
-				return errors.Wrapf(err, "couldn't apply change '%s'", change.PlanString())
+			resultsMu.Lock()
+			result := skipChange(ctx, slices.Collect(maps.Keys(deps)), results)
+			resultsMu.Unlock()
+			if result == nil {
+				result = applyChangeWithRetries(ctx, changeCtx, indent, change, dc, retries)
+			} else {
+				emitChangeFinished(change, result)
 			}
-			return nil
-		})
+			resultsMu.Lock()
+			results[change] = result
+			resultsMu.Unlock()
+		}()
 	}
-	return eg.Wait()
+	wg.Wait()
+	return results, nil
 }
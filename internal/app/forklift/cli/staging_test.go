@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/forklift-run/forklift/internal/app/forklift"
+)
+
+func TestListSucceededChanges(t *testing.T) {
+	web := &forklift.ResolvedDepl{Depl: forklift.Depl{Name: "apps/web"}}
+	db := &forklift.ResolvedDepl{Depl: forklift.Depl{Name: "apps/db"}}
+	docs := &forklift.ResolvedDepl{Depl: forklift.Depl{Name: "apps/docs"}}
+	caddy := &forklift.ResolvedDepl{Depl: forklift.Depl{Name: "infra/caddy"}}
+	changes := []*ReconciliationChange{
+		{Name: "apps_web", Type: updateReconciliationChange, Depl: web},
+		{Name: "apps_db", Type: addReconciliationChange, Depl: db},
+		{Name: "apps_docs", Type: noOpReconciliationChange, Depl: docs},
+		{Name: "caddy", Type: createNetworkReconciliationChange, Depl: caddy},
+		{Name: "infra_caddy", Type: updateReconciliationChange, Depl: caddy},
+		{Name: "old_app", Type: removeReconciliationChange},
+		{Name: "other_app", Type: removeReconciliationChange},
+	}
+	results := map[*ReconciliationChange]*changeResult{
+		changes[0]: {Outcome: changeSucceeded},
+		changes[1]: {Outcome: changeFailed},
+		changes[2]: {Outcome: changeSucceeded},
+		changes[3]: {Outcome: changeSucceeded},
+		changes[4]: {Outcome: changeSkipped},
+		changes[5]: {Outcome: changeSucceeded},
+		// changes[6] has no result
+	}
+
+	expected := []string{"apps/web", "old_app"}
+	if actual := listSucceededChanges(changes, results); !slices.Equal(actual, expected) {
+		t.Errorf("expected succeeded changes %v, got %v", expected, actual)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	for _, test := range []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 0, expected: 2 * time.Second},
+		{failures: 1, expected: 4 * time.Second},
+		{failures: 3, expected: 16 * time.Second},
+		{failures: 4, expected: 30 * time.Second},
+		{failures: 1000, expected: 30 * time.Second},
+	} {
+		if actual := backoffDelay(2*time.Second, 30*time.Second, test.failures); actual != test.expected {
+			t.Errorf(
+				"expected delay %s after %d failures, got %s", test.expected, test.failures, actual,
+			)
+		}
+	}
+}
+
+func TestSkipChange(t *testing.T) {
+	web := &ReconciliationChange{Name: "apps_web", Type: addReconciliationChange}
+	db := &ReconciliationChange{Name: "apps_db", Type: addReconciliationChange}
+	caddy := &ReconciliationChange{Name: "infra_caddy", Type: updateReconciliationChange}
+	results := map[*ReconciliationChange]*changeResult{
+		web:   {Outcome: changeSucceeded},
+		db:    {Outcome: changeFailed},
+		caddy: {Outcome: changeSkipped},
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, test := range []struct {
+		name     string
+		ctx      context.Context
+		deps     []*ReconciliationChange
+		expected string
+	}{
+		{name: "no dependencies", ctx: context.Background()},
+		{name: "succeeded dependency", ctx: context.Background(), deps: []*ReconciliationChange{web}},
+		{
+			name:     "failed dependency",
+			ctx:      context.Background(),
+			deps:     []*ReconciliationChange{web, db},
+			expected: changeSkipped,
+		},
+		{
+			name:     "skipped dependency",
+			ctx:      context.Background(),
+			deps:     []*ReconciliationChange{caddy},
+			expected: changeSkipped,
+		},
+		{name: "interrupted", ctx: canceled, expected: changeNotStarted},
+		{
+			name:     "interrupted with a failed dependency",
+			ctx:      canceled,
+			deps:     []*ReconciliationChange{db},
+			expected: changeSkipped,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			result := skipChange(test.ctx, test.deps, results)
+			switch {
+			case test.expected == "" && result != nil:
+				t.Errorf("expected change not to be skipped, got %s: %s", result.Outcome, result.Err)
+			case test.expected != "" && result == nil:
+				t.Errorf("expected change outcome %s, got nil", test.expected)
+			case test.expected != "" && result.Outcome != test.expected:
+				t.Errorf("expected change outcome %s, got %s", test.expected, result.Outcome)
+			}
+		})
+	}
+}
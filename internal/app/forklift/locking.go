@@ -0,0 +1,129 @@
+package forklift
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// A FileLock is an advisory lock held by the current process on a lock file. The lock file records
+// the PID of the process holding the lock and when the lock was acquired, so that other processes
+// can report who holds the lock.
+type FileLock struct {
+	file *os.File
+}
+
+// A LockedError is returned when a lock file is locked by another process.
+type LockedError struct {
+	// Path is the path of the lock file.
+	Path string
+	// PID is the process ID of the process holding the lock, or 0 if it's unknown.
+	PID int
+	// Since is when the lock was acquired, or the zero time if it's unknown.
+	Since time.Time
+}
+
+func (e *LockedError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("%s is locked by another process", e.Path)
+	}
+	return fmt.Sprintf(
+		"%s is locked by PID %d since %s", e.Path, e.PID, e.Since.Format(time.RFC3339),
+	)
+}
+
+// lockPollInterval is how often we try again to acquire a lock which is held by another process.
+const lockPollInterval = 100 * time.Millisecond
+
+// LockFile acquires an exclusive advisory lock on the file at the specified path (creating the file
+// if it doesn't already exist), waiting up to the specified amount of time for any other process to
+// release the lock. If the lock is still held by another process after the timeout, a
+// [*LockedError] is returned. If wait is non-nil, it's called once with that error if the lock
+// can't be acquired immediately.
+func LockFile(
+	lockPath string, timeout time.Duration, wait func(err *LockedError),
+) (*FileLock, error) {
+	if err := EnsureExists(filepath.Dir(lockPath)); err != nil {
+		return nil, errors.Wrapf(err, "couldn't ensure the existence of %s", filepath.Dir(lockPath))
+	}
+	const perm = 0o644 // owner rw, group r, public r
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, perm)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't open lock file %s", lockPath)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for waited := false; ; waited = true {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, errors.Wrapf(err, "couldn't lock %s", lockPath)
+		}
+		if locked {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			_ = file.Close()
+			return nil, readLockHolder(lockPath)
+		}
+		if !waited && wait != nil {
+			wait(readLockHolder(lockPath))
+		}
+		time.Sleep(min(lockPollInterval, time.Until(deadline)))
+	}
+
+	lock := &FileLock{file: file}
+	if err = lock.recordHolder(); err != nil {
+		_ = lock.Unlock()
+		return nil, errors.Wrapf(err, "couldn't record the holder of the lock on %s", lockPath)
+	}
+	return lock, nil
+}
+
+// recordHolder records the current process and time in the lock file.
+func (l *FileLock) recordHolder() error {
+	if err := l.file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := l.file.WriteAt(
+		fmt.Appendf(nil, "%d\n%s\n", os.Getpid(), time.Now().Format(time.RFC3339)), 0,
+	)
+	return err
+}
+
+// readLockHolder returns a [*LockedError] describing the holder of the lock file at the specified
+// path, as recorded in the lock file.
+func readLockHolder(lockPath string) *LockedError {
+	lockedErr := &LockedError{Path: lockPath}
+	contents, err := os.ReadFile(lockPath)
+	if err != nil {
+		return lockedErr
+	}
+	pid, since, _ := strings.Cut(strings.TrimSpace(string(contents)), "\n")
+	if lockedErr.PID, err = strconv.Atoi(pid); err != nil {
+		lockedErr.PID = 0
+		return lockedErr
+	}
+	lockedErr.Since, _ = time.Parse(time.RFC3339, strings.TrimSpace(since))
+	return lockedErr
+}
+
+// Path returns the path of the lock file.
+func (l *FileLock) Path() string {
+	return l.file.Name()
+}
+
+// Unlock releases the lock. The lock file is left in place, since removing it could allow two
+// processes to hold locks on different files at the same path.
+func (l *FileLock) Unlock() error {
+	if err := unlockFile(l.file); err != nil {
+		_ = l.file.Close()
+		return errors.Wrapf(err, "couldn't unlock %s", l.file.Name())
+	}
+	return errors.Wrapf(l.file.Close(), "couldn't close lock file %s", l.file.Name())
+}
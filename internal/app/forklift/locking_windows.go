@@ -0,0 +1,38 @@
+//go:build windows
+
+package forklift
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+)
+
+// lockOffsetHigh is the high word of the offset of the byte range which we lock. Windows locks are
+// mandatory for the locked byte range, so we lock a range far beyond the end of the lock file;
+// this way, other processes can still read the lock holder recorded in the lock file.
+const lockOffsetHigh = 0x7fffffff
+
+// tryLockFile attempts to acquire an exclusive lock on the file without blocking, returning false
+// if the lock is held by another file handle.
+func tryLockFile(file *os.File) (locked bool, err error) {
+	overlapped := &windows.Overlapped{OffsetHigh: lockOffsetHigh}
+	err = windows.LockFileEx(
+		windows.Handle(file.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, overlapped,
+	)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, windows.ERROR_LOCK_VIOLATION):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func unlockFile(file *os.File) error {
+	overlapped := &windows.Overlapped{OffsetHigh: lockOffsetHigh}
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, overlapped)
+}
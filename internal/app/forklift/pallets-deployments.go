@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
 	dct "github.com/compose-spec/compose-go/v2/types"
@@ -75,6 +76,9 @@ func (d *ResolvedDepl) Check() (errs []error) {
 			d.Pkg.Repo.Version, d.PkgReq.Repo.VersionLock.Version,
 		))
 	}
+	if _, err := d.GetReadinessTimeout(); err != nil {
+		errs = append(errs, err)
+	}
 	return errs
 }
 
@@ -171,6 +175,27 @@ func (d *ResolvedDepl) LoadComposeAppDefinition(resolvePaths bool) (*dct.Project
 	return appDef, nil
 }
 
+// GetReadinessTimeout returns the maximum duration to wait for the deployment's Docker Compose app
+// to become ready after it is deployed, as declared by the deployment or (if the deployment doesn't
+// declare it) by the package. A duration of zero means that there is no time limit.
+func (d *ResolvedDepl) GetReadinessTimeout() (time.Duration, error) {
+	timeout := d.Pkg.Def.Deployment.ReadinessTimeout
+	if d.Def.ReadinessTimeout != "" {
+		timeout = d.Def.ReadinessTimeout
+	}
+	if timeout == "" {
+		return 0, nil
+	}
+	parsed, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, errors.Wrapf(err, "couldn't parse readiness timeout '%s'", timeout)
+	}
+	if parsed < 0 {
+		return 0, errors.Errorf("readiness timeout '%s' is negative", timeout)
+	}
+	return parsed, nil
+}
+
 // GetComposeAppName converts the deployment's name into a string which is allowed for use as a
 // Docker Compose app name. It assumes that the resulting name will not be excessively long for
 // Docker Compose.
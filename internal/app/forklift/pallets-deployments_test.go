@@ -0,0 +1,43 @@
+package forklift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/forklift-run/forklift/pkg/core"
+)
+
+func TestGetReadinessTimeout(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		pkg      string
+		depl     string
+		expected time.Duration
+		invalid  bool
+	}{
+		{name: "undeclared"},
+		{name: "declared by package", pkg: "2m30s", expected: 150 * time.Second},
+		{name: "declared by deployment", depl: "10s", expected: 10 * time.Second},
+		{name: "overridden by deployment", pkg: "2m", depl: "0s"},
+		{name: "unparseable", pkg: "two minutes", invalid: true},
+		{name: "negative", depl: "-1m", invalid: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			depl := &ResolvedDepl{
+				Depl: Depl{Def: DeplDef{ReadinessTimeout: test.depl}},
+				Pkg: &core.FSPkg{Pkg: core.Pkg{Def: core.PkgDef{
+					Deployment: core.PkgDeplSpec{ReadinessTimeout: test.pkg},
+				}}},
+			}
+			timeout, err := depl.GetReadinessTimeout()
+			switch {
+			case test.invalid && err == nil:
+				t.Error("expected an error for an invalid readiness timeout")
+			case !test.invalid && err != nil:
+				t.Errorf("couldn't get readiness timeout: %s", err)
+			case timeout != test.expected:
+				t.Errorf("expected readiness timeout %s, got %s", test.expected, timeout)
+			}
+		})
+	}
+}
@@ -158,6 +158,10 @@ type DeplDef struct {
 	Features []string `yaml:"features,omitempty"`
 	// Disabled represents whether the deployment should be ignored.
 	Disabled bool `yaml:"disabled,omitempty"`
+	// ReadinessTimeout is the maximum duration (e.g. `2m30s`) to wait for the deployment's Docker
+	// Compose app to become running and healthy after it is deployed. If specified, this overrides the
+	// readiness timeout declared by the package.
+	ReadinessTimeout string `yaml:"readiness-timeout,omitempty"`
 }
 
 // Imports
@@ -1,6 +1,8 @@
 package forklift
 
 import (
+	"time"
+
 	"github.com/forklift-run/forklift/pkg/core"
 )
 
@@ -9,6 +11,11 @@ import (
 const (
 	StageStoreManifestFile     = "forklift-stage-store.yml"
 	StageStoreManifestSwapFile = "forklift-stage-store-swap.yml"
+	// StageStoreLockFile is the file used to lock the stage store against concurrent modifications.
+	StageStoreLockFile = "forklift-stage-store.lock"
+	// StageStoreApplyJournalFile is the append-only file recording every attempt to apply a staged
+	// pallet bundle, with one JSON object per line.
+	StageStoreApplyJournalFile = "forklift-apply-journal.jsonl"
 )
 
 // FSStageStore is a source of bundles rooted at a single path, with bundles stored as
@@ -29,6 +36,13 @@ type StageStoreManifest struct {
 	ForkliftVersion string `yaml:"forklift-version"`
 	// Stages keeps track of special stages
 	Stages StagesSpec `yaml:"staged"`
+	// Retention is the policy for which staged pallet bundles to keep when the stage store is
+	// garbage-collected. If it's not set, the stage store is never garbage-collected automatically.
+	Retention *StageRetentionSpec `yaml:"retention,omitempty"`
+	// BundleTrust specifies which keys are trusted to sign staged pallet bundles, and whether staged
+	// pallet bundles must be signed by a trusted key. If it's not set, staged pallet bundles need not
+	// be signed.
+	BundleTrust *BundleTrustConfig `yaml:"bundle-trust,omitempty"`
 }
 
 // StagesSpec describes the state of a stage store.
@@ -38,6 +52,14 @@ type StagesSpec struct {
 	Next int `yaml:"next,omitempty"`
 	// NextFailed records whether the next staged pallet bundle had failed to be applied.
 	NextFailed bool `yaml:"next-failed,omitempty"`
+	// NextInterrupted records whether the last attempt to apply the next staged pallet bundle was
+	// interrupted (e.g. by a signal) before it could finish, so that some changes might not have been
+	// made. An interruption isn't considered to be a failure of the bundle.
+	NextInterrupted bool `yaml:"next-interrupted,omitempty"`
+	// NextApplied is the list of names of the package deployments (or of Docker Compose apps without
+	// known deployments) which were successfully updated by partial applications of the next staged
+	// pallet bundle. It's cleared once the next staged pallet bundle is fully applied.
+	NextApplied []string `yaml:"next-applied,omitempty"`
 	// History is the stack of staged pallet bundles which have been applied successfully, with the
 	// most-recently-applied bundle last. The most-recently-applied bundle can be used as a fallback
 	// If the next staged pallet bundle (if it exists) is not applied successfully.
@@ -45,3 +67,81 @@ type StagesSpec struct {
 	// Names is a list of aliases for staged pallet bundles.
 	Names map[string]int `yaml:"names,omitempty"`
 }
+
+// A StageRetentionSpec is a policy for which staged pallet bundles to keep when the stage store is
+// garbage-collected. A staged pallet bundle is kept if any part of the policy keeps it; all other
+// staged pallet bundles are deleted. Regardless of the policy, the next staged pallet bundle to be
+// applied, the last successfully-applied staged pallet bundle, and the staged pallet bundle which
+// was successfully applied before it are always kept.
+type StageRetentionSpec struct {
+	// KeepApplied is the number of most-recently-applied staged pallet bundles in the history to
+	// keep.
+	KeepApplied int `yaml:"keep-applied,omitempty"`
+	// KeepNamed is whether to keep all staged pallet bundles which have names.
+	KeepNamed bool `yaml:"keep-named,omitempty"`
+	// KeepYoungerThan is a duration (e.g. 168h) such that all staged pallet bundles which were
+	// staged more recently than that are kept.
+	KeepYoungerThan string `yaml:"keep-younger-than,omitempty"`
+}
+
+// Bundle trust
+
+// A BundleTrustConfig specifies which public keys are trusted to sign staged pallet bundles, and
+// whether staged pallet bundles must be signed by a trusted key.
+type BundleTrustConfig struct {
+	// Enforce is whether staged pallet bundles must have valid signatures by trusted keys in order
+	// to be applied or to be set as the next staged pallet bundle to apply.
+	Enforce bool `yaml:"enforce,omitempty"`
+	// Keys is the list of trusted public keys.
+	Keys []TrustedKey `yaml:"keys,omitempty"`
+}
+
+// A TrustedKey is a public key trusted to sign staged pallet bundles.
+type TrustedKey struct {
+	// Name is a human-readable name for the key.
+	Name string `yaml:"name,omitempty"`
+	// PublicKey is the base64-encoded Ed25519 public key.
+	PublicKey string `yaml:"public-key"`
+}
+
+// Apply journal
+
+// Results of attempts to apply staged pallet bundles
+const (
+	ApplySucceeded   = "success"
+	ApplyFailed      = "failure"
+	ApplyInterrupted = "interrupted"
+)
+
+// An ApplyRecord is an entry in the stage store's apply journal, describing an attempt to apply a
+// staged pallet bundle.
+type ApplyRecord struct {
+	// Bundle is the index of the staged pallet bundle which was applied.
+	Bundle int `json:"bundle" yaml:"bundle"`
+	// Fallback is whether the staged pallet bundle was applied as a fallback, because the next
+	// staged pallet bundle had failed or couldn't be applied.
+	Fallback bool `json:"fallback,omitempty" yaml:"fallback,omitempty"`
+	// Partial is whether only some package deployments of the staged pallet bundle were selected to
+	// be applied.
+	Partial bool `json:"partial,omitempty" yaml:"partial,omitempty"`
+	// ToolVersion is the version of the Forklift tool which applied the staged pallet bundle.
+	ToolVersion string `json:"tool-version" yaml:"tool-version"`
+	// Started is when the attempt started.
+	Started time.Time `json:"started" yaml:"started"`
+	// Finished is when the attempt finished.
+	Finished time.Time `json:"finished" yaml:"finished"`
+	// Result is either "success", "failure", or "interrupted".
+	Result string `json:"result" yaml:"result"`
+	// Error is the error message of the attempt, if it didn't succeed.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+	// FailedChanges lists the changes which failed, if any.
+	FailedChanges []FailedChange `json:"failed-changes,omitempty" yaml:"failed-changes,omitempty"`
+}
+
+// A FailedChange describes a change which failed during an attempt to apply a staged pallet bundle.
+type FailedChange struct {
+	// Change is a description of the change.
+	Change string `json:"change" yaml:"change"`
+	// Error is the error message from the last attempt at the change.
+	Error string `json:"error" yaml:"error"`
+}
@@ -1,6 +1,7 @@
 package forklift
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
@@ -8,11 +9,14 @@ import (
 	"path/filepath"
 	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 
 	"github.com/forklift-run/forklift/pkg/core"
+	"github.com/forklift-run/forklift/pkg/structures"
 )
 
 // FSStageStore
@@ -147,6 +151,8 @@ func (s *FSStageStore) GetBundlePath(index int) string {
 // so no stage will be applied next.
 func (s *FSStageStore) SetNext(index int) {
 	s.Manifest.Stages.NextFailed = false
+	s.Manifest.Stages.NextInterrupted = false
+	s.Manifest.Stages.NextApplied = nil
 	s.Manifest.Stages.Next = index
 }
 
@@ -192,20 +198,62 @@ func (s *FSStageStore) RecordNextSuccess(succeeded bool) {
 		return
 	}
 	s.Manifest.Stages.NextFailed = !succeeded
+	s.Manifest.Stages.NextInterrupted = false
 	if !succeeded {
 		return
 	}
+	s.Manifest.Stages.NextApplied = nil
 	if current, ok := s.GetCurrent(); ok && s.Manifest.Stages.Next == current {
 		return
 	}
 	s.Manifest.Stages.History = append(s.Manifest.Stages.History, s.Manifest.Stages.Next)
 }
 
+// RecordNextPartialSuccess records whether a partial application of the stage which was to be
+// applied was successful, as well as the names of the package deployments which were applied.
+// Unlike a full application, a successful partial application doesn't add the stage to the history.
+func (s *FSStageStore) RecordNextPartialSuccess(succeeded bool, applied []string) {
+	if s.Manifest.Stages.Next == 0 {
+		return
+	}
+	s.Manifest.Stages.NextFailed = !succeeded
+	s.Manifest.Stages.NextInterrupted = false
+	if !succeeded {
+		return
+	}
+	merged := make(structures.Set[string])
+	merged.Add(s.Manifest.Stages.NextApplied...)
+	merged.Add(applied...)
+	s.Manifest.Stages.NextApplied = slices.Sorted(merged.All())
+}
+
+// GetNextApplied returns the names of the package deployments which were successfully applied by
+// partial applications of the next stage.
+func (s *FSStageStore) GetNextApplied() []string {
+	return s.Manifest.Stages.NextApplied
+}
+
 // NextFailed returns whether the next stage to be applied has encountered a failed application.
 func (s *FSStageStore) NextFailed() bool {
 	return s.Manifest.Stages.NextFailed
 }
 
+// RecordNextInterrupted records that an application of the stage which was to be applied was
+// interrupted before it could finish. Because the interruption doesn't indicate a problem with the
+// stage, the stage will still be applied next (unless it had already failed).
+func (s *FSStageStore) RecordNextInterrupted() {
+	if s.Manifest.Stages.Next == 0 {
+		return
+	}
+	s.Manifest.Stages.NextInterrupted = true
+}
+
+// NextInterrupted returns whether the last application of the next stage to be applied was
+// interrupted before it could finish.
+func (s *FSStageStore) NextInterrupted() bool {
+	return s.Manifest.Stages.NextInterrupted
+}
+
 // RemoveBundleNames removes all names for the specified bundle.
 func (s *FSStageStore) RemoveBundleNames(index int) {
 	for name, namedIndex := range s.Manifest.Stages.Names {
@@ -228,6 +276,96 @@ func (s *FSStageStore) RemoveBundleHistory(index int) {
 	s.Manifest.Stages.History = newHistory
 }
 
+// GetBundleTrust returns the stage store's configuration of which keys are trusted to sign staged
+// pallet bundles. If the stage store has no such configuration, an empty configuration (which
+// trusts no keys and doesn't require staged pallet bundles to be signed) is returned.
+func (s *FSStageStore) GetBundleTrust() BundleTrustConfig {
+	if s.Manifest.BundleTrust == nil {
+		return BundleTrustConfig{}
+	}
+	return *s.Manifest.BundleTrust
+}
+
+// SetBundleTrust updates the stage store's configuration of which keys are trusted to sign staged
+// pallet bundles. The change must be committed with [FSStageStore.CommitState].
+func (s *FSStageStore) SetBundleTrust(config BundleTrustConfig) {
+	if !config.Enforce && len(config.Keys) == 0 {
+		s.Manifest.BundleTrust = nil
+		return
+	}
+	s.Manifest.BundleTrust = &config
+}
+
+// PlanGC returns a numerically-sorted list of the staged pallet bundles in the store which would be
+// deleted according to the provided retention policy, if the store were garbage-collected at the
+// specified time.
+func (s *FSStageStore) PlanGC(
+	policy StageRetentionSpec, now time.Time,
+) (deletions []int, err error) {
+	maxAge, err := policy.ParseKeepYoungerThan()
+	if err != nil {
+		return nil, err
+	}
+	indices, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make(structures.Set[int])
+	next, _ := s.GetNext()
+	current, _ := s.GetCurrent()
+	rollback, _ := s.GetRollback()
+	kept.Add(next, current, rollback)
+	// Note: the history may list a bundle multiple times (e.g. if it was re-applied after a
+	// rollback), so we count distinct bundles, starting from the most recently-applied bundle:
+	history := s.Manifest.Stages.History
+	applied := make(structures.Set[int])
+	for i := len(history) - 1; i >= 0 && len(applied) < policy.KeepApplied; i-- {
+		applied.Add(history[i])
+	}
+	kept.Add(slices.Collect(applied.All())...)
+	if policy.KeepNamed {
+		for _, index := range s.Manifest.Stages.Names {
+			kept.Add(index)
+		}
+	}
+
+	deletions = make([]int, 0, len(indices))
+	for _, index := range indices {
+		if kept.Has(index) {
+			continue
+		}
+		if maxAge > 0 {
+			staged, err := s.getStagedTime(index)
+			if err != nil {
+				return nil, err
+			}
+			if now.Sub(staged) < maxAge {
+				continue
+			}
+		}
+		deletions = append(deletions, index)
+	}
+	return deletions, nil
+}
+
+// getStagedTime returns the time when the specified staged pallet bundle was staged, which is when
+// its manifest file was written (or, if the bundle has no manifest file because staging was
+// interrupted, when its directory was last modified).
+func (s *FSStageStore) getStagedTime(index int) (time.Time, error) {
+	bundlePath := fmt.Sprintf("%d", index)
+	info, err := fs.Stat(s.FS, path.Join(bundlePath, BundleManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		info, err = fs.Stat(s.FS, bundlePath)
+	}
+	if err != nil {
+		return time.Time{}, errors.Wrapf(
+			err, "couldn't determine when staged pallet bundle %d was staged", index,
+		)
+	}
+	return info.ModTime(), nil
+}
+
 // CommitState atomically updates the stage store's manifest file.
 // Warning: on non-Unix platforms, the update is not entirely atomic!
 func (s *FSStageStore) CommitState() error {
@@ -257,6 +395,91 @@ func (s *FSStageStore) CommitState() error {
 	return nil
 }
 
+// AppendApplyRecord appends the record to the stage store's apply journal.
+func (s *FSStageStore) AppendApplyRecord(record ApplyRecord) (err error) {
+	marshaled, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrap(err, "couldn't marshal apply record")
+	}
+	journalPath := path.Join(s.FS.Path(), StageStoreApplyJournalFile)
+	const perm = 0o644 // owner rw, group r, public r
+	file, err := os.OpenFile(
+		filepath.FromSlash(journalPath), os.O_RDWR|os.O_CREATE|os.O_APPEND, perm,
+	)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't open apply journal %s", journalPath)
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil && err == nil {
+			err = errors.Wrapf(cerr, "couldn't close apply journal %s", journalPath)
+		}
+	}()
+	line := append(marshaled, '\n')
+	// If a previous write was interrupted, the journal's incomplete last line must be terminated so
+	// that it doesn't corrupt the new line:
+	terminated, err := endsWithNewline(file)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't read apply journal %s", journalPath)
+	}
+	if !terminated {
+		line = append([]byte{'\n'}, line...)
+	}
+	// We write the whole line at once, so that an interrupted write can only leave an incomplete
+	// last line:
+	if _, err = file.Write(line); err != nil {
+		return errors.Wrapf(err, "couldn't append to apply journal %s", journalPath)
+	}
+	return nil
+}
+
+// endsWithNewline checks whether the file is empty or ends with a newline.
+func endsWithNewline(file *os.File) (bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() == 0 {
+		return true, nil
+	}
+	last := make([]byte, 1)
+	if _, err = file.ReadAt(last, info.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] == '\n', nil
+}
+
+// LoadApplyJournal loads all records from the stage store's apply journal, from oldest to newest.
+// An incomplete last line (e.g. from an interrupted write) is ignored. Any other lines which can't
+// be parsed (e.g. lines which were corrupted) are skipped, and an error describing each skipped
+// line is returned in skipped.
+func (s *FSStageStore) LoadApplyJournal() (records []ApplyRecord, skipped []error, err error) {
+	contents, err := fs.ReadFile(s.FS, StageStoreApplyJournalFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, nil
+		}
+		return nil, nil, errors.Wrapf(
+			err, "couldn't read apply journal %s/%s", s.FS.Path(), StageStoreApplyJournalFile,
+		)
+	}
+	lines := strings.Split(string(contents), "\n")
+	// The last element is either empty (if the journal ends with a newline) or incomplete:
+	lines = lines[:len(lines)-1]
+	records = make([]ApplyRecord, 0, len(lines))
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		var record ApplyRecord
+		if err = json.Unmarshal([]byte(line), &record); err != nil {
+			skipped = append(skipped, errors.Wrapf(err, "couldn't parse line %d of apply journal", i+1))
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, skipped, nil
+}
+
 // StageStoreManifest
 
 // loadStageStoreManifest loads a StageStoreManifest from the specified file path in the provided
@@ -289,3 +512,25 @@ func (m StageStoreManifest) Write(outputPath string) error {
 	}
 	return nil
 }
+
+// StageRetentionSpec
+
+// ParseKeepYoungerThan parses the retention policy's KeepYoungerThan duration. It returns 0 if the
+// duration isn't set.
+func (r StageRetentionSpec) ParseKeepYoungerThan() (time.Duration, error) {
+	if r.KeepYoungerThan == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(r.KeepYoungerThan)
+	if err != nil {
+		return 0, errors.Wrapf(
+			err, "couldn't parse retention policy's keep-younger-than duration %s", r.KeepYoungerThan,
+		)
+	}
+	if duration < 0 {
+		return 0, errors.Errorf(
+			"retention policy's keep-younger-than duration %s is negative", r.KeepYoungerThan,
+		)
+	}
+	return duration, nil
+}
@@ -0,0 +1,236 @@
+package forklift
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newTestStageStore makes a stage store in a temporary directory, with an empty directory for each
+// of the specified staged pallet bundles.
+func newTestStageStore(t *testing.T, indices ...int) *FSStageStore {
+	t.Helper()
+	dir := t.TempDir()
+	if err := EnsureFSStageStore(DirFS(filepath.ToSlash(dir)), "stages", "v0.1.0"); err != nil {
+		t.Fatalf("couldn't make stage store: %s", err)
+	}
+	store, err := LoadFSStageStore(DirFS(filepath.ToSlash(dir)), "stages")
+	if err != nil {
+		t.Fatalf("couldn't load stage store: %s", err)
+	}
+	for _, index := range indices {
+		if err := os.Mkdir(filepath.FromSlash(store.GetBundlePath(index)), 0o755); err != nil {
+			t.Fatalf("couldn't make staged pallet bundle %d: %s", index, err)
+		}
+	}
+	return store
+}
+
+func TestPlanGC(t *testing.T) {
+	now := time.Now()
+	for _, test := range []struct {
+		name     string
+		history  []int
+		next     int
+		names    map[string]int
+		policy   StageRetentionSpec
+		old      []int
+		expected []int
+	}{
+		{
+			name:     "empty policy keeps next, current, and rollback",
+			history:  []int{1, 2, 3},
+			next:     5,
+			expected: []int{1, 4},
+		},
+		{
+			name:     "keep applied",
+			history:  []int{1, 2, 3},
+			next:     5,
+			policy:   StageRetentionSpec{KeepApplied: 3},
+			expected: []int{4},
+		},
+		{
+			name:     "keep applied counts distinct bundles",
+			history:  []int{1, 2, 3, 2, 3},
+			next:     5,
+			policy:   StageRetentionSpec{KeepApplied: 3},
+			expected: []int{4},
+		},
+		{
+			name:     "keep applied with a short history",
+			history:  []int{2, 2},
+			policy:   StageRetentionSpec{KeepApplied: 3},
+			expected: []int{1, 3, 4, 5},
+		},
+		{
+			name:     "keep named",
+			history:  []int{3},
+			names:    map[string]int{"stable": 1},
+			policy:   StageRetentionSpec{KeepNamed: true},
+			expected: []int{2, 4, 5},
+		},
+		{
+			name:     "keep younger than",
+			history:  []int{3},
+			policy:   StageRetentionSpec{KeepYoungerThan: "1h"},
+			old:      []int{1, 2},
+			expected: []int{1, 2},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			store := newTestStageStore(t, 1, 2, 3, 4, 5)
+			store.Manifest.Stages.History = test.history
+			store.Manifest.Stages.Next = test.next
+			store.Manifest.Stages.Names = test.names
+			for _, index := range test.old {
+				mtime := now.Add(-2 * time.Hour)
+				bundlePath := filepath.FromSlash(store.GetBundlePath(index))
+				if err := os.Chtimes(bundlePath, mtime, mtime); err != nil {
+					t.Fatalf("couldn't change modification time of %s: %s", bundlePath, err)
+				}
+			}
+
+			deletions, err := store.PlanGC(test.policy, now)
+			if err != nil {
+				t.Fatalf("couldn't plan garbage collection: %s", err)
+			}
+			if !slices.Equal(deletions, test.expected) {
+				t.Errorf("expected deletions %v, got %v", test.expected, deletions)
+			}
+		})
+	}
+}
+
+func TestPlanGCInvalidPolicy(t *testing.T) {
+	store := newTestStageStore(t, 1)
+	if _, err := store.PlanGC(
+		StageRetentionSpec{KeepYoungerThan: "-1h"}, time.Now(),
+	); err == nil {
+		t.Error("expected an error for a negative keep-younger-than duration")
+	}
+}
+
+func TestApplyJournal(t *testing.T) {
+	store := newTestStageStore(t)
+	records, skipped, err := store.LoadApplyJournal()
+	if err != nil || len(records) != 0 || len(skipped) != 0 {
+		t.Fatalf("expected an empty journal, got %v, %v, %v", records, skipped, err)
+	}
+
+	first := ApplyRecord{Bundle: 1, ToolVersion: "v0.1.0", Result: "success"}
+	if err = store.AppendApplyRecord(first); err != nil {
+		t.Fatalf("couldn't append record: %s", err)
+	}
+	journalPath := filepath.Join(filepath.FromSlash(store.Path()), StageStoreApplyJournalFile)
+	// Simulate an interrupted write of a record, followed by a corrupted line:
+	file, err := os.OpenFile(journalPath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("couldn't open journal: %s", err)
+	}
+	if _, err = file.WriteString("not json\n{\"bundle\": 2, \"resu"); err != nil {
+		t.Fatalf("couldn't write to journal: %s", err)
+	}
+	if err = file.Close(); err != nil {
+		t.Fatalf("couldn't close journal: %s", err)
+	}
+
+	records, skipped, err = store.LoadApplyJournal()
+	if err != nil {
+		t.Fatalf("couldn't load journal: %s", err)
+	}
+	if len(records) != 1 || records[0].Bundle != 1 {
+		t.Errorf("expected only the first record, got %v", records)
+	}
+	if len(skipped) != 1 {
+		t.Errorf("expected the corrupted line to be skipped, got %v", skipped)
+	}
+
+	second := ApplyRecord{Bundle: 3, ToolVersion: "v0.1.0", Result: "failure", Error: "oops"}
+	if err = store.AppendApplyRecord(second); err != nil {
+		t.Fatalf("couldn't append record: %s", err)
+	}
+	records, skipped, err = store.LoadApplyJournal()
+	if err != nil {
+		t.Fatalf("couldn't load journal: %s", err)
+	}
+	if len(records) != 2 || records[0].Bundle != 1 || records[1].Bundle != 3 ||
+		records[1].Error != "oops" {
+		t.Errorf("expected the first and second records, got %v", records)
+	}
+	// The incomplete line should have been terminated, so now it's skipped along with the corrupted
+	// line:
+	if len(skipped) != 2 {
+		t.Errorf("expected two skipped lines, got %v", skipped)
+	}
+}
+
+func TestRecordNextInterrupted(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		next       int
+		failed     bool
+		record     func(store *FSStageStore)
+		expected   bool
+		pending    bool
+		nextFailed bool
+	}{
+		{
+			name:     "interrupted",
+			next:     1,
+			record:   func(store *FSStageStore) { store.RecordNextInterrupted() },
+			expected: true,
+			pending:  true,
+		},
+		{
+			name:       "interrupted after a failure",
+			next:       1,
+			failed:     true,
+			record:     func(store *FSStageStore) { store.RecordNextInterrupted() },
+			expected:   true,
+			pending:    true,
+			nextFailed: true,
+		},
+		{
+			name:   "no next stage",
+			record: func(store *FSStageStore) { store.RecordNextInterrupted() },
+		},
+		{
+			name: "interrupted then succeeded",
+			next: 1,
+			record: func(store *FSStageStore) {
+				store.RecordNextInterrupted()
+				store.RecordNextSuccess(true)
+			},
+		},
+		{
+			name: "interrupted then restaged",
+			next: 1,
+			record: func(store *FSStageStore) {
+				store.RecordNextInterrupted()
+				store.SetNext(2)
+			},
+			pending: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			store := &FSStageStore{}
+			store.SetNext(test.next)
+			if test.failed {
+				store.RecordNextSuccess(false)
+			}
+			test.record(store)
+			if actual := store.NextInterrupted(); actual != test.expected {
+				t.Errorf("expected next stage to be interrupted: %t", test.expected)
+			}
+			if _, pending := store.GetPending(); pending != test.pending {
+				t.Errorf("expected next stage to be pending: %t", test.pending)
+			}
+			if actual := store.NextFailed(); actual != test.nextFailed {
+				t.Errorf("expected next stage to have failed: %t", test.nextFailed)
+			}
+		})
+	}
+}
@@ -16,14 +16,16 @@ const (
 	cacheReposDirName     = "repositories"
 	cachePalletsDirName   = "pallets"
 	cacheDownloadsDirName = "downloads"
+	cacheLockFile         = "cache.lock"
 )
 
 // in $HOME/.local/share/forklift:
 
 const (
-	dataDirPath              = ".local/share/forklift"
-	dataCurrentPalletDirName = "pallet"
-	dataStageStoreDirName    = "stages"
+	dataDirPath               = ".local/share/forklift"
+	dataCurrentPalletDirName  = "pallet"
+	dataStageStoreDirName     = "stages"
+	dataCurrentPalletLockFile = "pallet.lock"
 )
 
 // in $HOME/.config/forklift:
@@ -79,6 +79,12 @@ func (w *FSWorkspace) GetCurrentPallet() (*FSPallet, error) {
 	return LoadFSPallet(fsys, dataCurrentPalletDirName)
 }
 
+// GetCurrentPalletLockPath returns the path of the file used to lock the current pallet against
+// concurrent modifications.
+func (w *FSWorkspace) GetCurrentPalletLockPath() string {
+	return path.Join(w.GetDataPath(), dataCurrentPalletLockFile)
+}
+
 // Data: Stages (i.e. pallet bundles which have been staged to be applied)
 
 func (w *FSWorkspace) GetStageStorePath() string {
@@ -118,6 +124,12 @@ func (w *FSWorkspace) getCacheFS() (core.PathedFS, error) {
 	return fsys, nil
 }
 
+// GetCacheLockPath returns the path of the file used to lock the caches against concurrent
+// modifications.
+func (w *FSWorkspace) GetCacheLockPath() string {
+	return path.Join(w.getCachePath(), cacheLockFile)
+}
+
 // Cache: Mirrors
 
 func (w *FSWorkspace) GetMirrorCachePath() string {
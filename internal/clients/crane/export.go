@@ -4,11 +4,13 @@ package crane
 import (
 	"context"
 	"io"
+	"strings"
 
 	"github.com/containerd/platforms"
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	"github.com/pkg/errors"
 )
 
@@ -17,31 +19,91 @@ type Platform = v1.Platform
 func ExportOCIImage(
 	ctx context.Context, imageName string, w io.Writer, platform string,
 ) error {
-	ref, err := name.ParseReference(imageName, name.StrictValidation)
+	_, image, err := pullImage(ctx, imageName, platform, name.StrictValidation)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't parse image name: %s", imageName)
+		return err
+	}
+	return crane.Export(image, w)
+}
+
+// SaveImage downloads the container image with the specified name for the specified platform and
+// writes it to w as a tarball which can be loaded with `docker image load`. Unlike with
+// [ExportOCIImage], the image name need not be fully-qualified. The image is recorded in the
+// tarball with the name returned by [GetSavedImageName], so that Docker will tag the loaded image
+// with that name.
+func SaveImage(ctx context.Context, imageName string, w io.Writer, platform string) error {
+	ref, image, err := pullImage(ctx, imageName, platform)
+	if err != nil {
+		return err
+	}
+	tag, err := getSavedImageTag(ref)
+	if err != nil {
+		return err
+	}
+	return tarball.MultiRefWrite(map[name.Reference]v1.Image{tag: image}, w)
+}
+
+// GetSavedImageName returns the name with which Docker will tag the container image with the
+// specified name after the image is loaded from a tarball written by [SaveImage]. For an image
+// name with a tag, this is the image name exactly as specified. Docker can't tag images with
+// digests, so for an image name with a digest, this is the name of the image's repository tagged
+// with the digest (e.g. `sha256-<hex>`).
+func GetSavedImageName(imageName string) (string, error) {
+	ref, err := name.ParseReference(imageName)
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't parse image name: %s", imageName)
+	}
+	tag, err := getSavedImageTag(ref)
+	if err != nil {
+		return "", err
+	}
+	return tag.String(), nil
+}
+
+func getSavedImageTag(ref name.Reference) (name.Tag, error) {
+	switch ref := ref.(type) {
+	default:
+		return name.Tag{}, errors.Errorf("unknown type of image reference: %s", ref)
+	case name.Tag:
+		return ref, nil
+	case name.Digest:
+		tagged := ref.Context().String() + ":" + strings.ReplaceAll(ref.DigestStr(), ":", "-")
+		tag, err := name.NewTag(tagged)
+		if err != nil {
+			return name.Tag{}, errors.Wrapf(err, "couldn't make tag for image %s", ref)
+		}
+		return tag, nil
+	}
+}
+
+func pullImage(
+	ctx context.Context, imageName string, platform string, opts ...name.Option,
+) (name.Reference, v1.Image, error) {
+	ref, err := name.ParseReference(imageName, opts...)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "couldn't parse image name: %s", imageName)
 	}
 	imageName = ref.Name()
 
 	parsedPlatform, err := v1.ParsePlatform(platform)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't parse platform: %s", platform)
+		return nil, nil, errors.Wrapf(err, "couldn't parse platform: %s", platform)
 	}
 	desc, err := crane.Get(imageName, crane.WithContext(ctx), crane.WithPlatform(parsedPlatform))
 	if err != nil {
-		return errors.Wrapf(err, "couldn't pull image %s", imageName)
+		return nil, nil, errors.Wrapf(err, "couldn't pull image %s", imageName)
 	}
 	var image v1.Image
 	if desc.MediaType.IsSchema1() {
 		if image, err = desc.Schema1(); err != nil {
-			return errors.Wrapf(err, "couldn't pull schema 1 image %s", imageName)
+			return nil, nil, errors.Wrapf(err, "couldn't pull schema 1 image %s", imageName)
 		}
 	} else {
 		if image, err = desc.Image(); err != nil {
-			return errors.Wrapf(err, "couldn't pull image %s", imageName)
+			return nil, nil, errors.Wrapf(err, "couldn't pull image %s", imageName)
 		}
 	}
-	return crane.Export(image, w)
+	return ref, image, nil
 }
 
 func DetectPlatform() Platform {
@@ -0,0 +1,191 @@
+package docker
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/docker/compose/v2/pkg/api"
+	dtc "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	dtm "github.com/docker/docker/api/types/mount"
+	dtn "github.com/docker/docker/api/types/network"
+	dtv "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+
+	"github.com/forklift-run/forklift/pkg/structures"
+)
+
+// ResolveAppDeps returns a digraph where each node is the name of one of the specified Docker
+// Compose apps and each edge goes from an app whose containers use some Docker resource to another
+// specified app which created that resource. The Docker resources considered are networks attached
+// to containers, volumes mounted into containers, containers whose network stacks are shared with
+// other containers (i.e. with `network_mode: container:...`), and containers linked to other
+// containers (i.e. with `external_links`). Dependencies declared with `depends_on` are also
+// considered when they refer to services which aren't in the same app, in which case they're
+// resolved to the apps which have containers of those services (or containers with those names);
+// dependencies among services of the same app are ignored, since Docker Compose already orders them
+// when removing an app.
+func (c *Client) ResolveAppDeps(
+	ctx context.Context, appNames []string,
+) (structures.Digraph[string], error) {
+	deps := make(structures.Digraph[string])
+	apps := make(structures.Set[string])
+	for _, name := range appNames {
+		apps.Add(name)
+		deps.AddNode(name)
+	}
+
+	containers, err := c.Client.ContainerList(ctx, dtc.ListOptions{
+		Filters: filters.NewArgs(oneOffFilter(false)),
+		All:     true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list Docker containers")
+	}
+	networkProviders, err := c.listNetworkProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	volumeProviders, err := c.listVolumeProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	containerProviders := make(map[string]string) // container ID or name -> app name
+	// service name -> app names:
+	serviceProviders := make(map[string]structures.Set[string])
+	for _, container := range containers {
+		app := container.Labels[api.ProjectLabel]
+		containerProviders[container.ID] = app
+		for _, name := range container.Names {
+			containerProviders[strings.TrimPrefix(name, "/")] = app
+		}
+		service := container.Labels[api.ServiceLabel]
+		if _, ok := serviceProviders[service]; !ok {
+			serviceProviders[service] = make(structures.Set[string])
+		}
+		serviceProviders[service].Add(app)
+	}
+
+	for _, container := range containers {
+		app := container.Labels[api.ProjectLabel]
+		if !apps.Has(app) {
+			continue
+		}
+		providers := make([]string, 0)
+		if container.NetworkSettings != nil {
+			for network := range container.NetworkSettings.Networks {
+				providers = append(providers, networkProviders[network])
+			}
+		}
+		for _, mount := range container.Mounts {
+			if mount.Type != dtm.TypeVolume {
+				continue
+			}
+			providers = append(providers, volumeProviders[mount.Name])
+		}
+		if linked, ok := strings.CutPrefix(container.HostConfig.NetworkMode, "container:"); ok {
+			providers = append(providers, containerProviders[linked])
+		}
+		providers = append(
+			providers, resolveDependsOnProviders(container, serviceProviders, containerProviders)...,
+		)
+		linked, err := c.listLinkedContainers(ctx, container.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range linked {
+			providers = append(providers, containerProviders[name])
+		}
+
+		for _, provider := range providers {
+			if provider == app || !apps.Has(provider) {
+				continue
+			}
+			deps.AddEdge(app, provider)
+		}
+	}
+	return deps, nil
+}
+
+// resolveDependsOnProviders returns the names of the Docker Compose apps which provide the
+// services listed in the container's `depends_on` dependencies, for any services which aren't in
+// the container's own app. Such a service is provided by any app with a container of that service,
+// or with a container whose name is the name of that service.
+func resolveDependsOnProviders(
+	container dtc.Summary,
+	serviceProviders map[string]structures.Set[string], containerProviders map[string]string,
+) []string {
+	app := container.Labels[api.ProjectLabel]
+	providers := make([]string, 0)
+	for _, dependency := range strings.Split(container.Labels[api.DependenciesLabel], ",") {
+		// Each dependency is formatted as `service:condition:restart`:
+		service, _, _ := strings.Cut(dependency, ":")
+		if service == "" || serviceProviders[service].Has(app) {
+			continue
+		}
+		providers = append(providers, slices.Collect(serviceProviders[service].All())...)
+		if provider, ok := containerProviders[service]; ok {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// listLinkedContainers returns the names of the containers which are linked to the specified
+// container (e.g. with `external_links`).
+func (c *Client) listLinkedContainers(ctx context.Context, id string) ([]string, error) {
+	container, err := c.Client.ContainerInspect(ctx, id)
+	if err != nil {
+		if errdefs.IsNotFound(err) { // i.e. the container was removed after it was listed
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "couldn't inspect container %.12s", id)
+	}
+	if container.HostConfig == nil {
+		return nil, nil
+	}
+	linked := make([]string, 0, len(container.HostConfig.Links))
+	for _, link := range container.HostConfig.Links {
+		// Each link is formatted as `/name:/alias`:
+		name, _, _ := strings.Cut(link, ":")
+		linked = append(linked, strings.TrimPrefix(name, "/"))
+	}
+	return linked, nil
+}
+
+// listNetworkProviders returns a map between the names of Docker networks and the names of the
+// Docker Compose apps which created them. Networks not created by Docker Compose are omitted.
+func (c *Client) listNetworkProviders(ctx context.Context) (map[string]string, error) {
+	networks, err := c.Client.NetworkList(ctx, dtn.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list Docker networks")
+	}
+	providers := make(map[string]string)
+	for _, network := range networks {
+		if app, ok := network.Labels[api.ProjectLabel]; ok {
+			providers[network.Name] = app
+		}
+	}
+	return providers, nil
+}
+
+// listVolumeProviders returns a map between the names of Docker volumes and the names of the
+// Docker Compose apps which created them. Volumes not created by Docker Compose are omitted.
+func (c *Client) listVolumeProviders(ctx context.Context) (map[string]string, error) {
+	volumes, err := c.Client.VolumeList(ctx, dtv.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list Docker volumes")
+	}
+	providers := make(map[string]string)
+	for _, volume := range volumes.Volumes {
+		if volume == nil {
+			continue
+		}
+		if app, ok := volume.Labels[api.ProjectLabel]; ok {
+			providers[volume.Name] = app
+		}
+	}
+	return providers, nil
+}
@@ -0,0 +1,296 @@
+package docker
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	dct "github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	dtc "github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ServiceAdded is the status of a service which will be added to a Docker Compose app.
+	ServiceAdded = "added"
+	// ServiceRemoved is the status of a service which will be removed from a Docker Compose app.
+	ServiceRemoved = "removed"
+	// ServiceChanged is the status of a service whose containers will be changed.
+	ServiceChanged = "changed"
+)
+
+// A ServiceDiff describes the differences between the active containers of a service of a Docker
+// Compose app and the desired definition of that service.
+type ServiceDiff struct {
+	// Service is the name of the service.
+	Service string
+	// Status is either ServiceAdded, ServiceRemoved, or ServiceChanged.
+	Status string
+	// Changes is a list of the differences between the service's active containers and the desired
+	// definition of the service. It's empty for services which will be added or removed.
+	Changes []FieldChange
+}
+
+// A FieldChange describes a difference in one field (e.g. one environment variable) between the
+// active configuration of a container and its desired configuration.
+type FieldChange struct {
+	// Field is the kind of configuration: image, environment, ports, mounts, networks, or labels.
+	Field string
+	// Key identifies the entry within the field (e.g. the name of an environment variable), for
+	// fields which are collections. For images, the key is empty when the image reference changes,
+	// and it's "id" when only the image downloaded for the same image reference changes.
+	Key string
+	// Current is the active value of the entry; it's empty if the entry will be added.
+	Current string
+	// Desired is the desired value of the entry; it's empty if the entry will be removed.
+	Desired string
+	// Added is true if the entry will be added (so that an empty Current value means the entry is
+	// missing, rather than that the entry has an empty value).
+	Added bool
+	// Removed is true if the entry will be removed (so that an empty Desired value means the entry
+	// will be missing, rather than that the entry will have an empty value).
+	Removed bool
+}
+
+// DiffApp compares the desired definition of the Docker Compose app with the active containers of
+// the app (as identified by the app's name), and returns the differences for each service with
+// differences, sorted by service name. Configuration inherited from container images (e.g.
+// environment variables and labels declared by images) is ignored.
+func (c *Client) DiffApp(ctx context.Context, app *dct.Project) ([]ServiceDiff, error) {
+	containers, err := c.ListContainers(ctx, app.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't list containers of Compose app %s", app.Name)
+	}
+	slices.SortFunc(containers, func(i, j dtc.Summary) int {
+		return cmp.Compare(strings.Join(i.Names, ","), strings.Join(j.Names, ","))
+	})
+	serviceContainers := make(map[string]string) // service name -> container ID
+	for _, container := range containers {
+		service := container.Labels[api.ServiceLabel]
+		if _, ok := serviceContainers[service]; !ok {
+			serviceContainers[service] = container.ID
+		}
+	}
+
+	diffs := make([]ServiceDiff, 0, len(app.Services)+len(serviceContainers))
+	for name, service := range app.Services {
+		id, ok := serviceContainers[name]
+		if !ok {
+			diffs = append(diffs, ServiceDiff{Service: name, Status: ServiceAdded})
+			continue
+		}
+		changes, err := c.diffService(ctx, app, service, id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't compare service %s with its container", name)
+		}
+		if len(changes) > 0 {
+			diffs = append(diffs, ServiceDiff{Service: name, Status: ServiceChanged, Changes: changes})
+		}
+	}
+	for name := range serviceContainers {
+		if _, ok := app.Services[name]; !ok {
+			diffs = append(diffs, ServiceDiff{Service: name, Status: ServiceRemoved})
+		}
+	}
+	slices.SortFunc(diffs, func(i, j ServiceDiff) int {
+		return cmp.Compare(i.Service, j.Service)
+	})
+	return diffs, nil
+}
+
+func (c *Client) diffService(
+	ctx context.Context, app *dct.Project, service dct.ServiceConfig, containerID string,
+) ([]FieldChange, error) {
+	container, err := c.Client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't inspect container %s", containerID)
+	}
+	imageEnv := make(map[string]string)
+	imageLabels := make(map[string]string)
+	imageVolumes := make(map[string]struct{})
+	if image, err := c.Client.ImageInspect(ctx, container.Image); err == nil && image.Config != nil {
+		imageEnv = parseEnv(image.Config.Env)
+		imageLabels = image.Config.Labels
+		imageVolumes = image.Config.Volumes
+	}
+	currentConfig := container.Config
+	if currentConfig == nil {
+		currentConfig = &dtc.Config{}
+	}
+
+	var changes []FieldChange
+	if currentConfig.Image != service.Image {
+		changes = append(changes, FieldChange{
+			Field: "image", Current: currentConfig.Image, Desired: service.Image,
+		})
+	} else if desired, err := c.Client.ImageInspect(ctx, service.Image); err == nil &&
+		desired.ID != container.Image {
+		// The image reference is unchanged, but a different image (e.g. a newer version of a mutable
+		// tag) was downloaded for it since the container was created
+		changes = append(changes, FieldChange{
+			Field: "image", Key: "id", Current: container.Image, Desired: desired.ID,
+		})
+	}
+
+	desiredEnv := make(map[string]string)
+	for key, value := range service.Environment {
+		if value != nil {
+			desiredEnv[key] = *value
+		}
+	}
+	currentEnv := withoutInherited(parseEnv(currentConfig.Env), imageEnv, desiredEnv)
+	changes = append(changes, diffMaps("environment", currentEnv, desiredEnv)...)
+
+	currentPorts := make(map[string]string)
+	if container.HostConfig != nil {
+		for port, bindings := range container.HostConfig.PortBindings {
+			for _, binding := range bindings {
+				desc := formatPortBinding(binding.HostIP, binding.HostPort, port.Port(), port.Proto())
+				currentPorts[desc] = desc
+			}
+		}
+	}
+	desiredPorts := make(map[string]string)
+	for _, port := range service.Ports {
+		desc := formatPortBinding(port.HostIP, port.Published, fmt.Sprint(port.Target), port.Protocol)
+		desiredPorts[desc] = desc
+	}
+	changes = append(changes, diffMaps("ports", currentPorts, desiredPorts)...)
+
+	desiredMounts := make(map[string]string) // target -> description
+	for _, volume := range service.Volumes {
+		source := volume.Source
+		if volume.Type == dct.VolumeTypeVolume {
+			if declared, ok := app.Volumes[source]; ok {
+				source = declared.Name
+			}
+		}
+		desiredMounts[volume.Target] = formatMount(volume.Type, source, !volume.ReadOnly)
+	}
+	currentMounts := make(map[string]string)
+	for _, mount := range container.Mounts {
+		desired, ok := desiredMounts[mount.Destination]
+		if _, inherited := imageVolumes[mount.Destination]; inherited && !ok {
+			continue
+		}
+		source := mount.Source
+		if mount.Name != "" {
+			source = mount.Name
+		}
+		current := formatMount(string(mount.Type), source, mount.RW)
+		if ok && desired == formatMount(string(mount.Type), "", mount.RW) {
+			current = desired // i.e. an anonymous volume
+		}
+		currentMounts[mount.Destination] = current
+	}
+	changes = append(changes, diffMaps("mounts", currentMounts, desiredMounts)...)
+
+	if service.NetworkMode == "" {
+		desiredNetworks := make(map[string]string)
+		networkKeys := slices.Collect(maps.Keys(service.Networks))
+		if len(networkKeys) == 0 {
+			networkKeys = []string{"default"}
+		}
+		for _, key := range networkKeys {
+			name := key
+			if declared, ok := app.Networks[key]; ok {
+				name = declared.Name
+			}
+			desiredNetworks[name] = name
+		}
+		currentNetworks := make(map[string]string)
+		if container.NetworkSettings != nil {
+			for name := range container.NetworkSettings.Networks {
+				currentNetworks[name] = name
+			}
+		}
+		changes = append(changes, diffMaps("networks", currentNetworks, desiredNetworks)...)
+	}
+
+	desiredLabels := make(map[string]string)
+	maps.Copy(desiredLabels, service.Labels)
+	delete(desiredLabels, ServiceFingerprintLabel)
+	currentLabels := make(map[string]string)
+	for key, value := range currentConfig.Labels {
+		if strings.HasPrefix(key, "com.docker.compose.") || key == ServiceFingerprintLabel {
+			continue
+		}
+		currentLabels[key] = value
+	}
+	currentLabels = withoutInherited(currentLabels, imageLabels, desiredLabels)
+	changes = append(changes, diffMaps("labels", currentLabels, desiredLabels)...)
+	return changes, nil
+}
+
+func parseEnv(env []string) map[string]string {
+	parsed := make(map[string]string)
+	for _, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		parsed[key] = value
+	}
+	return parsed
+}
+
+// withoutInherited returns a copy of the current map without any entries which were inherited
+// (without modification) from the inherited map and which aren't in the desired map.
+func withoutInherited(current, inherited, desired map[string]string) map[string]string {
+	filtered := make(map[string]string)
+	for key, value := range current {
+		if inheritedValue, ok := inherited[key]; ok && inheritedValue == value {
+			if _, ok := desired[key]; !ok {
+				continue
+			}
+		}
+		filtered[key] = value
+	}
+	return filtered
+}
+
+func formatPortBinding(hostIP, hostPort, containerPort, protocol string) string {
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	if hostIP != "" {
+		hostPort = hostIP + ":" + hostPort
+	}
+	return fmt.Sprintf("%s->%s/%s", hostPort, containerPort, protocol)
+}
+
+func formatMount(mountType, source string, writable bool) string {
+	desc := mountType
+	if source != "" {
+		desc += " " + source
+	}
+	if !writable {
+		desc += " (read-only)"
+	}
+	return desc
+}
+
+// diffMaps returns the changes needed to turn the current map into the desired map, sorted by key.
+func diffMaps(field string, current, desired map[string]string) []FieldChange {
+	keys := make(map[string]struct{})
+	for key := range current {
+		keys[key] = struct{}{}
+	}
+	for key := range desired {
+		keys[key] = struct{}{}
+	}
+	changes := make([]FieldChange, 0)
+	for _, key := range slices.Sorted(maps.Keys(keys)) {
+		currentValue, hasCurrent := current[key]
+		desiredValue, hasDesired := desired[key]
+		if hasCurrent && hasDesired && currentValue == desiredValue {
+			continue
+		}
+		changes = append(changes, FieldChange{
+			Field: field, Key: key, Current: currentValue, Desired: desiredValue,
+			Added: !hasCurrent, Removed: !hasDesired,
+		})
+	}
+	return changes
+}
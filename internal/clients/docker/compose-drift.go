@@ -0,0 +1,173 @@
+package docker
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	dct "github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	dtc "github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DriftMissingApp is the kind of drift where a Compose app is desired but doesn't exist.
+	DriftMissingApp = "missing-app"
+	// DriftExtraApp is the kind of drift where a Compose app exists but isn't desired.
+	DriftExtraApp = "extra-app"
+	// DriftMissingContainer is the kind of drift where a service has fewer containers than desired.
+	DriftMissingContainer = "missing-container"
+	// DriftExtraContainer is the kind of drift where a container exists for a service which isn't
+	// desired, or where a service has more containers than desired.
+	DriftExtraContainer = "extra-container"
+	// DriftStoppedContainer is the kind of drift where a container exists but isn't running.
+	DriftStoppedContainer = "stopped-container"
+	// DriftRestartingContainer is the kind of drift where a container is restarting (e.g. because it
+	// keeps crashing).
+	DriftRestartingContainer = "restarting-container"
+	// DriftUnhealthyContainer is the kind of drift where a container's health check is failing.
+	DriftUnhealthyContainer = "unhealthy-container"
+	// DriftWrongImage is the kind of drift where a container was created from an image other than
+	// the desired image.
+	DriftWrongImage = "wrong-image"
+)
+
+// A Drift describes one difference between the desired state of a Docker Compose app and the
+// actual state of the Docker host.
+type Drift struct {
+	// App is the name of the Compose app.
+	App string `json:"app" yaml:"app"`
+	// Service is the name of the app's service, if the drift is specific to one service.
+	Service string `json:"service,omitempty" yaml:"service,omitempty"`
+	// Container is the name of the container, if the drift is specific to one container.
+	Container string `json:"container,omitempty" yaml:"container,omitempty"`
+	// Kind is the kind of drift, e.g. DriftMissingContainer.
+	Kind string `json:"kind" yaml:"kind"`
+	// Detail is a human-readable description of the drift.
+	Detail string `json:"detail" yaml:"detail"`
+}
+
+// String returns a human-readable description of the drift.
+func (d Drift) String() string {
+	subject := d.App
+	if d.Service != "" {
+		subject += "/" + d.Service
+	}
+	if d.Container != "" {
+		subject += " (" + d.Container + ")"
+	}
+	return fmt.Sprintf("%s %s: %s", d.Kind, subject, d.Detail)
+}
+
+// CheckAppDrift compares the desired definition of the Docker Compose app with the containers of
+// the app (as identified by the app's name), and returns the ways in which the containers have
+// drifted from the desired definition, sorted by service and container name.
+func (c *Client) CheckAppDrift(ctx context.Context, app *dct.Project) ([]Drift, error) {
+	containers, err := c.ListContainers(ctx, app.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't list containers of Compose app %s", app.Name)
+	}
+	serviceContainers := make(map[string][]dtc.Summary)
+	for _, container := range containers {
+		service := container.Labels[api.ServiceLabel]
+		serviceContainers[service] = append(serviceContainers[service], container)
+	}
+
+	drifts := make([]Drift, 0)
+	for name, service := range app.Services {
+		desiredImageID := ""
+		if image, err := c.Client.ImageInspect(ctx, service.Image); err == nil {
+			desiredImageID = image.ID
+		}
+		containers := serviceContainers[name]
+		if scale := service.GetScale(); len(containers) < scale {
+			drifts = append(drifts, Drift{
+				App: app.Name, Service: name, Kind: DriftMissingContainer,
+				Detail: fmt.Sprintf("%d of %d containers exist", len(containers), scale),
+			})
+		} else if len(containers) > scale {
+			drifts = append(drifts, Drift{
+				App: app.Name, Service: name, Kind: DriftExtraContainer,
+				Detail: fmt.Sprintf("%d containers exist, but only %d are desired", len(containers), scale),
+			})
+		}
+		for _, container := range containers {
+			completed, err := c.hasContainerCompleted(ctx, container)
+			if err != nil {
+				return nil, err
+			}
+			drifts = append(drifts, checkContainerDrift(
+				app.Name, name, container, completed, service.Image, desiredImageID,
+			)...)
+		}
+	}
+	for name, containers := range serviceContainers {
+		if _, ok := app.Services[name]; ok {
+			continue
+		}
+		for _, container := range containers {
+			drifts = append(drifts, Drift{
+				App: app.Name, Service: name, Container: containerName(container),
+				Kind: DriftExtraContainer, Detail: "service is not defined by the app",
+			})
+		}
+	}
+	slices.SortFunc(drifts, func(i, j Drift) int {
+		return cmp.Or(
+			cmp.Compare(i.Service, j.Service),
+			cmp.Compare(i.Container, j.Container),
+			cmp.Compare(i.Kind, j.Kind),
+		)
+	})
+	return drifts, nil
+}
+
+func checkContainerDrift(
+	appName, serviceName string, container dtc.Summary, completed bool,
+	desiredImage, desiredImageID string,
+) []Drift {
+	drifts := make([]Drift, 0)
+	newDrift := func(kind, detail string) Drift {
+		return Drift{
+			App: appName, Service: serviceName, Container: containerName(container),
+			Kind: kind, Detail: detail,
+		}
+	}
+	switch {
+	case container.State == dtc.StateRunning:
+		if strings.Contains(container.Status, "(unhealthy)") {
+			drifts = append(drifts, newDrift(DriftUnhealthyContainer, container.Status))
+		}
+	case container.State == dtc.StateRestarting:
+		drifts = append(drifts, newDrift(DriftRestartingContainer, container.Status))
+	case completed:
+		// A one-shot container which exited successfully isn't expected to be running.
+	default:
+		drifts = append(drifts, newDrift(
+			DriftStoppedContainer, fmt.Sprintf("container is %s: %s", container.State, container.Status),
+		))
+	}
+	switch {
+	case container.Image != desiredImage && desiredImageID == "":
+		drifts = append(drifts, newDrift(
+			DriftWrongImage, fmt.Sprintf("%s (desired: %s)", container.Image, desiredImage),
+		))
+	case desiredImageID != "" && container.ImageID != desiredImageID:
+		drifts = append(drifts, newDrift(
+			DriftWrongImage, fmt.Sprintf(
+				"%s (desired: %s, which is %s)", container.ImageID, desiredImage, desiredImageID,
+			),
+		))
+	}
+	return drifts
+}
+
+func containerName(container dtc.Summary) string {
+	if len(container.Names) == 0 {
+		return container.ID
+	}
+	return strings.TrimPrefix(container.Names[0], "/")
+}
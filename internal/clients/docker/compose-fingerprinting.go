@@ -0,0 +1,150 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	dct "github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	dtc "github.com/docker/docker/api/types/container"
+	"github.com/pkg/errors"
+)
+
+// ServiceFingerprintLabel is the label which Forklift attaches to each service (and thus the
+// containers) of a Docker Compose app to record the fingerprint of the service's definition.
+const ServiceFingerprintLabel = "run.forklift.service.fingerprint"
+
+// serviceDefinition is the definition of a service of a Docker Compose app, together with the
+// definitions of the app's networks, volumes, secrets, and configs which are used by the service.
+type serviceDefinition struct {
+	Service  dct.ServiceConfig              `json:"service"`
+	Networks map[string]dct.NetworkConfig   `json:"networks,omitempty"`
+	Volumes  map[string]dct.VolumeConfig    `json:"volumes,omitempty"`
+	Secrets  map[string]dct.SecretConfig    `json:"secrets,omitempty"`
+	Configs  map[string]dct.ConfigObjConfig `json:"configs,omitempty"`
+}
+
+// ComputeAppFingerprint computes a deterministic fingerprint of the definition of each service of
+// the Docker Compose app (keyed by service name), so that two loads of an unchanged service will
+// produce identical fingerprints; and it combines them into a fingerprint of the app as a whole.
+// The fingerprints should be computed before they're stamped onto the app with
+// [StampAppFingerprint].
+func ComputeAppFingerprint(
+	app *dct.Project,
+) (fingerprint string, serviceFingerprints map[string]string, err error) {
+	serviceFingerprints = make(map[string]string)
+	for name, service := range app.Services {
+		def := serviceDefinition{
+			Service:  service,
+			Networks: make(map[string]dct.NetworkConfig),
+			Volumes:  make(map[string]dct.VolumeConfig),
+			Secrets:  make(map[string]dct.SecretConfig),
+			Configs:  make(map[string]dct.ConfigObjConfig),
+		}
+		for network := range service.Networks {
+			if config, ok := app.Networks[network]; ok {
+				def.Networks[network] = config
+			}
+		}
+		for _, volume := range service.Volumes {
+			if config, ok := app.Volumes[volume.Source]; ok && volume.Type == dct.VolumeTypeVolume {
+				def.Volumes[volume.Source] = config
+			}
+		}
+		for _, secret := range service.Secrets {
+			if config, ok := app.Secrets[secret.Source]; ok {
+				def.Secrets[secret.Source] = config
+			}
+		}
+		for _, config := range service.Configs {
+			if obj, ok := app.Configs[config.Source]; ok {
+				def.Configs[config.Source] = obj
+			}
+		}
+
+		// Note: encoding/json ignores the service's custom labels attached by LoadAppDefinition (which
+		// include the project's working directory and Compose file paths), and it sorts map keys, so
+		// the output is deterministic.
+		marshaled, err := json.Marshal(def)
+		if err != nil {
+			return "", nil, errors.Wrapf(
+				err, "couldn't marshal service %s of Compose app %s", name, app.Name,
+			)
+		}
+		hash := sha256.Sum256(marshaled)
+		serviceFingerprints[name] = hex.EncodeToString(hash[:])
+	}
+	return combineServiceFingerprints(serviceFingerprints), serviceFingerprints, nil
+}
+
+// combineServiceFingerprints computes a deterministic fingerprint of a Docker Compose app from the
+// fingerprints of its services (keyed by service name).
+func combineServiceFingerprints(serviceFingerprints map[string]string) string {
+	hash := sha256.New()
+	for _, service := range slices.Sorted(maps.Keys(serviceFingerprints)) {
+		_, _ = fmt.Fprintf(hash, "%s\t%s\n", service, serviceFingerprints[service])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// StampAppFingerprint attaches the fingerprint of each service of the Docker Compose app (keyed by
+// service name) as a label to that service, so that it can be compared against a newly-computed
+// fingerprint in the future.
+func StampAppFingerprint(app *dct.Project, serviceFingerprints map[string]string) {
+	for name, service := range app.Services {
+		// Note: we add the fingerprint to the service's regular labels (rather than its custom labels)
+		// so that Docker Compose's own change detection will notice when the fingerprint changes, and
+		// then the containers of the service will be recreated with the new fingerprint. Because each
+		// service has its own fingerprint, the containers of unchanged services are left alone.
+		if service.Labels == nil {
+			service.Labels = make(dct.Labels)
+		}
+		service.Labels[ServiceFingerprintLabel] = serviceFingerprints[name]
+		app.Services[name] = service
+	}
+}
+
+// GetAppFingerprint returns the fingerprint of the specified Docker Compose app, combined from the
+// service fingerprints stamped on the app's containers. If the containers of any service don't
+// all have the same fingerprint (e.g. because the app was not deployed by Forklift, or because a
+// previous deployment of the app was interrupted), or if any container is not running (so that
+// Docker Compose would need to start it again) unless it's a one-shot container which exited
+// successfully, or if any container is missing the AppOwnerLabel label (so that the label still
+// needs to be attached), an empty fingerprint is returned.
+func (c *Client) GetAppFingerprint(ctx context.Context, appName string) (string, error) {
+	containers, err := c.ListContainers(ctx, appName)
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't list containers of Compose app %s", appName)
+	}
+	if len(containers) == 0 {
+		return "", nil
+	}
+	serviceFingerprints := make(map[string]string)
+	for _, container := range containers {
+		if container.Labels[AppOwnerLabel] != AppOwner {
+			return "", nil
+		}
+		if container.State != dtc.StateRunning {
+			completed, err := c.hasContainerCompleted(ctx, container)
+			if err != nil {
+				return "", err
+			}
+			if !completed {
+				return "", nil
+			}
+		}
+		service := container.Labels[api.ServiceLabel]
+		fingerprint := container.Labels[ServiceFingerprintLabel]
+		existing, seen := serviceFingerprints[service]
+		if fingerprint == "" || (seen && existing != fingerprint) {
+			return "", nil
+		}
+		serviceFingerprints[service] = fingerprint
+	}
+	return combineServiceFingerprints(serviceFingerprints), nil
+}
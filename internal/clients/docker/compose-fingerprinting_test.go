@@ -0,0 +1,89 @@
+package docker
+
+import (
+	"testing"
+
+	dct "github.com/compose-spec/compose-go/v2/types"
+)
+
+func newTestApp(image string, networkDriver string) *dct.Project {
+	return &dct.Project{
+		Name: "app",
+		Services: dct.Services{
+			"server": {
+				Name:     "server",
+				Image:    image,
+				Networks: map[string]*dct.ServiceNetworkConfig{"backend": nil},
+			},
+			"worker": {Name: "worker", Image: "docker.io/library/alpine:3.20"},
+		},
+		Networks: dct.Networks{"backend": {Name: "app_backend", Driver: networkDriver}},
+	}
+}
+
+func TestComputeAppFingerprint(t *testing.T) {
+	fingerprint, serviceFingerprints, err := ComputeAppFingerprint(
+		newTestApp("docker.io/library/nginx:1.27", "bridge"),
+	)
+	if err != nil {
+		t.Fatalf("couldn't compute fingerprint: %s", err)
+	}
+
+	for _, test := range []struct {
+		name           string
+		app            *dct.Project
+		changedApp     bool
+		changedServers bool
+	}{
+		{
+			name: "unchanged",
+			app:  newTestApp("docker.io/library/nginx:1.27", "bridge"),
+		},
+		{
+			name:           "changed image",
+			app:            newTestApp("docker.io/library/nginx:1.28", "bridge"),
+			changedApp:     true,
+			changedServers: true,
+		},
+		{
+			name:           "changed network used by a service",
+			app:            newTestApp("docker.io/library/nginx:1.27", "macvlan"),
+			changedApp:     true,
+			changedServers: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			actual, actualServices, err := ComputeAppFingerprint(test.app)
+			if err != nil {
+				t.Fatalf("couldn't compute fingerprint: %s", err)
+			}
+			if (actual != fingerprint) != test.changedApp {
+				t.Errorf("expected app fingerprint to change: %t", test.changedApp)
+			}
+			if (actualServices["server"] != serviceFingerprints["server"]) != test.changedServers {
+				t.Errorf("expected fingerprint of service server to change: %t", test.changedServers)
+			}
+			// The worker service doesn't use the network, so it should never be affected:
+			if actualServices["worker"] != serviceFingerprints["worker"] {
+				t.Error("expected fingerprint of service worker not to change")
+			}
+		})
+	}
+}
+
+func TestStampAppFingerprint(t *testing.T) {
+	app := newTestApp("docker.io/library/nginx:1.27", "bridge")
+	fingerprint, serviceFingerprints, err := ComputeAppFingerprint(app)
+	if err != nil {
+		t.Fatalf("couldn't compute fingerprint: %s", err)
+	}
+	StampAppFingerprint(app, serviceFingerprints)
+	for name, service := range app.Services {
+		if service.Labels[ServiceFingerprintLabel] != serviceFingerprints[name] {
+			t.Errorf("expected service %s to be stamped with its fingerprint", name)
+		}
+	}
+	if combined := combineServiceFingerprints(serviceFingerprints); combined != fingerprint {
+		t.Errorf("expected app fingerprint %s, got %s", fingerprint, combined)
+	}
+}
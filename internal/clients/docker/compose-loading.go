@@ -73,6 +73,7 @@ func LoadAppDefinition(
 		}
 		project.Services[i] = s
 	}
+	stampAppOwnership(project)
 	project.WithoutUnnecessaryResources()
 	return project, nil
 }
@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"context"
+	"maps"
+
+	dct "github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/compose/v2/pkg/compose"
+	"github.com/docker/docker/api/types/filters"
+	dtn "github.com/docker/docker/api/types/network"
+	"github.com/pkg/errors"
+)
+
+// FindAppNetwork returns the key (in the Docker Compose app's definition) of the non-external
+// network with the specified Docker network name. If the app does not create such a network, an
+// empty key is returned.
+func FindAppNetwork(app *dct.Project, networkName string) string {
+	for key, network := range app.Networks {
+		if network.Name == networkName && !bool(network.External) {
+			return key
+		}
+	}
+	return ""
+}
+
+// EnsureAppNetwork creates the specified network of the Docker Compose app if no Docker network
+// with the same name already exists. The network is created with the same configuration and
+// labels which Docker Compose would use, so that Docker Compose will adopt (rather than recreate)
+// the network when the app is deployed. Returns true if the network was created.
+func (c *Client) EnsureAppNetwork(
+	ctx context.Context, app *dct.Project, networkName string,
+) (created bool, err error) {
+	key := FindAppNetwork(app, networkName)
+	if key == "" {
+		return false, errors.Errorf(
+			"Compose app %s does not define a network named %s", app.Name, networkName,
+		)
+	}
+
+	existing, err := c.Client.NetworkList(ctx, dtn.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("name", networkName)),
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "couldn't list Docker networks named %s", networkName)
+	}
+	for _, network := range existing {
+		// Note: the name filter also matches networks whose names merely contain the specified name
+		if network.Name == networkName {
+			return false, nil
+		}
+	}
+
+	network := app.Networks[key]
+	hash, err := compose.NetworkHash(&network)
+	if err != nil {
+		return false, errors.Wrapf(err, "couldn't compute configuration hash of network %s", key)
+	}
+	labels := make(map[string]string)
+	maps.Copy(labels, network.Labels)
+	maps.Copy(labels, network.CustomLabels)
+	labels[api.NetworkLabel] = key
+	labels[api.ProjectLabel] = app.Name
+	labels[api.VersionLabel] = api.ComposeVersion
+	labels[api.ConfigHashLabel] = hash
+
+	options := dtn.CreateOptions{
+		Labels:     labels,
+		Driver:     network.Driver,
+		Options:    network.DriverOpts,
+		Internal:   network.Internal,
+		Attachable: network.Attachable,
+		EnableIPv6: network.EnableIPv6,
+		EnableIPv4: network.EnableIPv4,
+	}
+	if network.Ipam.Driver != "" || len(network.Ipam.Config) > 0 {
+		options.IPAM = &dtn.IPAM{Driver: network.Ipam.Driver}
+		for _, pool := range network.Ipam.Config {
+			options.IPAM.Config = append(options.IPAM.Config, dtn.IPAMConfig{
+				Subnet:     pool.Subnet,
+				IPRange:    pool.IPRange,
+				Gateway:    pool.Gateway,
+				AuxAddress: pool.AuxiliaryAddresses,
+			})
+		}
+	}
+	if _, err = c.Client.NetworkCreate(ctx, networkName, options); err != nil {
+		return false, errors.Wrapf(err, "couldn't create Docker network %s", networkName)
+	}
+	return true, nil
+}
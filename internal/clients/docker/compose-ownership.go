@@ -0,0 +1,81 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	dct "github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	dtc "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/pkg/errors"
+
+	"github.com/forklift-run/forklift/pkg/structures"
+)
+
+const (
+	// AppOwnerLabel is the label which Forklift attaches to the services (and thus the containers) of
+	// every Docker Compose app it loads, to record that the app was created by Forklift.
+	AppOwnerLabel = "run.forklift.app.owner"
+	// AppOwner is the value of the AppOwnerLabel label.
+	AppOwner = "forklift"
+)
+
+// stampAppOwnership attaches the AppOwnerLabel label to every service of the Docker Compose app.
+func stampAppOwnership(app *dct.Project) {
+	for name, service := range app.Services {
+		// Note: we add the label to the service's regular labels (rather than its custom labels) so
+		// that Docker Compose's own change detection will recreate any containers which were created
+		// without the label (e.g. by older versions of Forklift, or by other tools).
+		if service.Labels == nil {
+			service.Labels = make(dct.Labels)
+		}
+		service.Labels[AppOwnerLabel] = AppOwner
+		app.Services[name] = service
+	}
+}
+
+// ListOwnedApps returns the names of the Docker Compose apps which have at least one container
+// labeled as having been created by Forklift.
+func (c *Client) ListOwnedApps(ctx context.Context) (structures.Set[string], error) {
+	containers, err := c.Client.ContainerList(ctx, dtc.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", fmt.Sprintf("%s=%s", AppOwnerLabel, AppOwner)), oneOffFilter(false),
+		),
+		All: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list Docker containers created by Forklift")
+	}
+	owned := make(structures.Set[string])
+	for _, container := range containers {
+		if app, ok := container.Labels[api.ProjectLabel]; ok {
+			owned.Add(app)
+		}
+	}
+	return owned, nil
+}
+
+// ListUnlabeledApps returns the names of the Docker Compose apps which have no container labeled
+// with AppOwnerLabel (with any value). Such apps were either created by tools other than Forklift,
+// or by versions of Forklift which did not yet label the apps they created.
+func (c *Client) ListUnlabeledApps(ctx context.Context) (structures.Set[string], error) {
+	containers, err := c.Client.ContainerList(ctx, dtc.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", api.ProjectLabel), oneOffFilter(false)),
+		All:     true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list Docker containers of Compose apps")
+	}
+	unlabeled := make(structures.Set[string])
+	labeled := make(structures.Set[string])
+	for _, container := range containers {
+		app := container.Labels[api.ProjectLabel]
+		if _, ok := container.Labels[AppOwnerLabel]; ok {
+			labeled.Add(app)
+			continue
+		}
+		unlabeled.Add(app)
+	}
+	return unlabeled.Difference(labeled), nil
+}
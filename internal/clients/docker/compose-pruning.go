@@ -0,0 +1,86 @@
+package docker
+
+import (
+	"context"
+	"slices"
+
+	dct "github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/docker/api/types/filters"
+	dtn "github.com/docker/docker/api/types/network"
+	dtv "github.com/docker/docker/api/types/volume"
+	"github.com/pkg/errors"
+
+	"github.com/forklift-run/forklift/pkg/structures"
+)
+
+// ListOrphanedAppNetworks returns the sorted names of Docker networks which were created for the
+// Docker Compose app (as identified by the app's name) but which are not declared by the provided
+// definition of the app.
+func (c *Client) ListOrphanedAppNetworks(ctx context.Context, app *dct.Project) ([]string, error) {
+	declared := make(structures.Set[string])
+	for _, network := range app.Networks {
+		if !bool(network.External) {
+			declared.Add(network.Name)
+		}
+	}
+	networks, err := c.Client.NetworkList(ctx, dtn.ListOptions{
+		Filters: filters.NewArgs(appFilter(app.Name)),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't list Docker networks of Compose app %s", app.Name)
+	}
+	orphaned := make([]string, 0, len(networks))
+	for _, network := range networks {
+		if !declared.Has(network.Name) {
+			orphaned = append(orphaned, network.Name)
+		}
+	}
+	slices.Sort(orphaned)
+	return orphaned, nil
+}
+
+// ListOrphanedAppVolumes returns the sorted names of Docker volumes which were created for the
+// Docker Compose app (as identified by the app's name) but which are not declared by the provided
+// definition of the app.
+func (c *Client) ListOrphanedAppVolumes(ctx context.Context, app *dct.Project) ([]string, error) {
+	declared := make(structures.Set[string])
+	for _, volume := range app.Volumes {
+		if !bool(volume.External) {
+			declared.Add(volume.Name)
+		}
+	}
+	volumes, err := c.Client.VolumeList(ctx, dtv.ListOptions{
+		Filters: filters.NewArgs(appFilter(app.Name)),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't list Docker volumes of Compose app %s", app.Name)
+	}
+	orphaned := make([]string, 0, len(volumes.Volumes))
+	for _, volume := range volumes.Volumes {
+		if volume != nil && !declared.Has(volume.Name) {
+			orphaned = append(orphaned, volume.Name)
+		}
+	}
+	slices.Sort(orphaned)
+	return orphaned, nil
+}
+
+// RemoveNetworks removes the specified Docker networks.
+func (c *Client) RemoveNetworks(ctx context.Context, names []string) error {
+	for _, name := range names {
+		if err := c.Client.NetworkRemove(ctx, name); err != nil {
+			return errors.Wrapf(err, "couldn't remove Docker network %s", name)
+		}
+	}
+	return nil
+}
+
+// RemoveVolumes removes the specified Docker volumes.
+func (c *Client) RemoveVolumes(ctx context.Context, names []string) error {
+	for _, name := range names {
+		if err := c.Client.VolumeRemove(ctx, name, false); err != nil {
+			return errors.Wrapf(err, "couldn't remove Docker volume %s", name)
+		}
+	}
+	return nil
+}
@@ -18,10 +18,11 @@ import (
 // Client
 
 type clientOptions struct {
-	quiet     bool
-	apiClient []dc.Opt
-	cli       []command.CLIOption
-	cliFlags  flags.ClientOptions
+	quiet        bool
+	apiClient    []dc.Opt
+	cli          []command.CLIOption
+	cliFlags     flags.ClientOptions
+	pullProgress func(PullProgress)
 }
 
 type ClientOption func(clientOptions) clientOptions
@@ -49,6 +50,15 @@ func WithOutputStream(w io.Writer) ClientOption {
 	}
 }
 
+// WithPullProgressHandler makes the client report the progress of image pulls to the handler, in
+// addition to printing the progress to the pull's output stream.
+func WithPullProgressHandler(handler func(PullProgress)) ClientOption {
+	return func(options clientOptions) clientOptions {
+		options.pullProgress = handler
+		return options
+	}
+}
+
 type Client struct {
 	options clientOptions
 	Client  *dc.Client
@@ -100,15 +110,23 @@ func (c *Client) ListApps(ctx context.Context) ([]api.Stack, error) {
 
 // docker compose up
 
+// DeployApp creates or updates the Docker Compose app and then waits until all of its containers
+// are running (or healthy, for containers with health checks), so that anything which depends on
+// the app won't be started until the app is ready. If waitTimeout is nonzero, an error is returned
+// when the app doesn't become ready within waitTimeout.
 func (c *Client) DeployApp(
 	ctx context.Context, app *dct.Project, waitTimeout time.Duration,
 ) error {
+	// Note: the timeout for stopping containers which need to be recreated is unrelated to the
+	// timeout for waiting until the app is ready, so we keep it at zero (as it was before readiness
+	// timeouts were added):
+	var stopTimeout time.Duration
 	options := api.UpOptions{
 		Create: api.CreateOptions{
 			RemoveOrphans:        true,
 			Recreate:             api.RecreateDiverged,
 			RecreateDependencies: api.RecreateDiverged,
-			Timeout:              &waitTimeout,
+			Timeout:              &stopTimeout,
 			QuietPull:            c.options.quiet,
 		},
 		Start: api.StartOptions{
@@ -117,8 +135,9 @@ func (c *Client) DeployApp(
 			WaitTimeout: waitTimeout,
 		},
 	}
+	// Note: Up doesn't prune networks (or volumes) which are no longer needed by the app; use
+	// ListOrphanedAppNetworks and ListOrphanedAppVolumes to find them.
 	return c.Compose.Up(ctx, app, options)
-	// FIXME: Up doesn't seem to prune networks which are no longer needed by the app!
 }
 
 // docker compose down
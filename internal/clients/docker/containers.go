@@ -7,6 +7,7 @@ import (
 	"github.com/docker/compose/v2/pkg/api"
 	dtc "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/pkg/errors"
 )
 
 // docker container ls
@@ -37,3 +38,27 @@ func oneOffFilter(b bool) filters.KeyValuePair {
 	}
 	return filters.Arg("label", fmt.Sprintf("%s=%s", api.OneoffLabel, v))
 }
+
+// docker container inspect
+
+// hasContainerCompleted checks whether the container is of a one-shot service (i.e. a container
+// which Docker doesn't restart) and exited successfully, in which case it's not expected to be
+// running.
+func (c *Client) hasContainerCompleted(ctx context.Context, container dtc.Summary) (bool, error) {
+	if container.State != dtc.StateExited {
+		return false, nil
+	}
+	inspected, err := c.Client.ContainerInspect(ctx, container.ID)
+	if err != nil {
+		return false, errors.Wrapf(err, "couldn't inspect container %s", container.ID)
+	}
+	return isCompleted(inspected), nil
+}
+
+// isCompleted checks whether the inspected container is of a one-shot service (i.e. a container
+// which Docker doesn't restart) and exited successfully.
+func isCompleted(container dtc.InspectResponse) bool {
+	return container.State != nil && container.State.Status == dtc.StateExited &&
+		container.State.ExitCode == 0 &&
+		container.HostConfig != nil && container.HostConfig.RestartPolicy.IsNone()
+}
@@ -0,0 +1,95 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// docker events
+
+// An AppEvent describes a container of a Docker Compose app created by Forklift which has stopped
+// or been removed.
+type AppEvent struct {
+	// App is the name of the Compose app.
+	App string
+	// Service is the name of the app's service.
+	Service string
+	// ContainerID is the ID of the container.
+	ContainerID string
+	// Action is either "die" or "destroy".
+	Action string
+	// Time is when the event happened.
+	Time time.Time
+}
+
+// WatchOwnedAppEvents streams events for containers of Docker Compose apps created by Forklift
+// which stop or are removed, until ctx is canceled. If watching fails, an error is sent on the
+// returned error channel and no more events will be sent.
+func (c *Client) WatchOwnedAppEvents(ctx context.Context) (<-chan AppEvent, <-chan error) {
+	messages, messageErrs := c.Client.Events(ctx, events.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", string(events.ContainerEventType)),
+			filters.Arg("event", string(events.ActionDie)),
+			filters.Arg("event", string(events.ActionDestroy)),
+			filters.Arg("label", fmt.Sprintf("%s=%s", AppOwnerLabel, AppOwner)),
+			oneOffFilter(false),
+		),
+	})
+	appEvents := make(chan AppEvent)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(appEvents)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-messageErrs:
+				if ctx.Err() == nil {
+					errs <- errors.Wrap(err, "couldn't receive Docker events")
+				}
+				return
+			case message := <-messages:
+				event := AppEvent{
+					App:         message.Actor.Attributes[api.ProjectLabel],
+					Service:     message.Actor.Attributes[api.ServiceLabel],
+					ContainerID: message.Actor.ID,
+					Action:      string(message.Action),
+					Time:        time.Unix(0, message.TimeNano),
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case appEvents <- event:
+				}
+			}
+		}
+	}()
+	return appEvents, errs
+}
+
+// IsContainerDown returns true if the container doesn't exist, or if it's neither running nor
+// about to be restarted by Docker (according to its restart policy). A container which Docker
+// doesn't restart (i.e. of a one-shot service) and which exited successfully is not down.
+func (c *Client) IsContainerDown(ctx context.Context, id string) (bool, error) {
+	container, err := c.Client.ContainerInspect(ctx, id)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return true, nil
+		}
+		return false, errors.Wrapf(err, "couldn't inspect container %s", id)
+	}
+	if container.State == nil {
+		return true, nil
+	}
+	if isCompleted(container) {
+		return false, nil
+	}
+	return !container.State.Running && !container.State.Restarting, nil
+}
@@ -15,6 +15,7 @@ import (
 	dti "github.com/docker/docker/api/types/image"
 	dtr "github.com/docker/docker/api/types/registry"
 	dc "github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/pkg/errors"
 )
@@ -107,6 +108,48 @@ func (c *Client) InspectImage(ctx context.Context, imageHash string) (Image, err
 	return image, nil
 }
 
+// ResolveImageDigest returns the digest of the image with the specified name: either the digest
+// given in the name, or else the repository digest of the matching image in Docker's local image
+// store. It returns an empty string if the image isn't in the local image store.
+func (c *Client) ResolveImageDigest(ctx context.Context, imageName string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't parse image name %s", imageName)
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		return canonical.Digest().String(), nil
+	}
+
+	inspect, err := c.Client.ImageInspect(ctx, imageName)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "couldn't inspect image %s", imageName)
+	}
+	for _, repoDigest := range inspect.RepoDigests {
+		digested, err := reference.ParseNormalizedNamed(repoDigest)
+		if err != nil {
+			continue
+		}
+		if canonical, ok := digested.(reference.Canonical); ok && digested.Name() == named.Name() {
+			return canonical.Digest().String(), nil
+		}
+	}
+	return "", nil
+}
+
+// HasImage checks whether the image with the specified name is in Docker's local image store.
+func (c *Client) HasImage(ctx context.Context, imageName string) (bool, error) {
+	if _, err := c.Client.ImageInspect(ctx, imageName); err != nil {
+		if errdefs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "couldn't inspect image %s", imageName)
+	}
+	return true, nil
+}
+
 func (c *Client) PruneUnusedImages(ctx context.Context) (dti.PruneReport, error) {
 	return c.Client.ImagesPrune(ctx, dtf.NewArgs(dtf.KeyValuePair{
 		// Note: it appears that the "dangling" filter sets whether to only prune dangling images;
@@ -202,5 +245,98 @@ func (c *Client) pullImage(
 		}
 	}()
 
-	return jsonmessage.DisplayJSONMessagesToStream(responseBody, out, nil)
+	var messages io.Reader = responseBody
+	if c.options.pullProgress != nil {
+		messages = io.TeeReader(responseBody, &pullProgressWriter{
+			image:   reference.FamiliarString(imgRefAndAuth.Reference()),
+			handler: c.options.pullProgress,
+		})
+	}
+	return jsonmessage.DisplayJSONMessagesToStream(messages, out, nil)
+}
+
+// A PullProgress describes the progress of an image pull, as reported by Docker.
+type PullProgress struct {
+	// Image is the name of the image being pulled.
+	Image string
+	// Layer is the ID of the image layer which the progress is about, if any.
+	Layer string
+	// Status is Docker's description of the progress (e.g. "Downloading" or "Pull complete").
+	Status string
+	// Current is the number of bytes which have been processed, if known.
+	Current int64
+	// Total is the total number of bytes to be processed, if known.
+	Total int64
+}
+
+// pullProgressWriter parses the stream of JSON messages from an image pull, reporting each
+// message to a handler.
+type pullProgressWriter struct {
+	image   string
+	handler func(PullProgress)
+	buffer  []byte
+}
+
+func (w *pullProgressWriter) Write(p []byte) (n int, err error) {
+	w.buffer = append(w.buffer, p...)
+	for {
+		line, rest, ok := bytes.Cut(w.buffer, []byte("\n"))
+		if !ok {
+			return len(p), nil
+		}
+		w.buffer = rest
+		var message jsonmessage.JSONMessage
+		if err := json.Unmarshal(line, &message); err != nil || message.Status == "" {
+			continue
+		}
+		progress := PullProgress{
+			Image:  w.image,
+			Layer:  message.ID,
+			Status: message.Status,
+		}
+		if message.Progress != nil {
+			progress.Current = message.Progress.Current
+			progress.Total = message.Progress.Total
+		}
+		w.handler(progress)
+	}
+}
+
+// docker image save
+
+// SaveImages writes the specified images to w as a tarball, in the format of `docker image save`.
+func (c *Client) SaveImages(ctx context.Context, imageNames []string, w io.Writer) (err error) {
+	responseBody, err := c.Client.ImageSave(ctx, imageNames)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := responseBody.Close(); cerr != nil {
+			if err == nil {
+				err = cerr
+			}
+		}
+	}()
+
+	_, err = io.Copy(w, responseBody)
+	return err
+}
+
+// docker image load
+
+// LoadImages loads images into Docker from a tarball in the format of `docker image save`.
+func (c *Client) LoadImages(ctx context.Context, r io.Reader, out io.Writer) (err error) {
+	response, err := c.Client.ImageLoad(ctx, r, dc.ImageLoadWithQuiet(true))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := response.Body.Close(); cerr != nil {
+			if err == nil {
+				err = cerr
+			}
+		}
+	}()
+
+	return jsonmessage.DisplayJSONMessagesStream(response.Body, out, 0, false, nil)
 }
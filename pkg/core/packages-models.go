@@ -80,6 +80,10 @@ type PkgDeplSpec struct {
 	ComposeFiles []string `yaml:"compose-files,omitempty"`
 	// Tags is a list of strings associated with the deployment.
 	Tags []string `yaml:"tags,omitempty"`
+	// ReadinessTimeout is the maximum duration (e.g. `2m30s`) to wait for the containers of the
+	// Docker Compose application to become running and healthy after they are deployed, before the
+	// deployment is considered to have failed. If omitted, there is no time limit.
+	ReadinessTimeout string `yaml:"readiness-timeout,omitempty"`
 	// Requires describes resource requirements which must be met for a deployment of the package to
 	// succeed.
 	Requires RequiredRes `yaml:"requires,omitempty"`